@@ -7,11 +7,14 @@ import (
 	"io"
 	"io/fs"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
 
 	"github.com/benpate/derp"
 	"github.com/benpate/form"
 	"github.com/benpate/rosetta/mapof"
+	"github.com/benpate/rosetta/null"
 	"github.com/benpate/rosetta/schema"
 	"github.com/benpate/rosetta/sliceof"
 	"github.com/benpate/table"
@@ -71,8 +74,8 @@ func handleTable() http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		// Get the table widget
-		exampleTable := getTable()
+		// Get the table widget, in the user's preferred language
+		exampleTable := getTable().UseLocale(preferredLocale(r))
 
 		// If this is a GET request, then simply draw the table
 		if r.Method == http.MethodGet {
@@ -95,6 +98,19 @@ func handleTable() http.HandlerFunc {
 
 		// Apply the changes to the database
 		if err := exampleTable.Do(r.URL, postData); err != nil {
+
+			// If someone else changed the row first, then let the user reconcile the changes
+			if table.IsConflict(err) {
+				_ = exampleTable.DrawConflict(r.URL, postData, w)
+				return
+			}
+
+			// If the values were not valid, then let the user correct them
+			if derp.IsValidationError(err) {
+				_ = exampleTable.DrawErrors(r.URL, postData, err, w)
+				return
+			}
+
 			writeError(w, err)
 			return
 		}
@@ -102,11 +118,53 @@ func handleTable() http.HandlerFunc {
 		// If we weren't using an in-memory data structure,
 		// there would probably be some sort of db.Save() call here.
 
-		// Finally, redraw the table
-		_ = exampleTable.DrawView(w)
+		// Tell screen reader users what just happened
+		announced := exampleTable.Announce(r.URL)
+
+		// Auto-saved rows stay open, so the user can keep editing them
+		if r.URL.Query().Has("autosave") {
+			_ = announced.Draw(r.URL, w)
+			return
+		}
+
+		// Finally, redraw the table just as the user was viewing it
+		_ = announced.Draw(viewURL(r.URL), w)
 	}
 }
 
+/******************************************
+ * Translations
+ ******************************************/
+
+// translations contains a (partial) German translation of the table.
+// Messages that are not translated here are displayed in English.
+var translations = table.Languages{
+	"de": table.Messages{
+		table.MessageAddRow:           "Zeile hinzufügen",
+		table.MessageConfirmDeleteRow: "Möchten Sie diese Zeile wirklich löschen?",
+		table.MessageSearch:           "Suchen",
+		table.MessageFilterAll:        "Alle",
+		table.MessagePageStatus:       "Seite %d von %d",
+		table.MessageRowAdded:         "Zeile hinzugefügt",
+		table.MessageRowSaved:         "Zeile %d gespeichert",
+		table.MessageRowDeleted:       "Zeile gelöscht",
+		table.MessageShowDetails:      "Details von Zeile %d anzeigen",
+		table.MessageHideDetails:      "Details von Zeile %d ausblenden",
+		table.MessageCollapseRow:      "Zeile %d zuklappen",
+		table.MessageExpandRow:        "Zeile %d aufklappen",
+		table.MessageAddChildRow:      "Unterzeile zu Zeile %d hinzufügen",
+	},
+}
+
+// preferredLocale returns the first language in the request's Accept-Language
+// header (such as "de-DE" in "de-DE,de;q=0.9,en;q=0.8").  Real apps usually
+// let their framework negotiate this.
+func preferredLocale(r *http.Request) string {
+	locale, _, _ := strings.Cut(r.Header.Get("Accept-Language"), ",")
+	locale, _, _ = strings.Cut(locale, ";")
+	return strings.TrimSpace(locale)
+}
+
 /******************************************
  * Data Structures for the Demo Table
  ******************************************/
@@ -117,6 +175,7 @@ func getTable() table.Table {
 
 	schema := getTableSchema()
 	form := getTableForm()
+	details := getTableDetails()
 
 	return table.New(
 		&schema,
@@ -125,7 +184,7 @@ func getTable() table.Table {
 		"data",
 		IconProvider{},
 		"/table",
-	)
+	).AllowInsert().AllowDuplicate().AllowMove().AllowSelect().UseDragHandle().UseCellEdit().UseAutoSave().UseKeyboard().UseCaption("Tasks").UseDetails(details).UseTranslator(translations).AllowSort().AllowSearch().AllowFilter().UsePageSize(4)
 }
 
 // getTableSchema defines the data layout for this example.
@@ -141,10 +200,23 @@ func getTableSchema() schema.Schema {
 					Items: schema.Object{
 						Properties: schema.ElementMap{
 							"taskId":      schema.String{},
+							"parentId":    schema.String{},
 							"label":       schema.String{MaxLength: 128},
 							"description": schema.String{MaxLength: 1024},
 							"status":      schema.String{Enum: []string{"New", "Pending", "Waiting", "In Progress", "Complete"}},
 							"assignedTo":  schema.String{Enum: []string{"Alice", "Bob", "Carl", "Dave"}},
+							"budget":      schema.Number{Minimum: null.NewFloat(0)},
+							"supplies": schema.Array{
+								Items: schema.Object{
+									Properties: schema.ElementMap{
+										"item":     schema.String{Required: true, MaxLength: 128},
+										"quantity": schema.Integer{Minimum: null.NewInt64(1)},
+									},
+								},
+							},
+							"tags": schema.Array{
+								Items: schema.String{MaxLength: 32},
+							},
 						},
 					},
 				},
@@ -165,11 +237,6 @@ func getTableForm() form.Element {
 				Path:  "label",
 				Label: "Task Name",
 			},
-			{
-				Type:  "textarea",
-				Path:  "description",
-				Label: "Description",
-			},
 			{
 				Type:  "select",
 				Path:  "status",
@@ -180,6 +247,45 @@ func getTableForm() form.Element {
 				Path:  "assignedTo",
 				Label: "Assigned To",
 			},
+			{
+				Type:    "text",
+				Path:    "budget",
+				Label:   "Budget",
+				Options: mapof.Any{"column-format": table.FormatCurrency, "column-currency": "EUR", "column-aggregate": table.AggregateSum},
+			},
+			{
+				Type:  table.SubTableType,
+				Path:  "supplies",
+				Label: "Supplies",
+				Children: []form.Element{
+					{Type: "text", Path: "item", Label: "Item"},
+					{Type: "text", Path: "quantity", Label: "Qty"},
+				},
+			},
+			{
+				Type:  table.SubTableType,
+				Path:  "tags",
+				Label: "Tags",
+				Children: []form.Element{
+					{Type: "text", Label: "Tag"},
+				},
+			},
+		},
+	}
+}
+
+// getTableDetails defines the fields that are displayed beneath each row,
+// instead of in their own columns.
+func getTableDetails() form.Element {
+
+	return form.Element{
+		Type: "layout-vertical",
+		Children: []form.Element{
+			{
+				Type:  "textarea",
+				Path:  "description",
+				Label: "Description",
+			},
 		},
 	}
 }
@@ -194,13 +300,21 @@ func getDefaultTableData() Database {
 				"description": "Some gibberish.",
 				"status":      "In Progress",
 				"assignedTo":  "Bob",
+				"budget":      120.5,
+				"supplies": &sliceof.Object[mapof.Any]{
+					{"item": "Milk", "quantity": 2},
+					{"item": "Bread", "quantity": 1},
+				},
+				"tags": &sliceof.String{"errand", "weekly"},
 			},
 			mapof.Any{
 				"taskId":      "2",
+				"parentId":    "1",
 				"label":       "Hardware Store",
 				"description": "More gibberish here.",
 				"status":      "Pending",
 				"assignedTo":  "Alice",
+				"budget":      1499,
 			},
 		},
 	}
@@ -233,6 +347,51 @@ func (i IconProvider) Get(name string) string {
 
 	case "cancel": // X
 		return `<i class="bi bi-x-circle-fill"></i>`
+
+	case "sort-ascending": // arrow up
+		return `<i class="bi bi-sort-up"></i>`
+
+	case "sort-descending": // arrow down
+		return `<i class="bi bi-sort-down"></i>`
+
+	case "first": // double left chevron
+		return `<i class="bi bi-chevron-double-left"></i>`
+
+	case "previous": // left chevron
+		return `<i class="bi bi-chevron-left"></i>`
+
+	case "next": // right chevron
+		return `<i class="bi bi-chevron-right"></i>`
+
+	case "last": // double right chevron
+		return `<i class="bi bi-chevron-double-right"></i>`
+
+	case "move-up": // up arrow
+		return `<i class="bi bi-arrow-up"></i>`
+
+	case "move-down": // down arrow
+		return `<i class="bi bi-arrow-down"></i>`
+
+	case "insert-above": // arrow into the top
+		return `<i class="bi bi-box-arrow-in-up"></i>`
+
+	case "insert-below": // arrow into the bottom
+		return `<i class="bi bi-box-arrow-in-down"></i>`
+
+	case "duplicate": // two overlapping pages
+		return `<i class="bi bi-copy"></i>`
+
+	case "collapse": // down chevron
+		return `<i class="bi bi-chevron-down"></i>`
+
+	case "expand": // right chevron
+		return `<i class="bi bi-chevron-right"></i>`
+
+	case "add-child": // node with a plus sign
+		return `<i class="bi bi-node-plus"></i>`
+
+	case "drag": // grip
+		return `<i class="bi bi-grip-vertical"></i>`
 	}
 
 	return name
@@ -278,17 +437,41 @@ func bind(r *http.Request) (map[string]any, error) {
 		return result, err
 	}
 
+	// Fields with several values (such as the "selected" checkboxes) are kept as slices
 	for key, value := range r.Form {
-		result[key] = value[0]
+		if len(value) == 1 {
+			result[key] = value[0]
+		} else {
+			result[key] = value
+		}
 	}
 
 	return result, nil
 }
 
+// viewURL removes the action parameters (add, edit, delete, etc.) from a request URL,
+// leaving only the view options (sort, page, search, etc.) so that the table can be
+// redrawn exactly as the user was viewing it.  The "duplicate", "next", and
+// "previous" parameters are kept, so that a newly duplicated row (or the row
+// that the user moved to with Tab) is opened for editing.  (Draw would open the
+// "next" or "previous" row even if "edit" were left in place.)
+func viewURL(requestURL *url.URL) *url.URL {
+
+	result := *requestURL
+	query := result.Query()
+
+	for _, action := range []string{"add", "insert", "edit", "cell", "focus", "delete", "version", "bulk", "column", "move", "to"} {
+		query.Del(action)
+	}
+
+	result.RawQuery = query.Encode()
+	return &result
+}
+
 // writeError writes an error to the http.ResponseWriter.
 // This is just some sugar to make the examples more readable.
 func writeError(writer http.ResponseWriter, err error) {
 	writer.WriteHeader(http.StatusInternalServerError)
-	_, _ = writer.Write([]byte(err.Error()))
+	_, _ = writer.Write([]byte(derp.RootMessage(err)))
 	derp.Report(err)
 }
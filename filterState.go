@@ -0,0 +1,288 @@
+package table
+
+import (
+	"math"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benpate/form"
+	"github.com/benpate/rosetta/convert"
+	"github.com/benpate/rosetta/schema"
+)
+
+// Filter types describe which controls a column offers, based on its schema element
+const (
+	filterTypeNone    = ""
+	filterTypeEnum    = "enum"
+	filterTypeBoolean = "boolean"
+	filterTypeNumber  = "number"
+	filterTypeDate    = "date"
+)
+
+// dateLayout is the format used by HTML date inputs, and therefore by date filters
+const dateLayout = "2006-01-02"
+
+// FilterState holds the per-column filters that a user has chosen.  It is read
+// from the query string ("filter.<path>", "min.<path>", and "max.<path>") and
+// can also be used outside of rendering, to apply the same predicate to a
+// caller's own data.
+type FilterState struct {
+	Schema  schema.Schema  // Schema for each row, used to interpret row values
+	Filters []ColumnFilter // Active filters, in column order
+}
+
+// ColumnFilter is the filter for a single column.  Which values apply depends on
+// the column's schema element: Equal is used by enumerations and booleans, while
+// Min and Max (both inclusive) are used by numbers and dates.
+type ColumnFilter struct {
+	Path  string // Path of the column being filtered
+	Equal string // Required value for enumerations, or "true"/"false" for booleans
+	Min   string // Lower bound for numbers, or a date in YYYY-MM-DD format
+	Max   string // Upper bound for numbers, or a date in YYYY-MM-DD format
+}
+
+// ParseFilterState reads the filters for a set of columns from the query string.
+// Values come from untrusted input, so filters on columns that cannot be filtered,
+// and values that are not valid for their column, are ignored.
+func ParseFilterState(rowSchema schema.Schema, columns []form.Element, query url.Values) FilterState {
+
+	result := FilterState{
+		Schema:  rowSchema,
+		Filters: make([]ColumnFilter, 0),
+	}
+
+	for _, column := range columns {
+
+		filter := ColumnFilter{Path: column.Path}
+
+		switch getFilterType(&rowSchema, column) {
+
+		case filterTypeEnum:
+			element, _ := rowSchema.GetStringElement(column.Path)
+			if value := query.Get("filter." + column.Path); slices.Contains(element.Enum, value) {
+				filter.Equal = value
+			}
+
+		case filterTypeBoolean:
+			if value := query.Get("filter." + column.Path); (value == "true") || (value == "false") {
+				filter.Equal = value
+			}
+
+		case filterTypeNumber:
+			filter.Min = validNumber(query.Get("min." + column.Path))
+			filter.Max = validNumber(query.Get("max." + column.Path))
+
+		case filterTypeDate:
+			filter.Min = validDate(query.Get("min." + column.Path))
+			filter.Max = validDate(query.Get("max." + column.Path))
+		}
+
+		if !filter.IsEmpty() {
+			result.Filters = append(result.Filters, filter)
+		}
+	}
+
+	return result
+}
+
+// IsEmpty returns TRUE if there are no active filters
+func (state FilterState) IsEmpty() bool {
+	return len(state.Filters) == 0
+}
+
+// Get returns the filter for the requested column path, if one is active
+func (state FilterState) Get(path string) ColumnFilter {
+
+	for _, filter := range state.Filters {
+		if filter.Path == path {
+			return filter
+		}
+	}
+
+	return ColumnFilter{Path: path}
+}
+
+// Match returns TRUE if the row passes every active filter
+func (state FilterState) Match(row any) bool {
+
+	for _, filter := range state.Filters {
+
+		element, ok := state.Schema.GetElement(filter.Path)
+
+		if !ok {
+			continue
+		}
+
+		value, _ := state.Schema.Get(row, filter.Path)
+
+		if !filter.match(element, value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// setQuery writes the active filters into a set of query parameters
+func (state FilterState) setQuery(query url.Values) {
+
+	for _, filter := range state.Filters {
+
+		if filter.Equal != "" {
+			query.Set("filter."+filter.Path, filter.Equal)
+		}
+
+		if filter.Min != "" {
+			query.Set("min."+filter.Path, filter.Min)
+		}
+
+		if filter.Max != "" {
+			query.Set("max."+filter.Path, filter.Max)
+		}
+	}
+}
+
+// IsEmpty returns TRUE if this filter does not restrict its column at all
+func (filter ColumnFilter) IsEmpty() bool {
+	return (filter.Equal == "") && (filter.Min == "") && (filter.Max == "")
+}
+
+// match returns TRUE if a single value passes this filter
+func (filter ColumnFilter) match(element schema.Element, value any) bool {
+
+	switch typed := element.(type) {
+
+	case schema.String:
+
+		if isDateFormat(typed.Format) {
+			return filter.matchDate(value)
+		}
+
+		return (filter.Equal == "") || (convert.String(value) == filter.Equal)
+
+	case schema.Boolean:
+		return (filter.Equal == "") || (convert.Bool(value) == (filter.Equal == "true"))
+
+	case schema.Integer, schema.Number:
+		number := convert.Float(value)
+
+		if (filter.Min != "") && (number < convert.Float(filter.Min)) {
+			return false
+		}
+
+		if (filter.Max != "") && (number > convert.Float(filter.Max)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchDate returns TRUE if a date value falls within this filter's range.
+// The maximum date includes the whole day.  Values that are not dates
+// never match a date range.
+func (filter ColumnFilter) matchDate(value any) bool {
+
+	if (filter.Min == "") && (filter.Max == "") {
+		return true
+	}
+
+	date, ok := convert.TimeOk(value, time.Time{})
+
+	if !ok {
+		return false
+	}
+
+	if minDate, err := time.Parse(dateLayout, filter.Min); err == nil {
+		if date.Before(minDate) {
+			return false
+		}
+	}
+
+	if maxDate, err := time.Parse(dateLayout, filter.Max); err == nil {
+		if !date.Before(maxDate.AddDate(0, 0, 1)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+/******************************************
+ * Helper Functions
+ ******************************************/
+
+// getFilterType returns the kind of filter controls that a column offers
+func getFilterType(rowSchema *schema.Schema, column form.Element) string {
+
+	if column.Path == "" {
+		return filterTypeNone
+	}
+
+	element, ok := rowSchema.GetElement(column.Path)
+
+	if !ok {
+		return filterTypeNone
+	}
+
+	switch typed := element.(type) {
+
+	case schema.String:
+
+		if len(typed.Enum) > 0 {
+			return filterTypeEnum
+		}
+
+		if isDateFormat(typed.Format) {
+			return filterTypeDate
+		}
+
+	case schema.Boolean:
+		return filterTypeBoolean
+
+	case schema.Integer, schema.Number:
+		return filterTypeNumber
+	}
+
+	return filterTypeNone
+}
+
+// isDateFormat returns TRUE if a schema.String format describes a date or time
+func isDateFormat(format string) bool {
+
+	for _, name := range strings.Fields(format) {
+		name, _, _ = strings.Cut(name, "=")
+		switch name {
+		case "date", "dateTime", "iso8601":
+			return true
+		}
+	}
+
+	return false
+}
+
+// validNumber returns the value if it is a valid, finite number, or "" if it is
+// not.  NaN and infinite values would exclude every row, so they are ignored.
+func validNumber(value string) string {
+
+	number, err := strconv.ParseFloat(value, 64)
+
+	if (err != nil) || math.IsNaN(number) || math.IsInf(number, 0) {
+		return ""
+	}
+
+	return value
+}
+
+// validDate returns the value if it is a valid YYYY-MM-DD date, or "" if it is not
+func validDate(value string) string {
+
+	if _, err := time.Parse(dateLayout, value); err != nil {
+		return ""
+	}
+
+	return value
+}
@@ -0,0 +1,175 @@
+package table
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/benpate/form"
+	"github.com/benpate/rosetta/mapof"
+	"github.com/benpate/rosetta/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+// filterSchema returns a row schema with one column of every filterable type.
+func filterSchema() schema.Schema {
+	return schema.New(schema.Object{
+		Properties: schema.ElementMap{
+			"name":   schema.String{},
+			"status": schema.String{Enum: []string{"New", "Done"}},
+			"active": schema.Boolean{},
+			"age":    schema.Integer{},
+			"score":  schema.Number{},
+			"due":    schema.String{Format: "date"},
+		},
+	})
+}
+
+// filterColumns returns one form column for each property in filterSchema.
+func filterColumns() []form.Element {
+	return []form.Element{
+		{Type: "text", Label: "Name", Path: "name"},
+		{Type: "select", Label: "Status", Path: "status"},
+		{Type: "checkbox", Label: "Active", Path: "active"},
+		{Type: "number", Label: "Age", Path: "age"},
+		{Type: "number", Label: "Score", Path: "score"},
+		{Type: "date", Label: "Due", Path: "due"},
+	}
+}
+
+/******************************************
+ * ParseFilterState()
+ ******************************************/
+
+func TestParseFilterState(t *testing.T) {
+
+	query := url.Values{
+		"filter.status": {"Done"},
+		"filter.active": {"false"},
+		"min.age":       {"18"},
+		"max.score":     {"9.5"},
+		"min.due":       {"2026-01-01"},
+		"max.due":       {"2026-01-31"},
+	}
+
+	state := ParseFilterState(filterSchema(), filterColumns(), query)
+
+	assert.Equal(t, []ColumnFilter{
+		{Path: "status", Equal: "Done"},
+		{Path: "active", Equal: "false"},
+		{Path: "age", Min: "18"},
+		{Path: "score", Max: "9.5"},
+		{Path: "due", Min: "2026-01-01", Max: "2026-01-31"},
+	}, state.Filters)
+}
+
+// Query values are untrusted, so anything invalid for its column is ignored.
+func TestParseFilterState_InvalidValues(t *testing.T) {
+
+	query := url.Values{
+		"filter.name":   {"Bob"},      // plain strings use the search box instead
+		"filter.status": {"Sideways"}, // not in the enumeration
+		"filter.active": {"maybe"},    // not a boolean
+		"min.age":       {"old"},      // not a number
+		"max.age":       {"NaN"},      // not a finite number
+		"min.score":     {"1e999"},
+		"max.score":     {"-Inf"},
+		"min.due":       {"yesterday"},
+		"filter.secret": {"x"}, // not a column
+	}
+
+	state := ParseFilterState(filterSchema(), filterColumns(), query)
+
+	assert.True(t, state.IsEmpty())
+}
+
+/******************************************
+ * FilterState.Match()
+ ******************************************/
+
+func TestFilterState_Match(t *testing.T) {
+
+	row := mapof.Any{"status": "Done", "active": true, "age": 30, "score": 7.25, "due": "2026-01-31"}
+
+	check := func(expected bool, query url.Values) {
+		state := ParseFilterState(filterSchema(), filterColumns(), query)
+		assert.Equal(t, expected, state.Match(row), "query: %v", query)
+	}
+
+	check(true, url.Values{}) // no filters match everything
+
+	check(true, url.Values{"filter.status": {"Done"}})
+	check(false, url.Values{"filter.status": {"New"}})
+
+	check(true, url.Values{"filter.active": {"true"}})
+	check(false, url.Values{"filter.active": {"false"}})
+
+	check(true, url.Values{"min.age": {"30"}, "max.age": {"30"}}) // inclusive
+	check(false, url.Values{"min.age": {"31"}})
+	check(false, url.Values{"max.score": {"7"}})
+	check(true, url.Values{"min.score": {"7.25"}})
+
+	check(true, url.Values{"max.due": {"2026-01-31"}}) // the whole day is included
+	check(false, url.Values{"min.due": {"2026-02-01"}})
+	check(true, url.Values{"min.due": {"2026-01-01"}, "max.due": {"2026-12-31"}})
+
+	// Every filter must match
+	check(false, url.Values{"filter.status": {"Done"}, "filter.active": {"false"}})
+}
+
+// Rows whose dates cannot be read never match a date range
+func TestFilterState_MatchBadDate(t *testing.T) {
+
+	state := ParseFilterState(filterSchema(), filterColumns(), url.Values{"min.due": {"2026-01-01"}})
+
+	assert.False(t, state.Match(mapof.Any{"due": "not a date"}))
+	assert.False(t, state.Match(mapof.Any{}))
+}
+
+func TestFilterState_Get(t *testing.T) {
+
+	state := ParseFilterState(filterSchema(), filterColumns(), url.Values{"min.age": {"5"}})
+
+	assert.Equal(t, ColumnFilter{Path: "age", Min: "5"}, state.Get("age"))
+	assert.Equal(t, ColumnFilter{Path: "score"}, state.Get("score"))
+}
+
+func TestFilterState_SetQuery(t *testing.T) {
+
+	query := url.Values{"filter.status": {"Done"}, "min.age": {"1"}, "max.age": {"9"}, "min.age.extra": {"x"}}
+	state := ParseFilterState(filterSchema(), filterColumns(), query)
+
+	result := url.Values{}
+	state.setQuery(result)
+
+	assert.Equal(t, "filter.status=Done&max.age=9&min.age=1", result.Encode())
+}
+
+/******************************************
+ * Helper Functions
+ ******************************************/
+
+func TestIsDateFormat(t *testing.T) {
+	assert.True(t, isDateFormat("date"))
+	assert.True(t, isDateFormat("dateTime"))
+	assert.True(t, isDateFormat("no-html iso8601"))
+	assert.False(t, isDateFormat(""))
+	assert.False(t, isDateFormat("email"))
+}
+
+func TestGetFilterType(t *testing.T) {
+
+	rowSchema := filterSchema()
+
+	check := func(path string, expected string) {
+		assert.Equal(t, expected, getFilterType(&rowSchema, form.Element{Path: path}), "path: %s", path)
+	}
+
+	check("name", filterTypeNone)
+	check("status", filterTypeEnum)
+	check("active", filterTypeBoolean)
+	check("age", filterTypeNumber)
+	check("score", filterTypeNumber)
+	check("due", filterTypeDate)
+	check("missing", filterTypeNone)
+	check("", filterTypeNone)
+}
@@ -4,10 +4,14 @@ package table
 
 import (
 	"net/url"
+	"slices"
+	"strconv"
 
 	"github.com/benpate/derp"
 	"github.com/benpate/form"
 	"github.com/benpate/rosetta/convert"
+	"github.com/benpate/rosetta/mapof"
+	"github.com/benpate/rosetta/null"
 	"github.com/benpate/rosetta/schema"
 )
 
@@ -22,10 +26,57 @@ type Table struct {
 	Icons     IconProvider   // IconProvider generates HTML for icons
 
 	// Optional Fields
-	LookupProvider form.LookupProvider // Optional dependency to provide lookup data for fields
-	CanAdd         bool                // If TRUE, then users can add new rows to the table
-	CanEdit        bool                // If TRUE, then users can edit existing rows in the table
-	CanDelete      bool                // If TRUE, then users can delete existing rows in the table
+	LookupProvider form.LookupProvider     // Optional dependency to provide lookup data for fields
+	CanAdd         bool                    // If TRUE, then users can add new rows to the table
+	CanEdit        bool                    // If TRUE, then users can edit existing rows in the table
+	CanDelete      bool                    // If TRUE, then users can delete existing rows in the table
+	CanMove        bool                    // If TRUE, then users can change the order of rows in the table
+	CanInsert      bool                    // If TRUE (and CanAdd is TRUE), then users can insert new rows above or below existing rows
+	CanDuplicate   bool                    // If TRUE, then users can copy an existing row into a new row immediately after it
+	CanSort        bool                    // If TRUE, then users can sort the table by clicking on column headers
+	CanSearch      bool                    // If TRUE, then users can filter rows by searching for text
+	CanFilter      bool                    // If TRUE, then users can filter rows using controls for each column
+	CanSelect      bool                    // If TRUE, then users can select rows with checkboxes and edit or delete all of them at once
+	KeyPath        string                  // Optional path to a unique key in each row.  If present, rows are addressed by key instead of by index
+	PageSize       int                     // If greater than zero, then the table displays this many rows per page
+	DragHandle     bool                    // If TRUE (and CanMove is TRUE), then rows can also be reordered by dragging a handle
+	CellEdit       bool                    // If TRUE, then clicking a cell edits only that cell, instead of the whole row
+	AutoSave       bool                    // If TRUE, then each field in the edit row is saved as soon as it changes
+	Caption        string                  // Optional caption that describes the table.  If empty, the Form's label is used instead
+	Keyboard       bool                    // If TRUE, then users can move between cells and rows, and open and close editors, with the keyboard
+	VersionPath    string                  // Optional path to a version field in each row.  If empty, rows are versioned by a hash of their values
+	Translator     Translator              // Optional translations for the text that users see.  If nil, then English is used
+	Locale         string                  // Optional locale (such as "de" or "es-MX") of the current request, passed to the Translator
+	LocaleFormats  map[string]LocaleFormat // Optional number and date formats by locale, used before the defaults (see DefaultLocaleFormats)
+	GroupPath      string                  // Optional path to a column that rows are grouped by.  Each group has a header that collapses or expands it
+	GroupTotals    bool                    // If TRUE (and GroupPath is set), then each group ends with the aggregates of its own rows
+	Details        *form.Element           // Optional fields that are displayed in an expandable row beneath each row, instead of in columns
+	ParentPath     string                  // Optional path to the key of each row's parent.  If present (along with KeyPath), rows are displayed as a tree
+	CascadeDelete  bool                    // If TRUE (and rows are a tree), then deleting a row also deletes its descendants, instead of moving them up to its parent
+
+	// Per-Request State
+	view         viewState         // View options (sorting, paging, filtering, etc.) read from the query string by Draw
+	autoSaving   bool              // If TRUE, then the edit row is being redrawn after one of its fields was saved
+	editCell     null.Int          // Column that is being edited on its own (see DoEditCell).  Unset means the whole row
+	insertRow    null.Int          // Position where the add row inserts a new row (unset means the end of the table)
+	announcement url.Values        // Query of the last action, described to screen readers when the table is drawn (see Announce)
+	selecting    bool              // If TRUE, then each row is drawn with a checkbox that selects it
+	selected     []string          // Checkbox values of the rows that are already selected (see selectedValue)
+	bulkColumn   null.Int          // Column that is being changed in every selected row (see DoBulkEdit)
+	drawingForm  bool              // If TRUE, then the table is drawn inside a form, so its sub-tables are view-only
+	parentPath   string            // Path (from the outermost table's Object) to the row that contains this nested table
+	addParent    null.Int          // Row (by index) that a new row is added beneath, when rows are a tree
+	tree         rowTree           // Hierarchy of the displayed rows, when rows are a tree
+	rowOrder     []int             // Display order of the rows (by index) after sorting, searching, filtering, and grouping
+	rowPositions map[int]int       // Position of each displayed row (by index) in rowOrder
+	groups       []rowGroup        // Groups of rows that share the same GroupPath value, in display order
+	rowGroups    []int             // Group (by index into groups) of each row (by index)
+	rowKeys      []string          // KeyPath value of each row (by index) collected by drawTable
+	rowVersions  []string          // Version token of each row (by index) collected by drawTable
+	submitted    mapof.Any         // Values submitted by the user, drawn back into the edit row by DrawConflict and DrawErrors
+	conflict     bool              // If TRUE, then the edit row also shows the newer values that are already saved
+	fieldErrors  map[string]string // Validation messages for submitted values (by path), drawn by DrawErrors
+	rowError     string            // Validation message that does not belong to a single field
 }
 
 // New returns a fully initialized Table widget (with all required fields)
@@ -69,19 +120,194 @@ func (widget Table) AllowDelete() Table {
 	return widget
 }
 
-// AllowAll returns a copy of the table that allows all write actions (Add, Edit, Delete).
+// AllowMove returns a copy of the table that allows changing the order of rows.
+func (widget Table) AllowMove() Table {
+	widget.CanMove = true
+	return widget
+}
+
+// AllowInsert returns a copy of the table that allows inserting new rows between
+// existing rows.  Adding rows must also be allowed.
+func (widget Table) AllowInsert() Table {
+	widget.CanInsert = true
+	return widget
+}
+
+// AllowDuplicate returns a copy of the table that allows copying existing rows.
+func (widget Table) AllowDuplicate() Table {
+	widget.CanDuplicate = true
+	return widget
+}
+
+// AllowSelect returns a copy of the table that allows selecting several rows at
+// once, so that they can be edited or deleted together.
+func (widget Table) AllowSelect() Table {
+	widget.CanSelect = true
+	return widget
+}
+
+// AllowSort returns a copy of the table that allows sorting rows by column.
+func (widget Table) AllowSort() Table {
+	widget.CanSort = true
+	return widget
+}
+
+// AllowSearch returns a copy of the table that allows filtering rows by free-text search.
+func (widget Table) AllowSearch() Table {
+	widget.CanSearch = true
+	return widget
+}
+
+// AllowFilter returns a copy of the table that allows filtering rows by column values.
+func (widget Table) AllowFilter() Table {
+	widget.CanFilter = true
+	return widget
+}
+
+// AllowAll returns a copy of the table that allows all write actions (Add, Insert, Duplicate, Edit, Delete, Move).
 func (widget Table) AllowAll() Table {
 	widget.CanAdd = true
+	widget.CanInsert = true
+	widget.CanDuplicate = true
 	widget.CanEdit = true
 	widget.CanDelete = true
+	widget.CanMove = true
 	return widget
 }
 
 // AllowNone returns a copy of the table that disallows all write actions.
 func (widget Table) AllowNone() Table {
 	widget.CanAdd = false
+	widget.CanInsert = false
+	widget.CanDuplicate = false
 	widget.CanEdit = false
 	widget.CanDelete = false
+	widget.CanMove = false
+	return widget
+}
+
+// UsePageSize returns a copy of the table that displays the given number of rows per page.
+// A page size of zero displays all rows at once.
+func (widget Table) UsePageSize(pageSize int) Table {
+	widget.PageSize = pageSize
+	return widget
+}
+
+// UseDragHandle returns a copy of the table that displays a handle for dragging
+// rows into a new position.  Rows are only draggable when CanMove is also TRUE.
+func (widget Table) UseDragHandle() Table {
+	widget.DragHandle = true
+	return widget
+}
+
+// UseCellEdit returns a copy of the table where clicking a cell edits only that
+// cell.  The edit button in each row still edits the whole row.
+func (widget Table) UseCellEdit() Table {
+	widget.CellEdit = true
+	return widget
+}
+
+// UseAutoSave returns a copy of the table that saves each field in the edit row
+// as soon as it changes.  The save and cancel buttons remain as a fallback.
+func (widget Table) UseAutoSave() Table {
+	widget.AutoSave = true
+	return widget
+}
+
+// UseKeyboard returns a copy of the table that can be used with the keyboard:
+// the arrow keys move between cells, Enter edits the focused cell, Escape
+// cancels editing, and Tab or Shift-Tab saves the edit row and moves on to the
+// next or previous row.  This includes a small script in the rendered table.
+func (widget Table) UseKeyboard() Table {
+	widget.Keyboard = true
+	return widget
+}
+
+// UseCaption returns a copy of the table with a caption that describes it.
+func (widget Table) UseCaption(caption string) Table {
+	widget.Caption = caption
+	return widget
+}
+
+// UseGroupPath returns a copy of the table that groups rows by the value at
+// groupPath (such as "status").  Each group has a header that collapses or
+// expands it.  Rows cannot be moved or inserted while they are grouped.
+func (widget Table) UseGroupPath(groupPath string) Table {
+	widget.GroupPath = groupPath
+	return widget
+}
+
+// UseGroupTotals returns a copy of the table that ends each group with the
+// aggregates (see the "column-aggregate" option) of the rows in that group.
+func (widget Table) UseGroupTotals() Table {
+	widget.GroupTotals = true
+	return widget
+}
+
+// UseDetails returns a copy of the table that displays the fields in details
+// beneath each row, instead of in columns.  Each row is expanded (or collapsed)
+// on its own in view mode, and the row being edited always shows editors for
+// these fields.  With auto-save, each of these fields is saved as it changes.
+func (widget Table) UseDetails(details form.Element) Table {
+	widget.Details = &details
+	return widget
+}
+
+// UseParentPath returns a copy of the table that displays rows as a tree, where
+// the value at parentPath (such as "parentId") is the KeyPath value of each
+// row's parent.  Rows with an empty (or unknown) parent are at the top of the
+// tree.  Each row with children has a button that collapses or expands them,
+// and new rows can be added beneath any row.  A KeyPath is required.  Rows
+// cannot be moved, inserted, or grouped while they are a tree.
+func (widget Table) UseParentPath(parentPath string) Table {
+	widget.ParentPath = parentPath
+	return widget
+}
+
+// UseCascadeDelete returns a copy of the table that deletes all of a row's
+// descendants along with it.  Otherwise, the children of a deleted row move up
+// to the deleted row's parent.
+func (widget Table) UseCascadeDelete() Table {
+	widget.CascadeDelete = true
+	return widget
+}
+
+// UseKeyPath returns a copy of the table that addresses rows by the value at keyPath
+// (such as "taskId") instead of by their position in the array.
+func (widget Table) UseKeyPath(keyPath string) Table {
+	widget.KeyPath = keyPath
+	return widget
+}
+
+// UseVersionPath returns a copy of the table that uses the value at versionPath
+// (such as "revision") to detect rows that have changed since they were displayed.
+// The caller must change this value whenever a row is saved.  Rows that have no
+// value at versionPath fall back to a hash of their values.
+func (widget Table) UseVersionPath(versionPath string) Table {
+	widget.VersionPath = versionPath
+	return widget
+}
+
+// UseTranslator returns a copy of the table that displays its text (and the
+// messages in the errors returned by Do) using the given Translator.
+func (widget Table) UseTranslator(translator Translator) Table {
+	widget.Translator = translator
+	return widget
+}
+
+// UseLocale returns a copy of the table that displays its text in the given
+// locale (such as "de" or "es-MX").  Locales usually change with each request,
+// so call this on the shared table before calling Draw or Do.
+func (widget Table) UseLocale(locale string) Table {
+	widget.Locale = locale
+	return widget
+}
+
+// UseLocaleFormats returns a copy of the table that formats numbers and dates
+// with the given formats (by locale).  Locales that are not included use the
+// defaults (see DefaultLocaleFormats).
+func (widget Table) UseLocaleFormats(formats map[string]LocaleFormat) Table {
+	widget.LocaleFormats = formats
 	return widget
 }
 
@@ -96,7 +322,9 @@ func (widget Table) UseLookupProvider(lookupProvider form.LookupProvider) Table
  ******************************************/
 
 // getURL returns a safe URL to use in callbacks, merging the action's query
-// parameters into any query string the TargetURL already has.
+// parameters into any query string the TargetURL already has.  Rows are addressed
+// by key when the table has a KeyPath.  The current view options (sorting, paging,
+// filtering, etc.) are carried forward so that the view survives the round trip.
 func (widget Table) getURL(action string, row int, col int) string {
 
 	parsed, err := url.Parse(widget.TargetURL)
@@ -107,15 +335,121 @@ func (widget Table) getURL(action string, row int, col int) string {
 	}
 
 	query := parsed.Query()
+	widget.view.setQuery(query)
 
 	switch action {
+	case "view":
+		// No additional parameters.  Just return to the current view
 	case "add":
 		query.Set("add", "true")
+	case "insert":
+		query.Set("insert", strconv.Itoa(row))
 	case "edit":
-		query.Set("edit", convert.String(row))
+		query.Set("edit", widget.rowID(row))
+		query.Set("focus", convert.String(col))
+	case "edit-next":
+		// Saves the row, then edits the row at "col"
+		query.Set("edit", widget.rowID(row))
+		query.Set("next", widget.rowID(col))
+	case "edit-previous":
+		// Saves the row, then edits the row at "col" starting from its last column
+		query.Set("edit", widget.rowID(row))
+		query.Set("previous", widget.rowID(col))
+	case "cell":
+		query.Set("cell", widget.rowID(row))
 		query.Set("focus", convert.String(col))
+	case "autosave":
+		query.Set("autosave", widget.rowID(row))
+		query.Set("focus", convert.String(col))
+	case "duplicate":
+		query.Set("duplicate", widget.rowID(row))
 	case "delete":
-		query.Set("delete", convert.String(row))
+		query.Set("delete", widget.rowID(row))
+
+		if (row >= 0) && (row < len(widget.rowVersions)) {
+			query.Set("version", widget.rowVersions[row])
+		}
+	case "bulk-delete":
+		// The selected rows are posted in the "selected" form value
+		query.Set("bulk", "delete")
+	case "bulk-edit":
+		// Changes the column at "col" in every selected row.  A negative
+		// column means that the column is chosen by the user instead.
+		query.Set("bulk", "edit")
+
+		if col >= 0 {
+			query.Set("column", strconv.Itoa(col))
+		}
+	case "move":
+		// Moves the row to the position of the row at "col"
+		query.Set("move", widget.rowID(row))
+		query.Set("to", widget.rowID(col))
+	case "drop":
+		// Drag-and-drop moves another row to this row's position.  The moved row
+		// is only known when it is dropped, so it is posted in the "move" form value.
+		query.Set("to", widget.rowID(row))
+	case "sort":
+		path := widget.Form.Children[col].Path
+		query.Set("sort", path)
+		query.Del("page") // A new sort order starts again from the first page
+
+		// Clicking the current sort column again reverses its direction
+		if (widget.view.SortPath == path) && !widget.view.SortDesc {
+			query.Set("dir", "desc")
+		} else {
+			query.Set("dir", "asc")
+		}
+	case "collapse":
+		// Hides the rows in the group at "row"
+		if (row >= 0) && (row < len(widget.groups)) && !slices.Contains(query["collapsed"], widget.groups[row].Key) {
+			query.Add("collapsed", widget.groups[row].Key)
+		}
+	case "expand":
+		// Shows the rows in the group at "row" again
+		if (row >= 0) && (row < len(widget.groups)) {
+			collapsed := slices.DeleteFunc(slices.Clone(query["collapsed"]), func(key string) bool {
+				return key == widget.groups[row].Key
+			})
+			query["collapsed"] = collapsed
+		}
+	case "collapse-row":
+		// Hides the children of the row at "row" in a tree
+		if key := widget.rowID(row); !slices.Contains(query["collapsed"], key) {
+			query.Add("collapsed", key)
+		}
+	case "expand-row":
+		// Shows the children of the row at "row" in a tree again
+		key := widget.rowID(row)
+		query["collapsed"] = slices.DeleteFunc(slices.Clone(query["collapsed"]), func(value string) bool {
+			return value == key
+		})
+	case "add-child":
+		// Adds a new row beneath the row at "row" in a tree
+		query.Set("add", "true")
+		query.Set("parent", widget.rowID(row))
+	case "details":
+		// Shows the Details of the row at "row" beneath it
+		if key := widget.rowID(row); !slices.Contains(query["expand"], key) {
+			query.Add("expand", key)
+		}
+	case "hide-details":
+		// Hides the Details of the row at "row" again
+		key := widget.rowID(row)
+		query["expand"] = slices.DeleteFunc(slices.Clone(query["expand"]), func(value string) bool {
+			return value == key
+		})
+	case "page":
+		query.Set("page", convert.String(row))
+	case "search":
+		// The search box supplies its own "q" value, and new results start from the first page
+		query.Del("q")
+		query.Del("page")
+	case "filter":
+		// The filter controls supply their own values, and new results start from the first page
+		for key := range widget.view.Filters {
+			query.Del(key)
+		}
+		query.Del("page")
 	default:
 		return widget.TargetURL
 	}
@@ -124,6 +458,16 @@ func (widget Table) getURL(action string, row int, col int) string {
 	return parsed.String()
 }
 
+// message returns the text of a message in the table's locale
+func (widget Table) message(key string, args ...any) string {
+
+	if widget.Translator == nil {
+		return english.Translate(widget.Locale, key, args...)
+	}
+
+	return widget.Translator.Translate(widget.Locale, key, args...)
+}
+
 func (widget Table) getTableElement() (schema.Array, error) {
 
 	const location = "table.Widget.getTableElement"
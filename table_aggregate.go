@@ -0,0 +1,209 @@
+package table
+
+import (
+	stdhtml "html"
+	"strconv"
+
+	"github.com/benpate/form"
+	"github.com/benpate/html"
+	"github.com/benpate/rosetta/convert"
+	"github.com/benpate/rosetta/schema"
+)
+
+/******************************************
+ * Column Aggregates
+ ******************************************/
+
+// Column aggregates, set in the "column-aggregate" option of each column in the
+// Form.  Aggregates are drawn in the table's footer, and are computed over every
+// row that passes the current search and filters (on every page).  Empty values
+// are skipped.
+const (
+	AggregateSum           = "sum"            // Total of a number column
+	AggregateAverage       = "average"        // Mean of a number column
+	AggregateMin           = "min"            // Smallest value, in sort order
+	AggregateMax           = "max"            // Largest value, in sort order
+	AggregateCount         = "count"          // Number of values
+	AggregateCountDistinct = "count-distinct" // Number of different values
+)
+
+// averageDecimals is the number of decimal places in averages that are not
+// formatted, so that a repeating fraction (such as 1/3) does not fill the column
+const averageDecimals = 2
+
+// hasAggregates returns TRUE if any column in the table has an aggregate
+func (widget Table) hasAggregates() bool {
+
+	for _, field := range widget.Form.Children {
+		if field.Options.GetString("column-aggregate") != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// drawAggregates writes a row with the aggregate of each column, computed over
+// the requested rows (by index)
+func (widget Table) drawAggregates(rowSchema *schema.Schema, rows []any, rowOrder []int, className string, b *html.Builder) {
+
+	b.TR().Class(className)
+
+	if widget.selecting {
+		b.TD().Class("grid-cell").Close()
+	}
+
+	for _, field := range widget.Form.Children {
+		b.TD().Class("grid-cell", "grid-aggregate").InnerHTML(widget.aggregateHTML(rowSchema, field, rows, rowOrder)).Close()
+	}
+
+	b.TD().Class("grid-cell", "grid-controls").Close()
+	b.Close() // TR
+}
+
+// aggregateHTML returns the aggregate of a single column, formatted in the same
+// way as the column's cells: columns without a "column-format" option show raw
+// values, just like their cells do.  Counts are never shown as currencies (or
+// other formats) but are grouped like numbers when the column has a format.
+func (widget Table) aggregateHTML(rowSchema *schema.Schema, field form.Element, rows []any, rowOrder []int) string {
+
+	aggregate := field.Options.GetString("column-aggregate")
+	value, ok := widget.aggregate(rowSchema, field, aggregate, rows, rowOrder)
+
+	if !ok {
+		return ""
+	}
+
+	if field.Options.GetString("column-format") == "" {
+		return stdhtml.EscapeString(rawAggregate(aggregate, value))
+	}
+
+	if (aggregate == AggregateCount) || (aggregate == AggregateCountDistinct) {
+		return stdhtml.EscapeString(widget.formatNumber(widget.localeFormat(), FormatNumber, convert.Float(value), form.Element{}))
+	}
+
+	if result, ok := widget.formatValue(field, value); ok {
+		return result
+	}
+
+	return stdhtml.EscapeString(rawAggregate(aggregate, value))
+}
+
+// aggregate computes one aggregate over the requested rows, using the rules of
+// the column's schema element.  It returns FALSE if there is nothing to show
+// (because there are no values, or because the aggregate does not apply to
+// this kind of column, such as the sum of a string).
+func (widget Table) aggregate(rowSchema *schema.Schema, field form.Element, aggregate string, rows []any, rowOrder []int) (any, bool) {
+
+	element, ok := rowSchema.GetElement(field.Path)
+
+	if (field.Path == "") || !ok {
+		return nil, false
+	}
+
+	// Collect the non-empty values from each row
+	values := make([]any, 0, len(rowOrder))
+
+	for _, rowIndex := range rowOrder {
+		if value, err := rowSchema.Get(rows[rowIndex], field.Path); (err == nil) && (value != nil) && (value != "") {
+			values = append(values, value)
+		}
+	}
+
+	switch aggregate {
+
+	case AggregateCount:
+		return len(values), true
+
+	case AggregateCountDistinct:
+		distinct := make(map[string]struct{}, len(values))
+		for _, value := range values {
+			distinct[convert.String(value)] = struct{}{}
+		}
+		return len(distinct), true
+	}
+
+	if len(values) == 0 {
+		return nil, false
+	}
+
+	switch aggregate {
+
+	case AggregateSum, AggregateAverage:
+
+		var sumInt int64
+		var sumFloat float64
+
+		switch element.(type) {
+
+		case schema.Integer:
+			for _, value := range values {
+				sumInt += convert.Int64(value)
+			}
+			sumFloat = float64(sumInt)
+
+		case schema.Number:
+			for _, value := range values {
+				sumFloat += convert.Float(value)
+			}
+
+		default:
+			return nil, false
+		}
+
+		if aggregate == AggregateAverage {
+			return sumFloat / float64(len(values)), true
+		}
+
+		if _, isInteger := element.(schema.Integer); isInteger {
+			return sumInt, true
+		}
+
+		return sumFloat, true
+
+	case AggregateMin, AggregateMax:
+
+		result := values[0]
+
+		for _, value := range values[1:] {
+			comparison := compareValues(element, value, result)
+			if ((aggregate == AggregateMin) && (comparison < 0)) || ((aggregate == AggregateMax) && (comparison > 0)) {
+				result = value
+			}
+		}
+
+		// Numbers are returned as numbers, so that they are written in the table's locale
+		switch element.(type) {
+		case schema.Integer:
+			return convert.Int64(result), true
+		case schema.Number:
+			return convert.Float(result), true
+		}
+
+		return result, true
+	}
+
+	return nil, false
+}
+
+/******************************************
+ * Helper Functions
+ ******************************************/
+
+// rawAggregate returns the text of an aggregate whose column has no format.
+// Floating point values are written with only the digits they need, except
+// for averages, which are rounded to averageDecimals.
+func rawAggregate(aggregate string, value any) string {
+
+	number, isFloat := value.(float64)
+
+	if !isFloat {
+		return convert.String(value)
+	}
+
+	if aggregate == AggregateAverage {
+		return strconv.FormatFloat(number, 'f', averageDecimals, 64)
+	}
+
+	return strconv.FormatFloat(number, 'f', -1, 64)
+}
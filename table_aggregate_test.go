@@ -0,0 +1,206 @@
+package table
+
+import (
+	"bytes"
+	"html"
+	"strings"
+	"testing"
+
+	"github.com/benpate/form"
+	"github.com/benpate/rosetta/mapof"
+	"github.com/benpate/rosetta/schema"
+	"github.com/benpate/rosetta/sliceof"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAggregateTable returns a filterable Table of expenses with an aggregate in every column.
+func newAggregateTable() Table {
+
+	s := schema.Schema{
+		Element: schema.Object{
+			Properties: schema.ElementMap{
+				"data": schema.Array{
+					Items: schema.Object{
+						Properties: schema.ElementMap{
+							"name":     schema.String{},
+							"category": schema.String{Enum: []string{"Rent", "Food", "Travel"}},
+							"amount":   schema.Number{},
+							"people":   schema.Integer{},
+							"date":     schema.String{Format: "date"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	f := form.Element{
+		Type: "layout-vertical",
+		Children: []form.Element{
+			{Type: "text", Label: "Name", Path: "name", Options: mapof.Any{"column-aggregate": AggregateCount}},
+			{Type: "select", Label: "Category", Path: "category", Options: mapof.Any{"column-aggregate": AggregateCountDistinct}},
+			{Type: "text", Label: "Amount", Path: "amount", Options: mapof.Any{"column-aggregate": AggregateSum, "column-format": FormatCurrency, "column-currency": "USD"}},
+			{Type: "text", Label: "People", Path: "people", Options: mapof.Any{"column-aggregate": AggregateAverage}},
+			{Type: "text", Label: "Date", Path: "date", Options: mapof.Any{"column-aggregate": AggregateMax, "column-format": FormatDate}},
+		},
+	}
+
+	db := &testDatabase{
+		Data: sliceof.Object[mapof.Any]{
+			mapof.Any{"name": "Office", "category": "Rent", "amount": 1200.0, "people": 4, "date": "2026-10-01"},
+			mapof.Any{"name": "Lunch", "category": "Food", "amount": 45.5, "people": 3, "date": "2026-10-14"},
+			mapof.Any{"name": "Dinner", "category": "Food", "amount": 120.25, "people": 6, "date": "2026-10-09"},
+			mapof.Any{"name": "", "category": "Travel", "amount": 900.0, "people": 1, "date": "2026-09-30"},
+		},
+	}
+
+	return New(&s, &f, db, "data", testIconProvider{}, "http://localhost/table").AllowFilter()
+}
+
+// aggregateRow returns the HTML of the aggregates row
+func aggregateRow(t *testing.T, result string) string {
+	start := strings.Index(result, `<tr class="grid-aggregates">`)
+	require.GreaterOrEqual(t, start, 0)
+	end := strings.Index(result[start:], "</tr>")
+	return result[start : start+end]
+}
+
+func TestDraw_Aggregates(t *testing.T) {
+
+	table := newAggregateTable()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+
+	row := aggregateRow(t, html.UnescapeString(buffer.String()))
+	assert.Equal(t, `<tr class="grid-aggregates">`+
+		`<td class="grid-cell grid-aggregate">3</td>`+ // the empty name is not counted
+		`<td class="grid-cell grid-aggregate">3</td>`+
+		`<td class="grid-cell grid-aggregate">$2,265.75</td>`+
+		`<td class="grid-cell grid-aggregate">3.50</td>`+ // unformatted columns show raw values, like their cells
+		`<td class="grid-cell grid-aggregate"><time datetime="2026-10-14T00:00:00Z">Oct 14, 2026</time></td>`+
+		`<td class="grid-cell grid-controls"></td>`, row)
+
+	// Aggregates come before the "Add a Row" button
+	assert.Contains(t, buffer.String(), `<tfoot><tr class="grid-aggregates">`)
+	assert.Contains(t, buffer.String(), `</tr><tr class="grid-footer">`)
+}
+
+// Aggregates only include the rows that pass the search and filters, on every page
+func TestDraw_AggregatesFiltered(t *testing.T) {
+
+	table := newAggregateTable().UsePageSize(1)
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x?filter.category=Food"), &buffer))
+
+	row := aggregateRow(t, html.UnescapeString(buffer.String()))
+	assert.Contains(t, row, ">$165.75<")
+	assert.Contains(t, row, ">4.50<")
+	assert.Contains(t, row, ">Oct 14, 2026<")
+}
+
+func TestDraw_AggregatesLocale(t *testing.T) {
+
+	table := newAggregateTable().UseLocale("de")
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+
+	row := aggregateRow(t, html.UnescapeString(buffer.String()))
+	assert.Contains(t, row, ">2.265,75 $<")
+	assert.Contains(t, row, ">3.50<") // only formatted columns follow the locale
+	assert.Contains(t, row, ">14.10.2026<")
+}
+
+// Tables without aggregates (or an add button) have no footer
+func TestDraw_NoAggregates(t *testing.T) {
+
+	table := newTestTable().AllowNone()
+
+	result, err := table.DrawViewString()
+
+	require.NoError(t, err)
+	assert.NotContains(t, result, "<tfoot")
+	assert.NotContains(t, result, "grid-aggregates")
+}
+
+func TestAggregate(t *testing.T) {
+
+	table := newAggregateTable()
+	rowSchema := schema.New(schema.Object{
+		Properties: schema.ElementMap{
+			"name":   schema.String{},
+			"amount": schema.Number{},
+			"people": schema.Integer{},
+		},
+	})
+
+	rows := []any{
+		mapof.Any{"name": "b", "amount": 2.5, "people": 2},
+		mapof.Any{"name": "A", "amount": 1.0, "people": 7},
+		mapof.Any{"name": "c", "people": 3},
+	}
+	rowOrder := []int{0, 1, 2}
+
+	aggregate := func(path string, aggregate string) any {
+		value, ok := table.aggregate(&rowSchema, form.Element{Path: path}, aggregate, rows, rowOrder)
+		if !ok {
+			return nil
+		}
+		return value
+	}
+
+	assert.Equal(t, int64(12), aggregate("people", AggregateSum))
+	assert.Equal(t, 3.5, aggregate("amount", AggregateSum))
+	assert.Equal(t, 1.75, aggregate("amount", AggregateAverage)) // missing values are skipped
+	assert.Equal(t, int64(2), aggregate("people", AggregateMin))
+	assert.Equal(t, 2.5, aggregate("amount", AggregateMax))
+	assert.Equal(t, "A", aggregate("name", AggregateMin)) // strings compare without regard to case
+	assert.Equal(t, "c", aggregate("name", AggregateMax))
+	assert.Equal(t, 2, aggregate("amount", AggregateCount))
+	assert.Nil(t, aggregate("name", AggregateSum))    // strings cannot be added
+	assert.Nil(t, aggregate("name", "median"))        // unknown aggregates are ignored
+	assert.Nil(t, aggregate("missing", AggregateMax)) // and so are unknown columns
+
+	rowOrder = []int{}
+	assert.Equal(t, 0, aggregate("amount", AggregateCount))
+	assert.Nil(t, aggregate("amount", AggregateSum)) // nothing to add up
+}
+
+// Totals of unformatted columns are not grouped, matching the cells above them
+func TestDraw_AggregatesUnformatted(t *testing.T) {
+
+	table := newAggregateTable()
+	table.Form.Children[3].Options["column-aggregate"] = AggregateSum
+	table.Object.(*testDatabase).Data[0]["people"] = 1230
+
+	var buffer bytes.Buffer
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+
+	assert.Contains(t, aggregateRow(t, buffer.String()), ">1240<")
+}
+
+// Unformatted floats only show the digits they need, and averages are rounded
+func TestDraw_AggregatesUnformattedFloats(t *testing.T) {
+
+	table := newAggregateTable()
+	delete(table.Form.Children[2].Options, "column-format")
+
+	db := table.Object.(*testDatabase)
+	db.Data = db.Data[:3]
+	db.Data[0]["people"], db.Data[1]["people"], db.Data[2]["people"] = 1, 0, 0
+
+	var buffer bytes.Buffer
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+
+	row := aggregateRow(t, buffer.String())
+	assert.Contains(t, row, ">1365.75<")
+	assert.Contains(t, row, ">0.33<")
+
+	table.Form.Children[2].Options["column-aggregate"] = AggregateMax
+	buffer.Reset()
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+	assert.Contains(t, aggregateRow(t, buffer.String()), ">1200<")
+}
@@ -0,0 +1,142 @@
+package table
+
+import (
+	"net/url"
+
+	"github.com/benpate/html"
+)
+
+/******************************************
+ * Accessibility
+ ******************************************/
+
+// Announce returns a copy of the table that announces the action in params (an
+// add, edit, delete, etc. that Do has just applied) to screen readers the next
+// time that it is drawn.  Pass the same URL that was passed to Do.  Rows are
+// numbered by their position in the redrawn table, not by their stored order.
+func (widget Table) Announce(params *url.URL) Table {
+	widget.announcement = params.Query()
+	return widget
+}
+
+// announcementText describes the action in a Do request, or returns an empty
+// string if there is nothing to announce.
+func (widget Table) announcementText(query url.Values) string {
+
+	// Actions in a nested table are described by the nested table
+	if path := query.Get("path"); widget.isSubTablePath(path) {
+
+		if subTable, err := widget.findSubTable(path); err == nil {
+			return subTable.announcementText(query)
+		}
+
+		return ""
+	}
+
+	switch {
+
+	case (query.Get("add") == "true") || (query.Get("insert") != ""):
+		return widget.message(MessageRowAdded)
+
+	case query.Get("edit") != "":
+		return widget.rowAnnouncement(query.Get("edit"), MessageRowSaved, MessageChangesSaved)
+
+	case query.Get("cell") != "":
+		return widget.rowAnnouncement(query.Get("cell"), MessageRowSaved, MessageChangesSaved)
+
+	case query.Get("autosave") != "":
+		return widget.rowAnnouncement(query.Get("autosave"), MessageRowSaved, MessageChangesSaved)
+
+	case query.Get("duplicate") != "":
+		return widget.rowAnnouncement(query.Get("duplicate"), MessageRowDuplicated, MessageChangesDuplicated)
+
+	case query.Get("delete") != "":
+		return widget.message(MessageRowDeleted)
+
+	case query.Get("bulk") == "delete":
+		return widget.message(MessageSelectedDeleted)
+
+	case query.Get("bulk") == "edit":
+		return widget.message(MessageSelectedSaved)
+
+	case query.Get("to") != "":
+		return widget.message(MessageRowMoved)
+	}
+
+	return ""
+}
+
+// rowAnnouncement describes an action on a single row, including the row's
+// number if the row is still displayed (or the unnumbered message if not)
+func (widget Table) rowAnnouncement(rowID string, key string, unnumberedKey string) string {
+
+	if rowIndex, ok, _ := widget.lookupRow(rowID); ok {
+		if number, ok := widget.rowNumber(rowIndex); ok {
+			return widget.message(key, number)
+		}
+	}
+
+	return widget.message(unnumberedKey)
+}
+
+// drawAnnouncement writes the live region where screen readers announce the
+// last action.  The region is always present, so that it is already known to
+// screen readers when its contents change.
+func (widget Table) drawAnnouncement(b *html.Builder) {
+	b.Div().
+		Class("grid-status").
+		Attr("role", "status").
+		Attr("aria-live", "polite").
+		InnerText(widget.announcementText(widget.announcement)).
+		Close()
+}
+
+// caption returns the text that describes the table: its Caption, or the
+// label of its Form if there is no Caption.
+func (widget Table) caption() string {
+
+	if widget.Caption != "" {
+		return widget.Caption
+	}
+
+	return widget.Form.Label
+}
+
+// columnCount returns the number of columns in each row of the table,
+// including the selection and controls columns.
+func (widget Table) columnCount() int {
+
+	result := len(widget.Form.Children) + 1
+
+	if widget.selecting {
+		result++
+	}
+
+	return result
+}
+
+// rowLabel returns the accessible label for a button that acts on a single row
+func (widget Table) rowLabel(key string, rowIndex int) string {
+
+	// Rows that are not displayed yet (such as a new row) use their stored position
+	number, ok := widget.rowNumber(rowIndex)
+
+	if !ok {
+		number = rowIndex + 1
+	}
+
+	return widget.message(key, number)
+}
+
+// rowNumber returns the position (starting from 1) where a row is displayed, so
+// that screen readers number rows in the same order that users see them.
+// Before the table is drawn, rows are numbered in their stored order.
+func (widget Table) rowNumber(rowIndex int) (int, bool) {
+
+	if widget.rowPositions == nil {
+		return rowIndex + 1, (rowIndex >= 0)
+	}
+
+	position, ok := widget.rowPositions[rowIndex]
+	return position + 1, ok
+}
@@ -0,0 +1,135 @@
+package table
+
+import (
+	"bytes"
+	"html"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDraw_SemanticMarkup(t *testing.T) {
+	table := newTestTable().UseCaption("People")
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `<table class="grid"><caption>People</caption><thead><tr class="grid-header"><th class="grid-cell" scope="col"><div>Name</div></th>`)
+	assert.Contains(t, result, `<th class="grid-cell grid-controls" scope="col" aria-label="Actions"></th></tr></thead><tbody><tr class="grid-row`)
+	assert.Contains(t, result, `</tbody><tfoot><tr class="grid-footer"><td class="grid-cell" colspan="3"><button type="button" class="link" hx-get="http://localhost/table?add=true">plus Add a Row</button></td></tr></tfoot></table>`)
+	assert.Contains(t, result, `<div class="grid-status" role="status" aria-live="polite"></div>`)
+}
+
+// Tables without a caption use the Form's label, or leave the caption out
+func TestDraw_Caption(t *testing.T) {
+	table := newTestTable()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+	assert.NotContains(t, buffer.String(), "<caption")
+
+	form := *table.Form
+	form.Label = "Everyone"
+	table.Form = &form
+	buffer.Reset()
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+	assert.Contains(t, buffer.String(), "<caption>Everyone</caption>")
+}
+
+func TestDraw_ButtonLabels(t *testing.T) {
+	table := newMoveTable().AllowInsert().AllowDuplicate()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	for _, label := range []string{"move up row 2", "move down row 2", "insert above row 2", "insert below row 2", "duplicate row 2", "edit row 2", "delete row 2"} {
+		assert.Contains(t, result, `aria-label="`+label+`"`)
+	}
+
+	// Every button has a label (or its own text)
+	for _, button := range strings.Split(result, "<button")[1:] {
+		if !strings.Contains(button, "Add a Row") {
+			assert.Contains(t, button[:strings.Index(button, ">")], "aria-label")
+		}
+	}
+}
+
+func TestDraw_EditButtonLabels(t *testing.T) {
+	table := newTestTable()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x?edit=0"), &buffer))
+
+	result := buffer.String()
+	assert.Contains(t, result, `aria-label="save row 1"`)
+	assert.Contains(t, result, `aria-label="cancel"`)
+}
+
+func TestDraw_AriaSort(t *testing.T) {
+	table := newTestTable().AllowSort()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x?sort=age&dir=desc"), &buffer))
+
+	result := buffer.String()
+	assert.Contains(t, result, `aria-sort="none"`)
+	assert.Contains(t, result, `aria-sort="descending"`)
+	assert.Contains(t, result, `<span aria-hidden="true">sort-descending</span>`)
+}
+
+/******************************************
+ * Announce()
+ ******************************************/
+
+func TestAnnounce(t *testing.T) {
+	table := newTestTable()
+
+	tests := map[string]string{
+		"http://x?add=true":           "Row added",
+		"http://x?insert=1":           "Row added",
+		"http://x?edit=1":             "Row 2 saved",
+		"http://x?cell=0&focus=1":     "Row 1 saved",
+		"http://x?autosave=1&focus=1": "Row 2 saved",
+		"http://x?duplicate=0":        "Row 1 duplicated",
+		"http://x?delete=1":           "Row deleted",
+		"http://x?bulk=delete":        "Selected rows deleted",
+		"http://x?bulk=edit&column=1": "Selected rows saved",
+		"http://x?move=1&to=0":        "Row moved",
+		"http://x?sort=name":          "",
+		"http://x?edit=bogus":         "Row saved",
+	}
+
+	for params, expected := range tests {
+		announced := table.Announce(mustURL(t, params))
+		assert.Equal(t, expected, announced.announcementText(announced.announcement), params)
+	}
+}
+
+// Rows are numbered by where they are displayed, not where they are stored
+func TestDraw_AnnouncementSorted(t *testing.T) {
+	table := newTestTable().AllowSort()
+	var buffer bytes.Buffer
+
+	// Sorted by name (descending), "John Connor" is displayed after "Sarah Connor"
+	require.NoError(t, table.Announce(mustURL(t, "http://x?edit=0")).Draw(mustURL(t, "http://x?sort=name&dir=desc"), &buffer))
+
+	result := buffer.String()
+	assert.Contains(t, result, `aria-live="polite">Row 2 saved</div>`)
+	assertOrder(t, result, "Sarah Connor", `aria-label="edit row 1"`, "John Connor", `aria-label="edit row 2"`)
+}
+
+func TestDraw_Announcement(t *testing.T) {
+	table := newTestTable()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Do(mustURL(t, "http://x?delete=0&version="+testRowVersion(t, table, 0)), nil))
+	require.NoError(t, table.Announce(mustURL(t, "http://x?delete=0")).Draw(mustURL(t, "http://x"), &buffer))
+
+	assert.Contains(t, buffer.String(), `<div class="grid-status" role="status" aria-live="polite">Row deleted</div>`)
+	assert.Empty(t, table.announcement) // the original is left unchanged
+}
@@ -0,0 +1,91 @@
+package table
+
+import (
+	"bytes"
+	"html"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseAutoSave(t *testing.T) {
+	table := newTestTable()
+
+	result := table.UseAutoSave()
+
+	assert.True(t, result.AutoSave)
+	assert.False(t, table.AutoSave) // the original is left unchanged
+}
+
+func TestDraw_AutoSaveEditors(t *testing.T) {
+	table := newTestTable()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x?edit=1"), &buffer))
+	assert.NotContains(t, buffer.String(), "autosave") // fields are saved with the save button by default
+
+	table = table.UseAutoSave()
+	buffer.Reset()
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x?edit=1"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `hx-post="http://localhost/table?autosave=1&focus=0" hx-trigger="change" hx-params="name,_version"`)
+	assert.Contains(t, result, `hx-post="http://localhost/table?autosave=1&focus=1" hx-trigger="change" hx-params="age,_version"`)
+	assert.Contains(t, result, `<button type="submit" class="text-green" aria-label="save row 2">save</button>`) // the save button is kept as a fallback
+}
+
+// New rows do not exist yet, so they cannot be saved one field at a time
+func TestDraw_AutoSaveAddRow(t *testing.T) {
+	table := newTestTable().UseAutoSave()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x?add=true"), &buffer))
+	assert.NotContains(t, buffer.String(), "autosave")
+}
+
+func TestDo_AutoSave(t *testing.T) {
+	table := newTestTable().UseAutoSave()
+	db := table.Object.(*testDatabase)
+
+	require.NoError(t, table.Do(mustURL(t, "http://x?autosave=1&focus=0"), withVersion(t, table, 1, map[string]any{"name": "Sarah Reese"})))
+	assert.Equal(t, "Sarah Reese", db.Data[1]["name"])
+	assert.Equal(t, 45, db.Data[1]["age"])
+
+	// The row stays open for editing, moving on to the next field
+	var buffer bytes.Buffer
+	require.NoError(t, table.Draw(mustURL(t, "http://x?autosave=1&focus=0"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `<input name="name" value="Sarah Reese">`)
+	assert.Contains(t, result, `<input name="age" value="45" autofocus="true">`)
+}
+
+func TestDo_AutoSaveConflict(t *testing.T) {
+	table := newTestTable().UseAutoSave()
+
+	err := table.Do(mustURL(t, "http://x?autosave=1&focus=0"), map[string]any{"name": "Sarah Reese", "_version": "old"})
+	require.Error(t, err)
+	assert.True(t, IsConflict(err))
+}
+
+// A rejected value is shown with its error, without closing the row or losing the other fields
+func TestDrawErrors_AutoSave(t *testing.T) {
+	table := newConstrainedTable().UseAutoSave()
+	db := table.Object.(*testDatabase)
+	var buffer bytes.Buffer
+
+	params := mustURL(t, "http://x?autosave=1&focus=1")
+	submitted := withVersion(t, table, 1, map[string]any{"age": "x"})
+	err := table.Do(params, submitted)
+	require.Error(t, err)
+	assert.Equal(t, 45, db.Data[1]["age"])
+
+	require.NoError(t, table.DrawErrors(params, submitted, err, &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `<div class="grid-error">Value must be an integer</div>`)
+	assert.Contains(t, result, `<input name="name" value="Sarah Connor">`)
+	assert.Contains(t, autofocusedInput(result), `name="age"`)
+}
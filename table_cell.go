@@ -0,0 +1,103 @@
+package table
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/benpate/derp"
+	"github.com/benpate/form"
+	"github.com/benpate/rosetta/convert"
+	"github.com/benpate/rosetta/list"
+)
+
+/******************************************
+ * Cell Edit Methods
+ ******************************************/
+
+// DoEditCell sets a single field in an existing row.  Only the requested value
+// is validated and written, so the row's other fields are left exactly as they
+// are (even if they would not pass validation themselves).  The value is only
+// written if version matches the row's current version token.
+func (widget Table) DoEditCell(rowIndex int, path string, value any, version string) error {
+
+	const location = "table.Widget.DoEditCell"
+
+	widget = widget.scalarColumns()
+
+	if !widget.CanEdit {
+		return derp.BadRequest(location, widget.message(MessageEditNotAllowed), widget.Path)
+	}
+
+	// Only fields that can be edited in the Form (or its Details) can be changed
+	if !slices.ContainsFunc(widget.rowFields(), func(field form.Element) bool { return field.Path == path }) {
+		return derp.BadRequest(location, widget.message(MessageFieldNotEditable), widget.Path, path)
+	}
+
+	tableElement, err := widget.getTableElement()
+
+	if err != nil {
+		return derp.Wrap(err, location, "Getting table element")
+	}
+
+	tableData, err := widget.Schema.Get(widget.Object, widget.Path)
+
+	if err != nil {
+		return derp.Wrap(err, location, "Locating table data", widget.Path)
+	}
+
+	length := convert.SliceLength(tableData)
+
+	if (rowIndex < 0) || (rowIndex >= length) {
+		return derp.BadRequest(location, widget.message(MessageRowNotFound), widget.Path, rowIndex, length)
+	}
+
+	if err := widget.checkVersion(rowIndex, version); err != nil {
+		return derp.Wrap(err, location, "Checking row version", widget.Path, rowIndex)
+	}
+
+	// Stage the value on a copy of the row, so that an invalid value is never written
+	rowSchema := newRowSchema(tableElement)
+	rowPath := list.ByDot(widget.Path, strconv.Itoa(rowIndex)).String()
+	rowValue, err := widget.getRow(tableElement, rowIndex)
+
+	if err != nil {
+		return derp.Wrap(err, location, "Getting row data", rowPath)
+	}
+
+	staged := cloneRow(rowValue)
+
+	if err := rowSchema.Set(&staged, path, value); err != nil {
+		return derp.Wrap(err, location, "Setting value in row", rowPath, path)
+	}
+
+	stagedValue, err := rowSchema.Get(&staged, path)
+
+	if err != nil {
+		return derp.Wrap(err, location, "Getting staged value", rowPath, path)
+	}
+
+	fieldPath := widget.fieldPath(tableElement, rowIndex, path)
+
+	if err := widget.Schema.Set(widget.Object, fieldPath, stagedValue); err != nil {
+		return derp.Wrap(err, location, "Setting value in table", fieldPath)
+	}
+
+	return nil
+}
+
+// editableField returns the column at the requested index, or FALSE if the
+// column does not exist or cannot be edited.
+func (widget Table) editableField(column int) (form.Element, bool) {
+
+	if (column < 0) || (column >= len(widget.Form.Children)) {
+		return form.Element{}, false
+	}
+
+	field := widget.Form.Children[column]
+
+	if (field.Path == "") || field.ReadOnly || isSubTable(field) {
+		return form.Element{}, false
+	}
+
+	return field, true
+}
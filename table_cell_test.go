@@ -0,0 +1,125 @@
+package table
+
+import (
+	"bytes"
+	"html"
+	"strings"
+	"testing"
+
+	"github.com/benpate/rosetta/mapof"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/******************************************
+ * DoEditCell()
+ ******************************************/
+
+func TestDoEditCell(t *testing.T) {
+	table := newTestTable()
+	db := table.Object.(*testDatabase)
+
+	require.NoError(t, table.DoEditCell(1, "age", "46", testRowVersion(t, table, 1)))
+	assert.Equal(t, 46, db.Data[1]["age"])
+	assert.Equal(t, "Sarah Connor", db.Data[1]["name"])
+	assert.Equal(t, 20, db.Data[0]["age"])
+}
+
+// Other fields in the row are not required, and are not touched
+func TestDoEditCell_IncompleteRow(t *testing.T) {
+	table := newConstrainedTable()
+	db := table.Object.(*testDatabase)
+	db.Data = append(db.Data, mapof.Any{"age": 3}) // missing its required name
+
+	require.NoError(t, table.DoEditCell(2, "age", 4, testRowVersion(t, table, 2)))
+	assert.Equal(t, mapof.Any{"age": 4}, db.Data[2])
+}
+
+func TestDoEditCell_Invalid(t *testing.T) {
+	table := newConstrainedTable()
+	db := table.Object.(*testDatabase)
+
+	require.Error(t, table.DoEditCell(0, "age", "not-a-number", testRowVersion(t, table, 0)))
+	assert.Equal(t, 20, db.Data[0]["age"])
+}
+
+func TestDoEditCell_Errors(t *testing.T) {
+	table := newTestTable()
+
+	require.Error(t, table.DoEditCell(2, "age", 1, ""))             // out of range (cells cannot add rows)
+	require.Error(t, table.DoEditCell(-1, "age", 1, ""))            // out of range
+	require.Error(t, table.DoEditCell(0, "secret", "x", ""))        // not in the Form
+	require.Error(t, table.AllowNone().DoEditCell(0, "age", 1, "")) // not allowed
+}
+
+func TestDo_EditCell(t *testing.T) {
+	table := newKeyTable()
+	db := table.Object.(*testDatabase)
+
+	require.NoError(t, table.Do(mustURL(t, "http://x?cell=abc&focus=0"), withVersion(t, table, 2, map[string]any{"name": "Kyle", "age": "99"})))
+	assert.Equal(t, "Kyle", db.Data[2]["name"])
+	assert.Equal(t, 30, db.Data[2]["age"]) // only the requested column is written
+
+	require.Error(t, table.Do(mustURL(t, "http://x?cell=abc&focus=5"), map[string]any{"name": "Kyle"}))
+}
+
+/******************************************
+ * Drawing
+ ******************************************/
+
+func TestDraw_CellLinks(t *testing.T) {
+	table := newTestTable()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+	assert.NotContains(t, buffer.String(), "cell=") // cells edit the whole row by default
+
+	table = table.UseCellEdit()
+	buffer.Reset()
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `hx-get="http://localhost/table?cell=1&focus=1" hx-trigger="click"`)
+	assert.Contains(t, result, `hx-get="http://localhost/table?edit=1&focus=0">edit`) // the edit button still edits the whole row
+}
+
+func TestDraw_Cell(t *testing.T) {
+	table := newTestTable().UseCellEdit()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x?cell=1&focus=1"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `hx-post="http://localhost/table?cell=1&focus=1"`)
+	assert.Contains(t, result, `<input name="age" value="45" autofocus="true">`)
+	assert.NotContains(t, result, `<input name="name"`) // the rest of the row is only displayed
+	assert.Contains(t, result, ">Sarah Connor<")
+	assert.Contains(t, result, `name="_version"`)
+}
+
+func TestDrawErrors_Cell(t *testing.T) {
+	table := newConstrainedTable().UseCellEdit()
+	var buffer bytes.Buffer
+
+	params := mustURL(t, "http://x?cell=0&focus=1")
+	submitted := withVersion(t, table, 0, map[string]any{"age": "x"})
+	err := table.Do(params, submitted)
+	require.Error(t, err)
+
+	require.NoError(t, table.DrawErrors(params, submitted, err, &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `<div class="grid-error">Value must be an integer</div>`)
+	assert.Equal(t, 1, strings.Count(result, "grid-error")) // the missing "name" is not an error here
+	assert.Contains(t, result, ">John Connor<")
+}
+
+func TestUseCellEdit(t *testing.T) {
+	table := newTestTable()
+
+	result := table.UseCellEdit()
+
+	assert.True(t, result.CellEdit)
+	assert.False(t, table.CellEdit) // the original is left unchanged
+}
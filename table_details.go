@@ -0,0 +1,178 @@
+package table
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/benpate/derp"
+	"github.com/benpate/form"
+	"github.com/benpate/html"
+	"github.com/benpate/rosetta/schema"
+)
+
+/******************************************
+ * Detail Rows
+ ******************************************/
+
+// hasDetails returns TRUE if the table displays fields beneath each row
+func (widget Table) hasDetails() bool {
+	return (widget.Details != nil) && (len(widget.Details.Children) > 0)
+}
+
+// isExpanded returns TRUE if the Details of a row are displayed beneath it
+func (widget Table) isExpanded(rowIndex int) bool {
+	return slices.Contains(widget.view.Expanded, widget.rowID(rowIndex))
+}
+
+// fieldCount returns the number of fields in the edit row: the columns in the
+// Form, followed by the fields in the Details.  Fields are focused (and
+// auto-saved) by their position in this list.
+func (widget Table) fieldCount() int {
+
+	if widget.hasDetails() {
+		return len(widget.Form.Children) + len(widget.Details.Children)
+	}
+
+	return len(widget.Form.Children)
+}
+
+// autoSaveField returns the field at the requested position (see fieldCount),
+// or FALSE if the field does not exist or cannot be edited.
+func (widget Table) autoSaveField(column int) (form.Element, bool) {
+
+	if column < len(widget.Form.Children) {
+		return widget.editableField(column)
+	}
+
+	detail := column - len(widget.Form.Children)
+
+	if !widget.hasDetails() || (detail >= len(widget.Details.Children)) {
+		return form.Element{}, false
+	}
+
+	field := widget.Details.Children[detail]
+
+	if (field.Path == "") || field.ReadOnly || isSubTable(field) {
+		return form.Element{}, false
+	}
+
+	return field, true
+}
+
+// rowFields returns every field that is written when a row is saved: the
+// columns in the Form, followed by the fields in the Details.
+func (widget Table) rowFields() []form.Element {
+
+	result := formFields(*widget.Form)
+
+	if widget.Details != nil {
+		result = append(result, formFields(*widget.Details)...)
+	}
+
+	return result
+}
+
+// drawDetailsButton writes the button that shows (or hides) the Details of a row
+func (widget Table) drawDetailsButton(rowIndex int, b *html.Builder) {
+
+	action, icon, labelKey, expanded := "details", "expand", MessageShowDetails, "false"
+	if widget.isExpanded(rowIndex) {
+		action, icon, labelKey, expanded = "hide-details", "collapse", MessageHideDetails, "true"
+	}
+
+	b.Button().
+		Type("button").
+		Attr("aria-label", widget.rowLabel(labelKey, rowIndex)).
+		Attr("aria-expanded", expanded).
+		Data("hx-get", widget.getURL(action, rowIndex, 0)).
+		InnerHTML(widget.Icons.Get(icon)).
+		Close()
+}
+
+// drawDetailsView writes a full-width row beneath a row, with the label and
+// value of each field in the Details
+func (widget Table) drawDetailsView(rowSchema *schema.Schema, rowValue any, b *html.Builder) error {
+
+	const location = "table.Widget.drawDetailsView"
+
+	f := form.New(*rowSchema, *widget.Details)
+
+	b.TR().Class("grid-row", "grid-details")
+	b.TD().Class("grid-cell").Attr("colspan", strconv.Itoa(widget.columnCount()))
+	b.Container("dl")
+
+	for _, field := range widget.Details.Children {
+
+		cellHTML, err := widget.viewCell(&f, field, rowValue)
+
+		if err != nil {
+			return derp.Wrap(err, location, "Rendering field", field)
+		}
+
+		b.Container("dt").InnerText(field.Label).Close()
+		b.Container("dd").InnerHTML(cellHTML).Close()
+	}
+
+	b.Close() // DL
+	b.Close() // TD
+	b.Close() // TR
+	return nil
+}
+
+// drawDetailsEdit writes a full-width row beneath the add or edit row, with an
+// editor for each field in the Details.  Every field is drawn (even when the
+// row was not expanded) so that saving the row never clears them.  The saved
+// row is shown beneath fields that someone else has changed, and is nil for
+// new rows.  With autoSave, each field is saved on its own as it changes, just
+// like the columns of the edit row.
+func (widget Table) drawDetailsEdit(rowSchema *schema.Schema, rowIndex int, editValue any, savedValue any, autoSave bool, focusColumn int, b *html.Builder) error {
+
+	const location = "table.Widget.drawDetailsEdit"
+
+	f := form.New(*rowSchema, *widget.Details)
+
+	b.TR().Class("grid-row", "grid-editable", "grid-details")
+	b.TD().Class("grid-cell", "grid-editable").Attr("colspan", strconv.Itoa(widget.columnCount()))
+
+	for index, field := range widget.Details.Children {
+
+		// Details are numbered after the columns (see fieldCount)
+		column := len(widget.Form.Children) + index
+		detail := b.Div().Class("grid-detail")
+
+		if autoSave {
+			detail.Data("hx-post", widget.getURL("autosave", rowIndex, column)).
+				Data("hx-trigger", "change").
+				Data("hx-params", field.Path+","+versionField)
+		}
+
+		if column == focusColumn {
+			field = focusField(field)
+		}
+
+		// Wrapping the editor in its label names it for screen readers
+		b.Container("label")
+		b.Span().InnerText(field.Label).Close()
+		b.Space()
+
+		if err := field.Edit(&f, widget.LookupProvider, editValue, b.SubTree()); err != nil {
+			return derp.Wrap(err, location, "Rendering field", field)
+		}
+
+		b.Close() // LABEL
+
+		widget.drawFieldError(field.Path, b.SubTree())
+
+		if widget.conflict && (savedValue != nil) {
+			if err := widget.drawConflictValue(&f, field, editValue, savedValue, b.SubTree()); err != nil {
+				return derp.Wrap(err, location, "Rendering saved value", field)
+			}
+		}
+
+		b.Close() // DIV
+	}
+
+	b.Close() // TD
+	b.Close() // TR
+	return nil
+}
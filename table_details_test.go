@@ -0,0 +1,168 @@
+package table
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/benpate/form"
+	"github.com/benpate/rosetta/mapof"
+	"github.com/benpate/rosetta/schema"
+	"github.com/benpate/rosetta/sliceof"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newDetailsTable returns a table with one column, and notes that are displayed beneath each row
+func newDetailsTable() Table {
+
+	s := schema.Schema{
+		Element: schema.Object{
+			Properties: schema.ElementMap{
+				"data": schema.Array{
+					Items: schema.Object{
+						Properties: schema.ElementMap{
+							"name":  schema.String{},
+							"notes": schema.String{Required: true},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	f := form.Element{
+		Type: "layout-vertical",
+		Children: []form.Element{
+			{Type: "text", Label: "Name", Path: "name"},
+		},
+	}
+
+	details := form.Element{
+		Type: "layout-vertical",
+		Children: []form.Element{
+			{Type: "textarea", Label: "Notes", Path: "notes"},
+		},
+	}
+
+	db := &testDatabase{
+		Data: sliceof.Object[mapof.Any]{
+			mapof.Any{"name": "John Connor", "notes": "Leader"},
+			mapof.Any{"name": "Sarah Connor", "notes": "Mother"},
+		},
+	}
+
+	return New(&s, &f, db, "data", testIconProvider{}, "http://localhost/table").UseDetails(details)
+}
+
+func TestDraw_DetailsCollapsed(t *testing.T) {
+
+	result := drawGroupTable(t, newDetailsTable(), "http://x")
+
+	assert.Contains(t, result, `<button type="button" aria-label="show details of row 1" aria-expanded="false" hx-get="http://localhost/table?expand=0">expand</button>`)
+	assert.NotContains(t, result, "grid-details")
+	assert.NotContains(t, result, "Leader")
+}
+
+func TestDraw_DetailsExpanded(t *testing.T) {
+
+	result := drawGroupTable(t, newDetailsTable(), "http://x?expand=1")
+
+	assert.Contains(t, result, `<button type="button" aria-label="hide details of row 2" aria-expanded="true" hx-get="http://localhost/table">collapse</button>`)
+	assertOrder(t, result, "Sarah Connor", `<tr class="grid-row grid-details"><td class="grid-cell" colspan="2"><dl><dt>Notes</dt><dd>Mother</dd></dl></td></tr>`)
+	assert.NotContains(t, result, "Leader")
+}
+
+func TestDraw_DetailsInvalidExpand(t *testing.T) {
+
+	result := drawGroupTable(t, newDetailsTable(), "http://x?expand=99")
+
+	assert.NotContains(t, result, "grid-details")
+}
+
+func TestDraw_DetailsEdit(t *testing.T) {
+
+	// The edit row always includes the Details, so that saving never clears them
+	result := drawGroupTable(t, newDetailsTable(), "http://x?edit=0")
+
+	assertOrder(t, result, `name="name" value="John Connor"`, `<tr class="grid-row grid-editable grid-details">`, `<label><span>Notes</span> `, `name="notes" value="Leader"`, "</label>")
+	assert.NotContains(t, result, "Mother")
+}
+
+func TestDraw_DetailsAdd(t *testing.T) {
+
+	result := drawGroupTable(t, newDetailsTable(), "http://x?add=true")
+
+	assertOrder(t, result, `aria-label="save new row"`, `<tr class="grid-row grid-editable grid-details">`, `name="notes"`)
+}
+
+func TestDraw_DetailsCell(t *testing.T) {
+
+	table := newDetailsTable().UseCellEdit()
+
+	// Single cells only display the Details, and only when they are expanded
+	result := drawGroupTable(t, table, "http://x?cell=0&focus=0")
+	assert.NotContains(t, result, "grid-details")
+
+	result = drawGroupTable(t, table, "http://x?cell=0&focus=0&expand=0")
+	assert.Contains(t, result, "<dd>Leader</dd>")
+	assert.NotContains(t, result, `name="notes"`)
+}
+
+func TestDoEdit_Details(t *testing.T) {
+
+	table := newDetailsTable()
+
+	require.NoError(t, table.Do(mustURL(t, "http://x?edit=1"), withVersion(t, table, 1, map[string]any{"name": "Sarah Reese", "notes": "Survivor"})))
+
+	db := table.Object.(*testDatabase)
+	assert.Equal(t, "Sarah Reese", db.Data[1]["name"])
+	assert.Equal(t, "Survivor", db.Data[1]["notes"])
+}
+
+func TestDrawErrors_Details(t *testing.T) {
+
+	table := newDetailsTable()
+	params := mustURL(t, "http://x?edit=0")
+	submitted := withVersion(t, table, 0, map[string]any{"name": "John Reese", "notes": ""})
+
+	err := table.Do(params, submitted)
+	require.Error(t, err)
+
+	var buffer bytes.Buffer
+	require.NoError(t, table.DrawErrors(params, submitted, err, &buffer))
+
+	// The error is drawn beneath the Details field, and nothing was saved
+	assertOrder(t, buffer.String(), `value="John Reese"`, "grid-details", `name="notes"`, `<div class="grid-error">Value is required</div>`)
+
+	db := table.Object.(*testDatabase)
+	assert.Equal(t, "John Connor", db.Data[0]["name"])
+}
+
+// Open rows stay open while the table is sorted, paged, searched, or edited
+func TestDraw_DetailsViewState(t *testing.T) {
+
+	table := newDetailsTable().AllowSort()
+	result := drawGroupTable(t, table, "http://x?expand=0&expand=1&sort=name")
+
+	assert.Contains(t, result, "Leader")
+	assert.Contains(t, result, "Mother")
+	assert.Contains(t, result, `hx-get="http://localhost/table?dir=desc&expand=0&expand=1&sort=name"`)
+	assert.Contains(t, result, `aria-label="hide details of row 1" aria-expanded="true" hx-get="http://localhost/table?dir=asc&expand=1&sort=name"`)
+	assert.Contains(t, result, `hx-get="http://localhost/table?dir=asc&edit=0&expand=0&expand=1&focus=0&sort=name"`)
+}
+
+// With auto-save, the fields in the Details are saved as they change, too
+func TestDo_DetailsAutoSave(t *testing.T) {
+
+	table := newDetailsTable().UseAutoSave()
+	db := table.Object.(*testDatabase)
+
+	result := drawGroupTable(t, table, "http://x?edit=1")
+	assert.Contains(t, result, `<div class="grid-detail" hx-post="http://localhost/table?autosave=1&focus=1" hx-trigger="change" hx-params="notes,_version">`)
+
+	require.NoError(t, table.Do(mustURL(t, "http://x?autosave=1&focus=1"), withVersion(t, table, 1, map[string]any{"notes": "Survivor"})))
+	assert.Equal(t, "Survivor", db.Data[1]["notes"])
+	assert.Equal(t, "Sarah Connor", db.Data[1]["name"])
+
+	require.Error(t, table.Do(mustURL(t, "http://x?autosave=1&focus=2"), withVersion(t, table, 1, map[string]any{"notes": "Nobody"})))
+}
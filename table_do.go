@@ -1,30 +1,113 @@
 package table
 
 import (
+	"encoding/json"
 	"net/url"
+	"slices"
 	"strconv"
 
 	"github.com/benpate/derp"
+	"github.com/benpate/form"
 	"github.com/benpate/rosetta/convert"
 	"github.com/benpate/rosetta/list"
+	"github.com/benpate/rosetta/mapof"
+	"github.com/benpate/rosetta/schema"
 )
 
 /******************************************
  * Update/Delete Methods
  ******************************************/
 
-// Do applies an edit or delete action to the table's data, selecting the action
-// from the "edit" and "delete" query parameters.
+// Do applies an add, insert, edit, duplicate, delete, or move action to the
+// table's data, selecting the action from the "add", "insert", "edit", "cell",
+// "autosave", "duplicate", "delete", "bulk", and "move" query parameters.  Rows are
+// identified by key when the table has a KeyPath, or by index otherwise.
+// Edits and deletes based on an out-of-date copy of a row (or that are missing
+// the row's version token) return a Conflict error (see IsConflict).
 func (widget Table) Do(queryParams *url.URL, data map[string]any) error {
 
 	const location = "table.Widget.Do"
 
 	query := queryParams.Query()
 
+	// Requests for a nested table are applied by the nested table
+	if path := query.Get("path"); widget.isSubTablePath(path) {
+
+		subTable, err := widget.findSubTable(path)
+
+		if err != nil {
+			return derp.Wrap(err, location, "Finding sub-table", path)
+		}
+
+		return subTable.Do(queryParams, data)
+	}
+
+	widget = widget.scalarColumns()
+
+	// If this is an add request, then append the data as a new row
+	if query.Get("add") == "true" {
+
+		// In a tree, the new row may be added beneath another row
+		if parent := query.Get("parent"); widget.isTree() && (parent != "") {
+
+			parentIndex, ok, err := widget.lookupRow(parent)
+
+			if err != nil {
+				return derp.Wrap(err, location, "Locating parent row", widget.Path, parent)
+			}
+
+			if !ok {
+				return derp.NotFound(location, widget.message(MessageRowNotFound), widget.Path, parent)
+			}
+
+			if err := widget.DoAddChild(data, parentIndex); err != nil {
+				return derp.Wrap(err, location, "Adding child row", widget.Path, parentIndex)
+			}
+
+			return nil
+		}
+
+		if err := widget.DoAdd(data); err != nil {
+			return derp.Wrap(err, location, "Adding row", widget.Path)
+		}
+
+		return nil
+	}
+
+	// If this is an insert request, then add the data as a new row at the requested position
+	if insert := query.Get("insert"); insert != "" {
+
+		// Rows cannot be inserted while they are sorted, grouped, or in a tree,
+		// because the user could not see where the new row would be saved
+		widget.view = parseViewState(query)
+
+		if !widget.canInsertRows() {
+			return derp.BadRequest(location, widget.message(MessageInsertNotAllowed), widget.Path, insert)
+		}
+
+		if insertIndex, err := strconv.Atoi(insert); err == nil {
+			if err := widget.DoInsert(data, insertIndex); err != nil {
+				return derp.Wrap(err, location, "Inserting row", widget.Path, insertIndex)
+			}
+		}
+
+		return nil
+	}
+
 	// If this is an edit request, then apply the data to the requested row
 	if edit := query.Get("edit"); edit != "" {
 
-		if editIndex, err := strconv.Atoi(edit); err == nil {
+		editIndex, ok, err := widget.lookupRow(edit)
+
+		if err != nil {
+			return derp.Wrap(err, location, "Locating row to edit", widget.Path, edit)
+		}
+
+		if ok {
+			if !widget.CanEdit {
+				return derp.BadRequest(location, widget.message(MessageEditNotAllowed), widget.Path)
+			}
+
 			if err := widget.DoEdit(data, editIndex); err != nil {
 				return derp.Wrap(err, location, "Editing row", widget.Path, editIndex)
 			}
@@ -33,11 +116,76 @@ func (widget Table) Do(queryParams *url.URL, data map[string]any) error {
 		return nil
 	}
 
+	// If this is a cell edit (or an auto-save from the edit row) then apply the
+	// one submitted value to the requested row
+	// Auto-saves can also save the fields in the Details (see fieldCount)
+	cell, findField := query.Get("cell"), widget.editableField
+
+	if cell == "" {
+		cell, findField = query.Get("autosave"), widget.autoSaveField
+	}
+
+	if cell != "" {
+
+		cellIndex, ok, err := widget.lookupRow(cell)
+
+		if err != nil {
+			return derp.Wrap(err, location, "Locating row to edit", widget.Path, cell)
+		}
+
+		column, _ := strconv.Atoi(query.Get("focus"))
+		field, editable := findField(column)
+
+		if !editable {
+			return derp.BadRequest(location, widget.message(MessageFieldNotEditable), widget.Path, query.Get("focus"))
+		}
+
+		if ok {
+			if !widget.CanEdit {
+				return derp.BadRequest(location, widget.message(MessageEditNotAllowed), widget.Path)
+			}
+
+			if err := widget.DoEditCell(cellIndex, field.Path, data[field.Path], convert.String(data[versionField])); err != nil {
+				return derp.Wrap(err, location, "Editing cell", widget.Path, cellIndex, field.Path)
+			}
+		}
+
+		return nil
+	}
+
+	// If this is a duplicate request, then copy the requested row
+	if duplicate := query.Get("duplicate"); duplicate != "" {
+
+		duplicateIndex, ok, err := widget.lookupRow(duplicate)
+
+		if err != nil {
+			return derp.Wrap(err, location, "Locating row to duplicate", widget.Path, duplicate)
+		}
+
+		if ok {
+			if err := widget.DoDuplicate(duplicateIndex); err != nil {
+				return derp.Wrap(err, location, "Duplicating row", widget.Path, duplicateIndex)
+			}
+		}
+
+		return nil
+	}
+
 	// If this is a delete request, then remove the requested row
 	if deleteParam := query.Get("delete"); deleteParam != "" {
 
-		if deleteIndex, err := strconv.Atoi(deleteParam); err == nil {
-			if err := widget.DoDelete(deleteIndex); err != nil {
+		deleteIndex, ok, err := widget.lookupRow(deleteParam)
+
+		if err != nil {
+			return derp.Wrap(err, location, "Locating row to delete", widget.Path, deleteParam)
+		}
+
+		if ok {
+			if !widget.CanDelete {
+				return derp.BadRequest(location, widget.message(MessageDeleteNotAllowed), widget.Path)
+			}
+
+			if err := widget.DoDelete(deleteIndex, query.Get("version")); err != nil {
 				return derp.Wrap(err, location, "Deleting row", widget.Path, deleteIndex)
 			}
 		}
@@ -45,15 +193,148 @@ func (widget Table) Do(queryParams *url.URL, data map[string]any) error {
 		return nil
 	}
 
+	// If this is a bulk request, then apply it to every selected row
+	if bulk := query.Get("bulk"); bulk != "" {
+
+		rows, err := widget.selectedRows(data)
+
+		if err != nil {
+			return derp.Wrap(err, location, "Locating selected rows", widget.Path)
+		}
+
+		switch bulk {
+
+		case "edit":
+			column, _ := strconv.Atoi(query.Get("column"))
+			field, ok := widget.editableField(column)
+
+			if !ok {
+				return derp.BadRequest(location, widget.message(MessageFieldNotEditable), widget.Path, query.Get("column"))
+			}
+
+			if err := widget.DoBulkEdit(rows, field.Path, data[field.Path]); err != nil {
+				return derp.Wrap(err, location, "Editing selected rows", widget.Path, rows)
+			}
+
+		case "delete":
+			if err := widget.DoBulkDelete(rows); err != nil {
+				return derp.Wrap(err, location, "Deleting selected rows", widget.Path, rows)
+			}
+		}
+
+		return nil
+	}
+
+	// If this is a move request, then change the position of the requested row.
+	// Drag-and-drop posts the moved row as form data instead of in the query string.
+	moveParam := query.Get("move")
+
+	if moveParam == "" {
+		moveParam = convert.String(data["move"])
+	}
+
+	if moveParam != "" {
+
+		// Rows cannot be moved while they are sorted, grouped, filtered, or in
+		// a tree, because their displayed order is not their stored order
+		widget.view = parseViewState(query)
+
+		if !widget.canMoveRows() {
+			return derp.BadRequest(location, widget.message(MessageMoveNotAllowed), widget.Path, moveParam)
+		}
+
+		moveIndex, ok, err := widget.lookupRow(moveParam)
+
+		if err != nil {
+			return derp.Wrap(err, location, "Locating row to move", widget.Path, moveParam)
+		}
+
+		// The target is identified in the same way as the moved row
+		to, toOK, err := widget.lookupRow(query.Get("to"))
+
+		if err != nil {
+			return derp.Wrap(err, location, "Locating move target", widget.Path, query.Get("to"))
+		}
+
+		if ok && toOK {
+			if err := widget.DoMove(moveIndex, to); err != nil {
+				return derp.Wrap(err, location, "Moving row", widget.Path, moveIndex, to)
+			}
+		}
+
+		return nil
+	}
+
 	// Nothing to do here
 	return nil
 }
 
-// DoEdit applies a dataset to the requested row in the table
+// DoAdd appends a dataset to the end of the table as a new row
+func (widget Table) DoAdd(data map[string]any) error {
+
+	const location = "table.Widget.DoAdd"
+
+	tableData, err := widget.Schema.Get(widget.Object, widget.Path)
+
+	if err != nil {
+		return derp.Wrap(err, location, "Locating table data", widget.Path)
+	}
+
+	if err := widget.DoEdit(data, convert.SliceLength(tableData)); err != nil {
+		return derp.Wrap(err, location, "Adding row", widget.Path)
+	}
+
+	return nil
+}
+
+// DoInsert adds a dataset to the table as a new row at the requested index,
+// moving the rows at and after that index down by one.  An index equal to the
+// length of the table appends the row to the end, exactly like DoAdd.
+func (widget Table) DoInsert(data map[string]any, insertIndex int) error {
+
+	const location = "table.Widget.DoInsert"
+
+	if !widget.CanInsert {
+		return derp.BadRequest(location, widget.message(MessageInsertNotAllowed), widget.Path)
+	}
+
+	tableData, err := widget.Schema.Get(widget.Object, widget.Path)
+
+	if err != nil {
+		return derp.Wrap(err, location, "Locating table data", widget.Path)
+	}
+
+	length := convert.SliceLength(tableData)
+
+	if (insertIndex < 0) || (insertIndex > length) {
+		return derp.BadRequest(location, widget.message(MessageRowNotFound), widget.Path, insertIndex, length)
+	}
+
+	// Append the row (with all of the same checks as adding a row) then move it into place
+	if err := widget.DoEdit(data, length); err != nil {
+		return derp.Wrap(err, location, "Adding row", widget.Path)
+	}
+
+	if err := widget.moveRow(length, insertIndex); err != nil {
+
+		// Remove the new row, so that a failed insert leaves the table unchanged
+		_ = widget.Schema.Remove(widget.Object, list.ByDot(widget.Path, strconv.Itoa(length)).String())
+		return derp.Wrap(err, location, "Moving row into position", widget.Path, insertIndex)
+	}
+
+	return nil
+}
+
+// DoEdit applies a dataset to the requested row in the table.  The update is
+// atomic: the whole row is validated before anything is written, so an error
+// always leaves widget.Object unchanged.  Existing rows are only changed if
+// data includes their current version token (see versionField).
 func (widget Table) DoEdit(data map[string]any, editIndex int) error {
 
 	const location = "table.Widget.DoEdit"
 
+	widget = widget.scalarColumns()
+
 	// Locate the table data and validate the length of the existing array
 	tableData, err := widget.Schema.Get(widget.Object, widget.Path)
 
@@ -67,39 +348,92 @@ func (widget Table) DoEdit(data map[string]any, editIndex int) error {
 
 	// Cannot be negative index
 	case editIndex < 0:
-		return derp.Internal(location, "Edit index out of range (negative index not allowed)", widget.Path, editIndex)
+		return derp.Internal(location, widget.message(MessageRowNotFound), widget.Path, editIndex)
 
 	// Cannot be greater than length (but equal to length is okay because it means "add a new row")
 	case editIndex > length:
-		return derp.Internal(location, "Edit index out of range (too large)", data, widget.Path, tableData, length, editIndex)
+		return derp.Internal(location, widget.message(MessageRowNotFound), data, widget.Path, tableData, length, editIndex)
 
-	// Verify permission to add
+	// Verify permission to add, and that the table has room for another row
 	case editIndex == length:
 		if !widget.CanAdd {
-			return derp.Internal(location, "Cannot add new row", widget.Path, editIndex)
+			return derp.Internal(location, widget.message(MessageAddNotAllowed), widget.Path, editIndex)
+		}
+
+		if err := widget.checkMaxLength(length); err != nil {
+			return derp.Wrap(err, location, "Cannot add new row", widget.Path, editIndex)
 		}
 
 	// Verify permission to edit
 	default:
 		if !widget.CanEdit {
-			return derp.Internal(location, "Cannot edit row", widget.Path, editIndex)
+			return derp.Internal(location, widget.message(MessageEditNotAllowed), widget.Path, editIndex)
+		}
+
+		if err := widget.checkVersion(editIndex, convert.String(data[versionField])); err != nil {
+			return derp.Wrap(err, location, "Checking row version", widget.Path, editIndex)
 		}
 	}
 
-	// Try to add/edit the row in the data table.
-	//
-	// NOTE: This loop is not atomic.  Schema.Set validates each value as it writes
-	// (rosetta v0.26+), so a later field failing validation leaves earlier fields
-	// already written to widget.Object.  This is acceptable by contract: a caller
-	// that receives an error MUST discard the whole object rather than persist it.
+	// Stage every value on a copy of the row, so that a field that fails
+	// validation never leaves widget.Object partially updated.
 	//
-	// Only fields present in the Form are written, and AllElements() omits ReadOnly
-	// fields -- so a client cannot set a column that is not editable, and extra keys
-	// in `data` that are not in the Form are silently ignored.
-	for _, field := range widget.Form.AllElements() {
-		path := list.ByDot(widget.Path, strconv.Itoa(editIndex), field.Path)
-		if err := widget.Schema.Set(widget.Object, path.String(), data[field.Path]); err != nil {
-			return derp.Wrap(err, location, "Setting value in table", path.String(), data)
+	// Only fields present in the Form (or its Details) are written, and rowFields()
+	// omits ReadOnly fields and sub-tables -- so a client cannot set a column that is
+	// not editable, and extra keys in `data` that are not in the Form are silently ignored.
+	tableElement, err := widget.getTableElement()
+
+	if err != nil {
+		return derp.Wrap(err, location, "Getting table element", widget.Path)
+	}
+
+	rowSchema := newRowSchema(tableElement)
+	rowPath := list.ByDot(widget.Path, strconv.Itoa(editIndex))
+
+	var rowValue any
+
+	if editIndex < length {
+		if rowValue, err = widget.getRow(tableElement, editIndex); err != nil {
+			return derp.Wrap(err, location, "Getting row data", rowPath.String())
+		}
+	}
+
+	staged := cloneRow(rowValue)
+	fields := widget.rowFields()
+
+	// New children in a tree are saved with their parent's key (see DoAddChild)
+	if (editIndex == length) && widget.addParent.IsPresent() {
+		fields = append(fields, form.Element{Path: widget.ParentPath})
+	}
+
+	for _, field := range fields {
+		if err := rowSchema.Set(&staged, field.Path, data[field.Path]); err != nil {
+			return derp.Wrap(err, location, "Setting value in row", field.Path, data)
+		}
+	}
+
+	// Validate the whole row, including rules that span several fields.
+	// Empty values are removed from maps, so they are filled in with defaults first.
+	staged = completeRow(rowSchema.Element, staged)
+
+	if _, err := rowSchema.Validate(&staged); err != nil {
+		return derp.Wrap(err, location, "Validating row", rowPath.String(), data)
+	}
+
+	// Write the staged values back into the table.  Every value has already been
+	// validated, so this only fails if widget.Object cannot store them at all.
+	for _, field := range fields {
+
+		value, err := rowSchema.Get(&staged, field.Path)
+
+		if err != nil {
+			return derp.Wrap(err, location, "Getting staged value", field.Path)
+		}
+
+		path := widget.fieldPath(tableElement, editIndex, field.Path)
+
+		if err := widget.Schema.Set(widget.Object, path, value); err != nil {
+			return derp.Wrap(err, location, "Setting value in table", path, data)
 		}
 	}
 
@@ -107,20 +441,203 @@ func (widget Table) DoEdit(data map[string]any, editIndex int) error {
 	return nil
 }
 
-// DoDelete removes the requested row from the table
-func (widget Table) DoDelete(deleteIndex int) error {
+// DoDelete removes the requested row from the table.  In a tree (see
+// UseParentPath) the row's children move up to its parent, or are deleted
+// along with it when CascadeDelete is TRUE.  The row is only removed if
+// version matches its current version token.
+func (widget Table) DoDelete(deleteIndex int, version string) error {
 
 	const location = "table.Widget.DoDelete"
 
 	if !widget.CanDelete {
-		return derp.BadRequest(location, "Deleting is not allowed", widget.Path)
+		return derp.BadRequest(location, widget.message(MessageDeleteNotAllowed), widget.Path)
 	}
 
-	path := list.ByDot(widget.Path, strconv.Itoa(deleteIndex)).String()
+	if err := widget.checkVersion(deleteIndex, version); err != nil {
+		return derp.Wrap(err, location, "Checking row version", widget.Path, deleteIndex)
+	}
 
-	if ok := widget.Schema.Remove(widget.Object, path); !ok {
-		return derp.Internal(location, "Removing value from table")
+	if err := widget.deleteRows([]int{deleteIndex}); err != nil {
+		return derp.Wrap(err, location, "Removing row", widget.Path, deleteIndex)
 	}
 
 	return nil
 }
+
+// deleteRows removes rows from the table.  In a tree, the descendants of each
+// row are removed too, or move up to a new parent (see treeRemovals).  Either
+// every row is removed, or (if the table would end up shorter than its schema
+// allows) none of them are.
+func (widget Table) deleteRows(indexes []int) error {
+
+	const location = "table.Widget.deleteRows"
+
+	tableElement, err := widget.getTableElement()
+
+	if err != nil {
+		return derp.Wrap(err, location, "Getting table element")
+	}
+
+	tableData, err := widget.Schema.Get(widget.Object, widget.Path)
+
+	if err != nil {
+		return derp.Wrap(err, location, "Locating table data", widget.Path)
+	}
+
+	length := convert.SliceLength(tableData)
+
+	// Remove duplicates, so that each row is only counted once
+	removals := slices.Clone(indexes)
+	slices.Sort(removals)
+	removals = slices.Compact(removals)
+
+	var reparent map[int]string
+
+	if widget.isTree() {
+		if removals, reparent, err = widget.treeRemovals(removals); err != nil {
+			return derp.Wrap(err, location, "Finding descendants", widget.Path, indexes)
+		}
+	}
+
+	if length-len(removals) < tableElement.MinLength {
+		return derp.BadRequest(location, widget.message(MessageTooFewRows), widget.Path, tableElement.MinLength, len(removals))
+	}
+
+	if err := widget.removeRows(removals, reparent); err != nil {
+		return derp.Wrap(err, location, "Removing rows", widget.Path, indexes)
+	}
+
+	return nil
+}
+
+// checkMaxLength returns an error if a table of the given length is already
+// as long as its schema allows
+func (widget Table) checkMaxLength(length int) error {
+
+	const location = "table.Widget.checkMaxLength"
+
+	tableElement, err := widget.getTableElement()
+
+	if err != nil {
+		return derp.Wrap(err, location, "Getting table element")
+	}
+
+	if (tableElement.MaxLength > 0) && (length >= tableElement.MaxLength) {
+		return derp.BadRequest(location, widget.message(MessageTooManyRows), widget.Path, tableElement.MaxLength)
+	}
+
+	return nil
+}
+
+/******************************************
+ * Helper Functions
+ ******************************************/
+
+// cloneRow returns a deep copy of a row as a mapof.Any, so that changes can be
+// staged without touching the original.  Rows that are not maps (such as
+// structs) are copied through their JSON representation.
+func cloneRow(rowValue any) mapof.Any {
+
+	switch typed := rowValue.(type) {
+
+	case nil:
+		return mapof.Any{}
+
+	case *mapof.Any:
+		if typed != nil {
+			return cloneMap(*typed)
+		}
+		return mapof.Any{}
+
+	case mapof.Any:
+		return cloneMap(typed)
+
+	case map[string]any:
+		return cloneMap(typed)
+	}
+
+	result := mapof.Any{}
+
+	if rowJSON, err := json.Marshal(rowValue); err == nil {
+		_ = json.Unmarshal(rowJSON, &result)
+	}
+
+	return result
+}
+
+// cloneMap returns a deep copy of a map and every map or slice inside of it
+func cloneMap(value map[string]any) mapof.Any {
+
+	result := make(mapof.Any, len(value))
+
+	for key, item := range value {
+		result[key] = cloneValue(item)
+	}
+
+	return result
+}
+
+// cloneValue returns a deep copy of maps and slices, and all other values as-is
+func cloneValue(value any) any {
+
+	switch typed := value.(type) {
+
+	case mapof.Any:
+		return cloneMap(typed)
+
+	case map[string]any:
+		return cloneMap(typed)
+
+	case []any:
+		result := make([]any, len(typed))
+		for index, item := range typed {
+			result[index] = cloneValue(item)
+		}
+		return result
+	}
+
+	return value
+}
+
+// completeRow returns a copy of a row that includes every property in the row
+// schema, using the schema's default value for any property that is missing.
+// Schema.Validate requires every property to be present, but maps drop
+// properties when their values are set to empty.
+func completeRow(element schema.Element, row mapof.Any) mapof.Any {
+
+	object, ok := element.(schema.Object)
+
+	if !ok {
+		return row
+	}
+
+	result := make(mapof.Any, len(object.Properties))
+
+	for key, value := range row {
+		result[key] = value
+	}
+
+	for key, property := range object.Properties {
+
+		switch value := result[key].(type) {
+
+		case nil:
+			switch typed := property.(type) {
+			case schema.Object:
+				result[key] = completeRow(property, mapof.Any{})
+			case schema.Array:
+				result[key] = newArray(typed)
+			default:
+				result[key] = property.DefaultValue()
+			}
+
+		case mapof.Any:
+			result[key] = completeRow(property, value)
+
+		case map[string]any:
+			result[key] = completeRow(property, value)
+		}
+	}
+
+	return result
+}
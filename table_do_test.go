@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/benpate/form"
+	"github.com/benpate/rosetta/mapof"
 	"github.com/benpate/rosetta/null"
 	"github.com/benpate/rosetta/schema"
 	"github.com/stretchr/testify/assert"
@@ -54,7 +55,7 @@ func TestDo_Edit(t *testing.T) {
 	table := newTestTable()
 	db := table.Object.(*testDatabase)
 
-	err := table.Do(mustURL(t, "http://x?edit=0"), map[string]any{"name": "Kyle Reese", "age": 30})
+	err := table.Do(mustURL(t, "http://x?edit=0"), withVersion(t, table, 0, map[string]any{"name": "Kyle Reese", "age": 30}))
 
 	require.NoError(t, err)
 	assert.Equal(t, "Kyle Reese", db.Data[0]["name"])
@@ -65,7 +66,7 @@ func TestDo_Delete(t *testing.T) {
 	table := newTestTable()
 	db := table.Object.(*testDatabase)
 
-	err := table.Do(mustURL(t, "http://x?delete=0"), nil)
+	err := table.Do(mustURL(t, "http://x?delete=0&version="+testRowVersion(t, table, 0)), nil)
 
 	require.NoError(t, err)
 	require.Equal(t, 1, len(db.Data))
@@ -138,7 +139,7 @@ func TestDoEdit_ExistingRow(t *testing.T) {
 	table := newTestTable()
 	db := table.Object.(*testDatabase)
 
-	err := table.DoEdit(map[string]any{"name": "Miles Dyson", "age": 40}, 1)
+	err := table.DoEdit(withVersion(t, table, 1, map[string]any{"name": "Miles Dyson", "age": 40}), 1)
 
 	require.NoError(t, err)
 	require.Equal(t, 2, len(db.Data)) // length unchanged
@@ -214,7 +215,7 @@ func TestDoEdit_MissingStringKeyClearsField(t *testing.T) {
 	db := table.Object.(*testDatabase)
 
 	// Omit "name" entirely; supply a valid "age".
-	err := table.DoEdit(map[string]any{"age": 30}, 0)
+	err := table.DoEdit(withVersion(t, table, 0, map[string]any{"age": 30}), 0)
 
 	require.NoError(t, err)
 	assert.Nil(t, db.Data[0]["name"]) // nil overwrote the previous "John Connor"
@@ -229,7 +230,7 @@ func TestDoEdit_MissingIntegerKeyFails(t *testing.T) {
 	db := table.Object.(*testDatabase)
 
 	// Supply a valid "name" but omit "age".
-	err := table.DoEdit(map[string]any{"name": "Kyle Reese"}, 0)
+	err := table.DoEdit(withVersion(t, table, 0, map[string]any{"name": "Kyle Reese"}), 0)
 
 	require.Error(t, err)
 	assert.EqualValues(t, 20, db.Data[0]["age"]) // age field never reached a valid write
@@ -254,7 +255,7 @@ func TestDoEdit_MissingRequiredKeyFails(t *testing.T) {
 	db := table.Object.(*testDatabase)
 
 	// Omit the Required "name"; supply a valid "age".
-	err := table.DoEdit(map[string]any{"age": 30}, 0)
+	err := table.DoEdit(withVersion(t, table, 0, map[string]any{"age": 30}), 0)
 
 	require.Error(t, err)
 	assert.Equal(t, "John Connor", db.Data[0]["name"]) // original row untouched
@@ -300,7 +301,7 @@ func TestDoEdit_ValidationRejectsRequired(t *testing.T) {
 	table := newConstrainedTable()
 	db := table.Object.(*testDatabase)
 
-	err := table.DoEdit(map[string]any{"name": "", "age": 30}, 0)
+	err := table.DoEdit(withVersion(t, table, 0, map[string]any{"name": "", "age": 30}), 0)
 
 	require.Error(t, err)
 	assert.Equal(t, "John Connor", db.Data[0]["name"]) // original row is left untouched
@@ -312,7 +313,7 @@ func TestDoEdit_ValidationRejectsBadType(t *testing.T) {
 
 	table := newConstrainedTable()
 
-	err := table.DoEdit(map[string]any{"name": "Bob", "age": "not-a-number"}, 0)
+	err := table.DoEdit(withVersion(t, table, 0, map[string]any{"name": "Bob", "age": "not-a-number"}), 0)
 
 	require.Error(t, err)
 }
@@ -324,7 +325,7 @@ func TestDoEdit_ValidationTruncatesString(t *testing.T) {
 	table := newConstrainedTable()
 	db := table.Object.(*testDatabase)
 
-	err := table.DoEdit(map[string]any{"name": "Abcdefghij", "age": 30}, 0)
+	err := table.DoEdit(withVersion(t, table, 0, map[string]any{"name": "Abcdefghij", "age": 30}), 0)
 
 	require.NoError(t, err)
 	assert.Equal(t, "Abcde", db.Data[0]["name"]) // truncated to MaxLength (5 runes)
@@ -337,12 +338,77 @@ func TestDoEdit_ValidationClampsInteger(t *testing.T) {
 	table := newConstrainedTable()
 	db := table.Object.(*testDatabase)
 
-	err := table.DoEdit(map[string]any{"name": "Bob", "age": 999}, 0)
+	err := table.DoEdit(withVersion(t, table, 0, map[string]any{"name": "Bob", "age": 999}), 0)
 
 	require.NoError(t, err)
 	assert.EqualValues(t, 150, db.Data[0]["age"]) // clamped to Maximum
 }
 
+/******************************************
+ * DoEdit() - Atomic Updates
+ *
+ * DoEdit stages every value on a copy of the row and validates the whole row
+ * before writing anything, so a failure never leaves the table half-updated.
+ ******************************************/
+
+// A later field that fails validation leaves earlier fields untouched.
+func TestDoEdit_AtomicFieldFailure(t *testing.T) {
+
+	table := newConstrainedTable()
+	db := table.Object.(*testDatabase)
+
+	err := table.DoEdit(withVersion(t, table, 0, map[string]any{"name": "Kyle", "age": "not-a-number"}), 0)
+
+	require.Error(t, err)
+	assert.Equal(t, "John Connor", db.Data[0]["name"]) // "name" was valid, but never written
+	assert.EqualValues(t, 20, db.Data[0]["age"])
+}
+
+// The whole row is validated, including required fields that are not in the Form,
+// so an invalid new row is never appended.
+func TestDoEdit_AtomicRowValidation(t *testing.T) {
+
+	s := schema.Schema{
+		Element: schema.Object{
+			Properties: schema.ElementMap{
+				"data": schema.Array{
+					Items: schema.Object{
+						Properties: schema.ElementMap{
+							"name":   schema.String{},
+							"age":    schema.Integer{},
+							"taskId": schema.String{Required: true},
+						},
+					},
+				},
+			},
+		},
+	}
+	f := testForm()
+	table := New(&s, &f, testData(), "data", testIconProvider{}, "http://localhost/table")
+	db := table.Object.(*testDatabase)
+
+	err := table.DoEdit(map[string]any{"name": "Kyle Reese", "age": 30}, 2)
+
+	require.Error(t, err)
+	assert.Equal(t, 2, len(db.Data)) // no partial row was appended
+}
+
+// Staging works on a copy, so nested values in the original row are never shared
+func TestCloneRow(t *testing.T) {
+
+	original := mapof.Any{"name": "John", "address": mapof.Any{"city": "LA"}, "tags": []any{"a"}}
+	clone := cloneRow(&original)
+
+	clone["name"] = "Kyle"
+	clone["address"].(mapof.Any)["city"] = "SF"
+	clone["tags"].([]any)[0] = "b"
+
+	assert.Equal(t, "John", original["name"])
+	assert.Equal(t, "LA", original["address"].(mapof.Any)["city"])
+	assert.Equal(t, "a", original["tags"].([]any)[0])
+	assert.Equal(t, mapof.Any{}, cloneRow(nil))
+}
+
 /******************************************
  * DoEdit() - Field Allow-listing (Mass-Assignment Guard)
  *
@@ -390,7 +456,7 @@ func TestDoEdit_IgnoresKeyNotInForm(t *testing.T) {
 	})
 	db := table.Object.(*testDatabase)
 
-	err := table.DoEdit(map[string]any{"name": "Bob", "age": 1, "secret": "INJECTED"}, 0)
+	err := table.DoEdit(withVersion(t, table, 0, map[string]any{"name": "Bob", "age": 1, "secret": "INJECTED"}), 0)
 
 	require.NoError(t, err)
 	assert.Equal(t, "Bob", db.Data[0]["name"])
@@ -409,7 +475,7 @@ func TestDoEdit_IgnoresReadOnlyField(t *testing.T) {
 	})
 	db := table.Object.(*testDatabase)
 
-	err := table.DoEdit(map[string]any{"name": "Bob", "age": 1, "secret": "INJECTED"}, 0)
+	err := table.DoEdit(withVersion(t, table, 0, map[string]any{"name": "Bob", "age": 1, "secret": "INJECTED"}), 0)
 
 	require.NoError(t, err)
 	assert.Equal(t, "Bob", db.Data[0]["name"])
@@ -425,7 +491,7 @@ func TestDoDelete_Success(t *testing.T) {
 	table := newTestTable()
 	db := table.Object.(*testDatabase)
 
-	err := table.DoDelete(0)
+	err := table.DoDelete(0, testRowVersion(t, table, 0))
 
 	require.NoError(t, err)
 	require.Equal(t, 1, len(db.Data))
@@ -438,7 +504,7 @@ func TestDoDelete_NotAllowed(t *testing.T) {
 	table.CanDelete = false
 	db := table.Object.(*testDatabase)
 
-	err := table.DoDelete(0)
+	err := table.DoDelete(0, testRowVersion(t, table, 0))
 
 	require.Error(t, err)
 	assert.Equal(t, 2, len(db.Data)) // nothing removed
@@ -448,8 +514,8 @@ func TestDoDelete_IndexOutOfRange(t *testing.T) {
 
 	table := newTestTable()
 
-	// schema.Remove returns false for an out-of-range index => DoDelete returns an error
-	err := table.DoDelete(99)
+	// An out-of-range index has no row to check or remove => DoDelete returns an error
+	err := table.DoDelete(99, "")
 
 	require.Error(t, err)
 }
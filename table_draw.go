@@ -2,9 +2,11 @@ package table
 
 import (
 	"bytes"
+	stdhtml "html"
 	"io"
 	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/benpate/derp"
 	"github.com/benpate/form"
@@ -20,34 +22,162 @@ import (
  *******************************************/
 
 // Draw renders the table to the buffer, choosing view, add, or edit mode based
-// on the "add", "edit", and "focus" query parameters.
+// on the "add", "insert", "edit", "cell", "autosave", "next", "previous",
+// "duplicate", "bulk", and "focus" query parameters.  View options such as "sort", "dir", "page", "size", "q", and
+// column filters are also read here, and carried forward into every link.  The "expand" parameters display the
+// Details of each listed row, and the "path" parameter (such as "data.2.items") draws a nested table instead (see SubTableType).
+// In a tree (see UseParentPath) the "parent" parameter adds the new row beneath another row.
 func (widget Table) Draw(params *url.URL, buffer io.Writer) error {
 
+	const location = "table.Widget.Draw"
+
 	query := params.Query()
 
+	// Requests for a nested table draw only the nested table
+	if path := query.Get("path"); widget.isSubTablePath(path) {
+
+		subTable, err := widget.findSubTable(path)
+
+		if err != nil {
+			return derp.Wrap(err, location, "Finding sub-table", path)
+		}
+
+		return subTable.Draw(params, buffer)
+	}
+
+	widget = widget.scalarColumns()
+	widget.view = parseViewState(query)
+
 	// Parse and clamp the focus column to a valid index, since it comes from untrusted query input.
 	// A non-numeric value parses to 0, which the clamp below treats as the first column.
 	focusColumn, _ := strconv.Atoi(query.Get("focus"))
-	if (focusColumn < 0) || (focusColumn >= len(widget.Form.Children)) {
+	if (focusColumn < 0) || (focusColumn >= widget.fieldCount()) {
 		focusColumn = 0
 	}
 
-	// Try to ADD a row
+	// Focus the first field that failed validation, so the user can correct it
+	if errorColumn := widget.errorColumn(); errorColumn >= 0 {
+		focusColumn = errorColumn
+	}
+
+	// Try to ADD a row, which may be the child of another row in a tree
 	if query.Get("add") == "true" {
+
+		if parent := query.Get("parent"); widget.isTree() && (parent != "") {
+			if parentIndex, ok, _ := widget.lookupRow(parent); ok {
+				widget.addParent = null.NewInt(parentIndex)
+			}
+		}
+
 		return widget.drawTable(null.Int{}, true, focusColumn, buffer)
 	}
 
-	// Try to EDIT a row
+	// Try to INSERT a row at a specific position.  Requests to insert rows where
+	// they cannot be inserted (such as into a sorted table) are ignored.
+	if insert := query.Get("insert"); (insert != "") && widget.canInsertRows() {
+		if insertIndex, err := strconv.Atoi(insert); err == nil {
+			widget.insertRow = null.NewInt(insertIndex)
+			return widget.drawTable(null.Int{}, true, focusColumn, buffer)
+		}
+	}
+
+	// After a row is saved with Tab (or Shift-Tab) edit the next (or previous) row,
+	// starting from its first (or last) column.  These are checked before "edit",
+	// because the URL that saved the row also names the row that was saved.  If
+	// the save was rejected (see DrawErrors) then the saved row stays open instead.
+	if widget.submitted == nil {
+
+		if next := query.Get("next"); next != "" {
+			if nextIndex, ok, _ := widget.lookupRow(next); ok {
+				return widget.drawTable(null.NewInt(nextIndex), false, 0, buffer)
+			}
+		}
+
+		if previous := query.Get("previous"); previous != "" {
+			if previousIndex, ok, _ := widget.lookupRow(previous); ok {
+				return widget.drawTable(null.NewInt(previousIndex), false, len(widget.Form.Children)-1, buffer)
+			}
+		}
+	}
+
+	// Try to EDIT a row.  A row that cannot be found (such as a key for a row
+	// that has since been deleted) falls through to view-only mode.
 	if edit := query.Get("edit"); edit != "" {
-		if editIndex, err := strconv.Atoi(edit); err == nil {
+		if editIndex, ok, _ := widget.lookupRow(edit); ok {
 			return widget.drawTable(null.NewInt(editIndex), false, focusColumn, buffer)
 		}
 	}
 
+	// Try to EDIT a single cell.  Cells that cannot be edited fall through to view-only mode.
+	if cell := query.Get("cell"); cell != "" {
+		if cellIndex, ok, _ := widget.lookupRow(cell); ok {
+			if _, editable := widget.editableField(focusColumn); editable {
+				widget.editCell = null.NewInt(focusColumn)
+				return widget.drawTable(null.NewInt(cellIndex), false, focusColumn, buffer)
+			}
+		}
+	}
+
+	// After a field is AUTOSAVEd, keep editing the same row, moving on to the next
+	// column (unless the value was rejected)
+	if autosave := query.Get("autosave"); autosave != "" {
+		if autosaveIndex, ok, _ := widget.lookupRow(autosave); ok {
+
+			if (widget.submitted == nil) && (focusColumn < widget.fieldCount()-1) {
+				focusColumn++
+			}
+
+			widget.autoSaving = true
+			return widget.drawTable(null.NewInt(autosaveIndex), false, focusColumn, buffer)
+		}
+	}
+
+	// After a row is DUPLICATEd, edit the copy (which is immediately after the original)
+	if duplicate := query.Get("duplicate"); duplicate != "" {
+		if duplicateIndex, ok, _ := widget.lookupRow(duplicate); ok {
+			return widget.drawTable(null.NewInt(duplicateIndex+1), false, focusColumn, buffer)
+		}
+	}
+
+	// Try to change one column in every selected row
+	if query.Get("bulk") == "edit" {
+		if column, err := strconv.Atoi(query.Get("column")); err == nil {
+			widget.bulkColumn = null.NewInt(column)
+		}
+	}
+
+	if selected, ok := query[selectedField]; ok {
+		widget.selected = selected
+	}
+
 	// Otherwise, just draw the table (view only)
 	return widget.drawTable(null.Int{}, false, focusColumn, buffer)
 }
 
+// DrawConflict redraws the table after an edit was rejected because the row had
+// changed (see IsConflict).  The edit row is drawn again with the values that the
+// user submitted, along with the newer values that are already saved, so that the
+// user can reconcile them.  Saving again replaces the newer values.
+func (widget Table) DrawConflict(params *url.URL, data map[string]any, buffer io.Writer) error {
+
+	const location = "table.Widget.DrawConflict"
+
+	if path := params.Query().Get("path"); widget.isSubTablePath(path) {
+
+		subTable, err := widget.findSubTable(path)
+
+		if err != nil {
+			return derp.Wrap(err, location, "Finding sub-table", path)
+		}
+
+		return subTable.DrawConflict(params, data, buffer)
+	}
+
+	widget.submitted = data
+	widget.conflict = true
+	return widget.Draw(params, buffer)
+}
+
 // DrawView returns a VIEW ONLY representation of the table
 func (widget Table) DrawView(buffer io.Writer) error {
 	return widget.drawTable(null.Int{}, false, 0, buffer)
@@ -115,6 +245,49 @@ func (widget Table) DrawEditString(index int) (string, error) {
  * Draw Methods (these do the actual work of rendering the table)
  ******************************************/
 
+// rowPermissions are the actions that a single render of the table offers on its
+// rows, after the table's length and current view have been applied
+type rowPermissions struct {
+	Add       bool // If TRUE, then new rows can be added at the end of the table
+	Edit      bool // If TRUE, then rows can be edited
+	Delete    bool // If TRUE, then rows can be deleted
+	Move      bool // If TRUE, then rows can be moved within the table
+	Insert    bool // If TRUE, then new rows can be inserted between rows
+	Duplicate bool // If TRUE, then rows can be duplicated
+	AddChild  bool // If TRUE, then new rows can be added beneath rows in a tree
+}
+
+// getRowPermissions returns the effective permissions for one render of the
+// table.  The table data's min/max bounds only change this value, and never
+// the caller's widget.
+func (widget Table) getRowPermissions(tableElement schema.Array, tableLength int) rowPermissions {
+
+	result := rowPermissions{
+		Add:       widget.CanAdd,
+		Edit:      widget.CanEdit,
+		Delete:    widget.CanDelete,
+		Move:      widget.canMoveRows(),
+		Duplicate: widget.CanDuplicate,
+	}
+
+	// Only allow ADDs (and DUPLICATEs) if the table is smaller than the maximum value
+	if (tableElement.MaxLength > 0) && (tableLength >= tableElement.MaxLength) {
+		result.Add = false
+		result.Duplicate = false
+	}
+
+	// Only allow DELETEs if the table is larger than the minimum value
+	if tableLength <= tableElement.MinLength {
+		result.Delete = false
+	}
+
+	// Rows can only be inserted while they are displayed in their stored order
+	result.Insert = result.Add && widget.canInsertRows()
+	result.AddChild = result.Add && widget.isTree()
+
+	return result
+}
+
 // drawTable writes this table to the provided io.Writer
 func (widget Table) drawTable(editRow null.Int, addRow bool, focusColumn int, buffer io.Writer) error {
 
@@ -127,9 +300,9 @@ func (widget Table) drawTable(editRow null.Int, addRow bool, focusColumn int, bu
 		return derp.Wrap(err, location, "Getting table element")
 	}
 
-	rowElement := tableElement.Items
+	widget = widget.scalarColumns()
 	tableSchema := schema.New(tableElement)
-	rowSchema := schema.New(rowElement)
+	rowSchema := newRowSchema(tableElement)
 
 	tableValue, err := widget.Schema.Get(widget.Object, widget.Path)
 
@@ -139,32 +312,19 @@ func (widget Table) drawTable(editRow null.Int, addRow bool, focusColumn int, bu
 
 	tableLength := convert.SliceLength(tableValue)
 
-	// Compute the effective permissions for THIS render as locals, so the table data's
-	// min/max bounds never mutate the caller's widget.
-	canAdd := widget.CanAdd
-	canEdit := widget.CanEdit
-	canDelete := widget.CanDelete
-
-	// Only allow ADDs if the table is smaller than the maximum value
-	if (tableElement.MaxLength > 0) && (tableLength >= tableElement.MaxLength) {
-		canAdd = false
-	}
-
-	// Only allow DELETEs if the table is larger than the minimum value
-	if tableLength <= tableElement.MinLength {
-		canDelete = false
-	}
+	// Compute the effective permissions for THIS render once, and share them with every row
+	permissions := widget.getRowPermissions(tableElement, tableLength)
 
 	//
 	// Verify Permissions Here
 	//
 
-	if canAdd && addRow {
+	if permissions.Add && addRow {
 
 		// If adding is allowed and requested, then set the editable row to a new row at the end of the table
 		editRow.Set(tableLength)
 
-	} else if canEdit && editRow.IsPresent() {
+	} else if permissions.Edit && editRow.IsPresent() {
 
 		// If editing is allowed and requested, then bounds check the editRow
 		// If the editRow is out of bounds, then use view-only mode
@@ -178,14 +338,130 @@ func (widget Table) drawTable(editRow null.Int, addRow bool, focusColumn int, bu
 		editRow.Unset()
 	}
 
+	// Collect all rows before drawing, so that they can be sorted and paged
+	rows := make([]any, tableLength)
+	for rowIndex := range rows {
+
+		rowValue, err := tableSchema.Get(tableValue, strconv.Itoa(rowIndex))
+
+		if err != nil {
+			return derp.Wrap(err, location, "Getting row data", tableSchema, tableValue, rowIndex, tableLength)
+		}
+
+		rows[rowIndex] = newRowValue(tableElement, rowValue)
+	}
+
+	widget.rowKeys = widget.getRowKeys(&rowSchema, rows)
+
+	// Version tokens are only needed by edit forms and delete links
+	if permissions.Edit || permissions.Delete {
+		widget.rowVersions = widget.getRowVersions(&rowSchema, rows)
+	}
+
+	sortOrder := widget.sortRows(&rowSchema, rows)
+	rowOrder, err := widget.searchRows(&rowSchema, rows, sortOrder, editRow)
+
+	if err != nil {
+		return derp.Wrap(err, location, "Searching rows", widget.Path)
+	}
+
+	// Apply column filters, carrying only the valid filters forward into every link
+	filters := ParseFilterState(rowSchema, widget.Form.Children, widget.view.Filters)
+
+	if widget.CanFilter {
+		rowOrder = widget.filterRows(filters, rows, rowOrder, editRow)
+		widget.view.Filters = url.Values{}
+		filters.setQuery(widget.view.Filters)
+	} else {
+		widget.view.Filters = nil
+	}
+
+	// New rows can only be added beneath rows that are in the table
+	tree := widget.isTree()
+	if !tree || !permissions.Add || !addRow || (widget.addParent.Int() < 0) || (widget.addParent.Int() >= tableLength) {
+		widget.addParent.Unset()
+	}
+
+	// Gather rows into groups (or into a tree).  Rows in collapsed groups (or
+	// beneath collapsed rows) are still counted by the footer's aggregates, but
+	// are not displayed.
+	grouped := widget.isGrouped(&rowSchema) && !tree
+	aggregateOrder := rowOrder
+
+	if grouped {
+		widget.groups, widget.rowGroups = widget.groupRows(&rowSchema, rows, rowOrder, editRow)
+		aggregateOrder = groupedOrder(widget.groups)
+		rowOrder = expandedOrder(widget.groups)
+	}
+
+	if tree {
+		widget.tree = widget.treeRows(&rowSchema, rows, sortOrder, rowOrder, editRow)
+		aggregateOrder = widget.tree.Order
+		rowOrder = widget.tree.Visible
+	}
+
+	widget.rowOrder = rowOrder
+	widget.rowPositions = getRowPositions(rowOrder)
+
+	// Rows can only be selected while the table is not being edited
+	widget.selecting = widget.CanSelect && !editRow.IsPresent()
+
+	// Selected rows can be changed one column at a time
+	bulkField, bulkEdit := widget.editableField(widget.bulkColumn.Int())
+	bulkEdit = bulkEdit && widget.bulkColumn.IsPresent() && widget.selecting && permissions.Edit
+	widget.drawingForm = editRow.IsPresent() || bulkEdit
+
+	sortColumn := widget.sortColumn(&rowSchema)
+
+	// Inserting at an invalid position (or at the end) is the same as adding
+	if (widget.insertRow.Int() < 0) || (widget.insertRow.Int() >= tableLength) {
+		widget.insertRow.Unset()
+	}
+
+	// Display the page where a new row will be inserted (or the page of its parent)
+	pageRow := editRow
+	if addRow && widget.insertRow.IsPresent() {
+		pageRow = widget.insertRow
+	} else if widget.addParent.IsPresent() {
+		pageRow = widget.addParent
+	}
+
+	// Resolve the page to display before rendering, so that every link carries it
+	pageSize := widget.pageSize()
+	pageCount := getPageCount(len(rowOrder), pageSize)
+	widget.view.Page = widget.currentPage(rowOrder, pageRow, pageSize, pageCount)
+
 	// Begin rendering the widget
 	b := html.New()
 
 	// Wrapper
 	if editRow.IsPresent() {
+
+		// New rows have no key (or index) yet, so they are posted as an "add" or "insert"
+		action, actionRow, actionColumn := "edit", editRow.Int(), 0
+		if widget.editCell.IsPresent() {
+			action, actionColumn = "cell", widget.editCell.Int()
+		} else if editRow.Int() == tableLength {
+			action = "add"
+			if widget.insertRow.IsPresent() {
+				action, actionRow = "insert", widget.insertRow.Int()
+			} else if widget.addParent.IsPresent() {
+				action, actionRow = "add-child", widget.addParent.Int()
+			}
+		}
+
 		b.Form("", "").
 			Class("grid").
-			Data("hx-post", widget.getURL("edit", editRow.Int(), 0)).
+			Data("hx-post", widget.getURL(action, actionRow, actionColumn)).
+			Data("hx-target", "this").
+			Data("hx-swap", "outerHTML").
+			Data("hx-push-url", "false")
+
+	} else if bulkEdit {
+
+		b.Form("", "").
+			Class("grid").
+			Data("hx-post", widget.getURL("bulk-edit", 0, widget.bulkColumn.Int())).
 			Data("hx-target", "this").
 			Data("hx-swap", "outerHTML").
 			Data("hx-push-url", "false")
@@ -199,65 +475,218 @@ func (widget Table) drawTable(editRow null.Int, addRow bool, focusColumn int, bu
 			Data("hx-push-url", "false")
 	}
 
+	// Search box (hidden while editing, but the search is still carried in every link)
+	if widget.CanSearch && !editRow.IsPresent() {
+		widget.drawSearch(b.SubTree())
+	}
+
+	// Bulk actions for the selected rows
+	if bulkEdit {
+		if err := widget.drawBulkEdit(&rowSchema, bulkField, b.SubTree()); err != nil {
+			return derp.Wrap(err, location, "Drawing bulk editor", widget.Path)
+		}
+	} else if widget.selecting && (permissions.Edit || permissions.Delete) {
+		widget.drawBulkActions(permissions, b.SubTree())
+	}
+
 	// Table
-	b.Table().
-		Class("grid")
+	grid := b.Table().Class("grid")
+
+	if tree {
+		grid.Attr("role", "treegrid")
+	}
+
+	if caption := widget.caption(); caption != "" {
+		b.Container("caption").InnerText(caption).Close()
+	}
 
 	// Header row
+	b.Container("thead")
 	b.TR().Class("grid-header")
-	for _, field := range widget.Form.Children {
-		td := b.TD().Class("grid-cell") // nolint:scopeguard
+
+	if widget.selecting {
+		widget.drawSelectHeader(b.SubTree())
+	}
+
+	for colIndex, field := range widget.Form.Children {
+
+		sortable := widget.CanSort && isSortable(&rowSchema, field)
+
+		classes := []string{"grid-cell"}
+		if sortable {
+			classes = append(classes, "grid-sortable")
+		}
+
+		th := b.Container("th").Class(classes...).Attr("scope", "col") // nolint:scopeguard
+
 		if width := field.Options.GetString("column-width"); width != "" {
-			td.Style("width", width)
+			th.Style("width", width)
+		}
+
+		if sortable {
+			th.Data("hx-get", widget.getURL("sort", 0, colIndex)).Data("hx-trigger", "click")
+
+			switch {
+			case colIndex != sortColumn:
+				th.Attr("aria-sort", "none")
+			case widget.view.SortDesc:
+				th.Attr("aria-sort", "descending")
+			default:
+				th.Attr("aria-sort", "ascending")
+			}
 		}
+
 		b.Div().InnerText(field.Label).Close()
-		b.Close() // TD
+
+		if colIndex == sortColumn {
+			if widget.view.SortDesc {
+				b.Span().Attr("aria-hidden", "true").InnerHTML(widget.Icons.Get("sort-descending")).Close()
+			} else {
+				b.Span().Attr("aria-hidden", "true").InnerHTML(widget.Icons.Get("sort-ascending")).Close()
+			}
+		}
+
+		b.Close() // TH
 	}
-	b.TD().Class("grid-cell", "grid-controls").Close()
+	b.Container("th").Class("grid-cell", "grid-controls").Attr("scope", "col").Attr("aria-label", widget.message(MessageActions)).Close()
 	b.Close() // TR
 
-	// Data rows
-	for rowIndex := 0; rowIndex < tableLength; rowIndex++ {
+	// Filter row (hidden while editing, but the filters are still carried in every link)
+	if widget.CanFilter && !editRow.IsPresent() && widget.hasFilters(&rowSchema) {
+		widget.drawFilters(&rowSchema, filters, b.SubTree())
+	}
 
-		rowValue, err := tableSchema.Get(tableValue, strconv.Itoa(rowIndex))
+	b.Close() // THEAD
 
-		if err != nil {
-			return derp.Wrap(err, location, "Getting row data", tableSchema, tableValue, rowIndex, tableLength)
+	b.Container("tbody")
+
+	// Data rows.  Sorting and paging only change which rows are displayed (and in
+	// what order) so each rowIndex still addresses its original position in the data.
+	addRowDrawn := false
+	pageRows := getPage(rowOrder, widget.view.Page, pageSize)
+
+	// New children are added after the last row beneath their parent
+	addChildAfter := -1
+	if widget.addParent.IsPresent() {
+		addChildAfter = widget.lastDescendant(widget.addParent.Int())
+	}
+
+	for position, rowIndex := range pageRows {
+
+		rowValue := rows[rowIndex]
+
+		// Introduce each group with a header
+		if grouped {
+			previousRow := -1
+			if position > 0 {
+				previousRow = pageRows[position-1]
+			}
+			widget.drawGroupStart(&rowSchema, rows, rowIndex, previousRow, b)
+		}
+
+		// Draw the row for inserting a new record above the row at the same position
+		if permissions.Add && addRow && (sortColumn < 0) && widget.insertRow.IsPresent() && (widget.insertRow.Int() == rowIndex) {
+
+			if err := widget.drawNewRow(&rowSchema, permissions.Add, focusColumn, b); err != nil {
+				return derp.Wrap(err, location, "Drawing row (insert)", widget.Path, rowIndex)
+			}
+
+			addRowDrawn = true
 		}
 
-		if canEdit && editRow.IsPresent() && (editRow.Int() == rowIndex) {
+		if permissions.Edit && editRow.IsPresent() && (editRow.Int() == rowIndex) {
+
+			if widget.rowError != "" {
+				widget.drawMessage("grid-error", widget.rowError, b.SubTree())
+			}
+
+			if widget.conflict {
+				widget.drawMessage("grid-conflict", widget.message(MessageConflict), b.SubTree())
+			}
 
-			if err := widget.drawEditRow(&rowSchema, rowValue, canEdit, focusColumn, b.SubTree()); err != nil {
+			if err := widget.drawEditRow(&rowSchema, rowIndex, rowValue, permissions.Edit, focusColumn, b.SubTree()); err != nil {
 				return derp.Wrap(err, location, "Drawing row (edit)", widget.Path, rowIndex)
 			}
 
 		} else {
 
-			if err := widget.drawViewRow(&rowSchema, rowIndex, rowValue, canEdit, canDelete, b.SubTree()); err != nil {
+			if err := widget.drawViewRow(&rowSchema, rowIndex, rowValue, permissions, b.SubTree()); err != nil {
 				return derp.Wrap(err, location, "Drawing row (view)", widget.Path, rowIndex)
 			}
 		}
-	}
 
-	// If we're not editing an existing row, then let users add a new row
-	if canAdd {
-		if addRow {
-			if err := widget.drawAddRow(&rowSchema, canAdd, b.SubTree()); err != nil {
-				return derp.Wrap(err, location, "Drawing row (add)", widget.Path, tableLength)
+		if grouped {
+			widget.drawGroupEnd(&rowSchema, rows, rowIndex, b)
+		}
+
+		if permissions.Add && addRow && (rowIndex == addChildAfter) {
+
+			if err := widget.drawNewRow(&rowSchema, permissions.Add, focusColumn, b); err != nil {
+				return derp.Wrap(err, location, "Drawing row (add child)", widget.Path, rowIndex)
 			}
-		} else {
-			b.Close() // TABLE
-			b.Div()
-			b.Button().
-				Type("button").
-				Class("link").
-				Data("hx-get", widget.getURL("add", tableLength, 0)).
-				InnerHTML(widget.Icons.Get("plus") + " Add a Row")
-			b.Close() // Button
-			b.Close() // Div
+
+			addRowDrawn = true
 		}
 	}
 
+	// Collapsed groups at the end of the table are drawn on the last page
+	if grouped && (widget.view.Page >= pageCount) {
+		widget.drawGroupsAfter(&rowSchema, rows, b)
+	}
+
+	// Draw the row for adding a new record at the end, if requested
+	if permissions.Add && addRow && !addRowDrawn {
+		if err := widget.drawNewRow(&rowSchema, permissions.Add, focusColumn, b); err != nil {
+			return derp.Wrap(err, location, "Drawing row (add)", widget.Path, tableLength)
+		}
+	}
+
+	b.Close() // TBODY
+
+	// Footer, with the aggregate of each column and a button to add a new row
+	hasAggregates := widget.hasAggregates()
+	canAddFooter := permissions.Add && !addRow
+
+	if hasAggregates || canAddFooter {
+		b.Container("tfoot")
+	}
+
+	if hasAggregates {
+		widget.drawAggregates(&rowSchema, rows, aggregateOrder, "grid-aggregates", b.SubTree())
+	}
+
+	// If we're not editing an existing row, then let users add a new row
+	if canAddFooter {
+		b.TR().Class("grid-footer")
+		b.TD().Class("grid-cell").Attr("colspan", strconv.Itoa(widget.columnCount()))
+		b.Button().
+			Type("button").
+			Class("link").
+			Data("hx-get", widget.getURL("add", tableLength, 0)).
+			InnerHTML(widget.Icons.Get("plus") + " " + stdhtml.EscapeString(widget.message(MessageAddRow)))
+		b.Close() // Button
+		b.Close() // TD
+		b.Close() // TR
+	}
+
+	if hasAggregates || canAddFooter {
+		b.Close() // TFOOT
+	}
+
+	b.Close() // TABLE
+
+	// Let users move between pages when the table is too long for one
+	if pageCount > 1 {
+		widget.drawPager(pageCount, b.SubTree())
+	}
+
+	// Tell screen readers about the last action
+	widget.drawAnnouncement(b.SubTree())
+
+	if widget.Keyboard {
+		widget.drawKeyboardScript(b.SubTree())
+	}
+
 	b.CloseAll()
 
 	if _, err := buffer.Write(b.Bytes()); err != nil {
@@ -282,7 +711,18 @@ func focusField(field form.Element) form.Element {
 	return field
 }
 
-func (widget Table) drawAddRow(rowSchema *schema.Schema, canAdd bool, b *html.Builder) error {
+// drawNewRow writes the row for adding a new record, along with any error
+// message about the values that were last submitted for it.
+func (widget Table) drawNewRow(rowSchema *schema.Schema, canAdd bool, focusColumn int, b *html.Builder) error {
+
+	if widget.rowError != "" {
+		widget.drawMessage("grid-error", widget.rowError, b.SubTree())
+	}
+
+	return widget.drawAddRow(rowSchema, canAdd, focusColumn, b.SubTree())
+}
+
+func (widget Table) drawAddRow(rowSchema *schema.Schema, canAdd bool, focusColumn int, b *html.Builder) error {
 
 	const location = "table.Widget.drawAddRow"
 
@@ -291,36 +731,69 @@ func (widget Table) drawAddRow(rowSchema *schema.Schema, canAdd bool, b *html.Bu
 		return nil
 	}
 
-	b.TR().Class("grid-row", "grid-editable")
+	row := b.TR().Class("grid-row", "grid-editable")
+
+	// New children are one level beneath their parent
+	depth := -1
+	if widget.addParent.IsPresent() {
+		depth = widget.treeDepth(widget.addParent.Int()) + 1
+		widget.treeRowAttributes(depth, -1, row)
+	}
 
 	width := "width:calc(100% / " + strconv.Itoa(len(widget.Form.Children)) + ")"
 	f := form.New(*rowSchema, *widget.Form)
 
+	// New rows start empty, unless the user has already submitted values
+	var addValue any
+	if widget.submitted != nil {
+		addValue = widget.submittedRow(rowSchema, nil)
+	}
+
 	for column, field := range widget.Form.Children {
+
+		// New rows do not have any nested rows yet
+		if isSubTable(field) {
+			b.TD().Class("grid-cell").Style(width).Close()
+			continue
+		}
+
 		b.TD().Class("grid-cell", "grid-editable").Style(width)
 
-		// Focus the first column when adding a new row
-		if column == 0 {
+		if (column == 0) && (depth >= 0) {
+			widget.drawTreeToggle(-1, depth, false, b.SubTree())
+		}
+
+		// Focus the requested column when adding a new row
+		if column == focusColumn {
 			field = focusField(field)
 		}
 
-		if err := field.Edit(&f, widget.LookupProvider, nil, b.SubTree()); err != nil {
+		if err := field.Edit(&f, widget.LookupProvider, addValue, b.SubTree()); err != nil {
 			return derp.Wrap(err, location, "Rendering field", field)
 		}
+
+		widget.drawFieldError(field.Path, b.SubTree())
 		b.Close() // TD
 	}
 
 	b.TD().Class("grid-cell", "grid-editable", "grid-controls")
-	b.Button().Type("submit").Class("text-green").InnerHTML(widget.Icons.Get("save")).Close()
+	b.Button().Type("submit").Class("text-green").Attr("aria-label", widget.message(MessageSaveNewRow)).InnerHTML(widget.Icons.Get("save")).Close()
 	b.Space()
-	b.Button().Type("button").Data("hx-get", widget.TargetURL).InnerHTML(widget.Icons.Get("cancel")).Close()
+	widget.drawCancelButton(b.SubTree())
 	b.Close() // TD
 
 	b.Close() // TR
+
+	if widget.hasDetails() {
+		if err := widget.drawDetailsEdit(rowSchema, -1, addValue, nil, false, focusColumn, b); err != nil {
+			return derp.Wrap(err, location, "Rendering details")
+		}
+	}
+
 	return nil
 }
 
-func (widget Table) drawEditRow(rowSchema *schema.Schema, rowValue any, canEdit bool, focusColumn int, b *html.Builder) error {
+func (widget Table) drawEditRow(rowSchema *schema.Schema, rowIndex int, rowValue any, canEdit bool, focusColumn int, b *html.Builder) error {
 
 	const location = "table.Widget.drawEditRow"
 
@@ -329,14 +802,77 @@ func (widget Table) drawEditRow(rowSchema *schema.Schema, rowValue any, canEdit
 		return derp.Internal(location, "Editing is not allowed.  THIS SHOULD NEVER HAPPEN")
 	}
 
-	b.TR().Class("grid-row", "grid-editable")
+	editRow := b.TR().Class("grid-row", "grid-editable")
+	depth := widget.treeDepth(rowIndex)
+
+	if depth >= 0 {
+		widget.treeRowAttributes(depth, rowIndex, editRow)
+	}
+
+	// Tab and Shift-Tab save this row and move on to the next or previous row
+	if widget.Keyboard && !widget.editCell.IsPresent() {
+		widget.keyboardEditRow(rowIndex, editRow)
+	}
 
 	width := "width:calc(100% / " + strconv.Itoa(len(widget.Form.Children)) + ")"
 	f := form.New(*rowSchema, *widget.Form)
 
+	// Redraw values that the user has already submitted, instead of the saved values.
+	// Auto-saves only submit one field, so the others keep their saved values.
+	editValue := rowValue
+	if widget.submitted != nil {
+		if widget.autoSaving {
+			editValue = widget.submittedRow(rowSchema, rowValue)
+		} else {
+			editValue = widget.submittedRow(rowSchema, nil)
+		}
+	}
+
+	autoSave := widget.AutoSave && !widget.editCell.IsPresent()
+
 	for index, field := range widget.Form.Children {
 
-		b.TD().Class("grid-cell", "grid-editable").Style(width)
+		// Nested rows are saved on their own, so nested tables are only displayed
+		if isSubTable(field) {
+
+			cellHTML, err := widget.subTableHTML(field, rowIndex)
+
+			if err != nil {
+				return derp.Wrap(err, location, "Rendering sub-table", field)
+			}
+
+			b.TD().Class("grid-cell").Style(width).InnerHTML(widget.treeCellPrefix(index, rowIndex, depth, false) + cellHTML).Close()
+			continue
+		}
+
+		// When editing a single cell, the row's other cells are only displayed
+		if widget.editCell.IsPresent() && (index != widget.editCell.Int()) {
+
+			cellHTML, err := widget.viewCell(&f, field, rowValue)
+
+			if err != nil {
+				return derp.Wrap(err, location, "Rendering field", field)
+			}
+
+			b.TD().Class("grid-cell").Style(width).InnerHTML(widget.treeCellPrefix(index, rowIndex, depth, false) + cellHTML).Close()
+			continue
+		}
+
+		cell := b.TD().Class("grid-cell", "grid-editable").Style(width) // nolint:scopeguard
+
+		// Save each field as soon as it changes.  The change event fires when an
+		// edited field loses focus, and only this field (and the row's version)
+		// is posted.  The response redraws the row, still open for editing.
+		if autoSave {
+			cell.Data("hx-post", widget.getURL("autosave", rowIndex, index)).
+				Data("hx-trigger", "change").
+				Data("hx-params", field.Path+","+versionField)
+		}
+
+		// Indent the first column to the row's place in the tree
+		if (index == 0) && (depth >= 0) {
+			widget.drawTreeToggle(rowIndex, depth, false, b.SubTree())
+		}
 
 		// Focus the requested column when editing.  An out-of-range focusColumn
 		// simply matches no column, so no field is focused (and nothing panics).
@@ -344,27 +880,80 @@ func (widget Table) drawEditRow(rowSchema *schema.Schema, rowValue any, canEdit
 			field = focusField(field)
 		}
 
-		if err := field.Edit(&f, widget.LookupProvider, rowValue, b.SubTree()); err != nil {
+		if err := field.Edit(&f, widget.LookupProvider, editValue, b.SubTree()); err != nil {
 			return derp.Wrap(err, location, "Rendering field", field)
 		}
+
+		widget.drawFieldError(field.Path, b.SubTree())
+
+		// Show the saved value beneath any field that someone else has changed
+		if widget.conflict {
+			if err := widget.drawConflictValue(&f, field, editValue, rowValue, b.SubTree()); err != nil {
+				return derp.Wrap(err, location, "Rendering saved value", field)
+			}
+		}
+
 		b.Close() // TD
 	}
 
 	// Write actions column
 	b.TD().Class("grid-cell", "grid-editable", "grid-controls")
-	b.Button().Type("submit").Class("text-green").InnerHTML(widget.Icons.Get("save")).Close()
+
+	// The version token lets DoEdit detect changes made after this row was drawn.
+	// Once the user has seen a conflict, saving again replaces the newer values.
+	if rowIndex < len(widget.rowVersions) {
+		b.Empty("input").
+			Type("hidden").
+			Attr("name", versionField).
+			Attr("value", widget.rowVersions[rowIndex]).
+			Close()
+	}
+
+	b.Button().Type("submit").Class("text-green").Attr("aria-label", widget.rowLabel(MessageSaveRow, rowIndex)).InnerHTML(widget.Icons.Get("save")).Close()
 	b.Space()
-	b.Button().Type("button").Data("hx-get", widget.TargetURL).InnerHTML(widget.Icons.Get("cancel")).Close()
+	widget.drawCancelButton(b.SubTree())
+	b.Close() // TD
 	b.Close() // TR
 
+	// Cells that are edited on their own only display the Details.  Otherwise,
+	// the Details are edited along with the rest of the row.
+	if widget.hasDetails() && widget.editCell.IsPresent() {
+		if widget.isExpanded(rowIndex) {
+			if err := widget.drawDetailsView(rowSchema, rowValue, b); err != nil {
+				return derp.Wrap(err, location, "Rendering details", rowIndex)
+			}
+		}
+	} else if widget.hasDetails() {
+		if err := widget.drawDetailsEdit(rowSchema, rowIndex, editValue, rowValue, autoSave, focusColumn, b); err != nil {
+			return derp.Wrap(err, location, "Rendering details", rowIndex)
+		}
+	}
+
 	return nil
 }
 
-func (widget Table) drawViewRow(rowSchema *schema.Schema, rowIndex int, rowValue any, canEdit bool, canDelete bool, b *html.Builder) error {
+func (widget Table) drawViewRow(rowSchema *schema.Schema, rowIndex int, rowValue any, permissions rowPermissions, b *html.Builder) error {
 
 	const location = "table.Widget.drawViewRow"
 
-	b.TR().Class("grid-row", "hover-trigger")
+	row := b.TR().Class("grid-row", "hover-trigger")
+	depth := widget.treeDepth(rowIndex)
+
+	if depth >= 0 {
+		widget.treeRowAttributes(depth, rowIndex, row)
+	}
+
+	// Dropping a dragged row onto this one moves it into this row's position
+	if permissions.Move && widget.DragHandle {
+		row.Data("hx-post", widget.getURL("drop", rowIndex, 0)).
+			Data("hx-trigger", "drop").
+			Data("hx-vals", "js:{move: event.dataTransfer.getData('text/plain')}").
+			Attr("ondragover", "event.preventDefault()")
+	}
+
+	if widget.selecting {
+		widget.drawSelectCell(rowIndex, b.SubTree())
+	}
 
 	width := "width:calc(100% / " + strconv.Itoa(len(widget.Form.Children)) + ")"
 	f := form.New(*rowSchema, *widget.Form)
@@ -373,38 +962,133 @@ func (widget Table) drawViewRow(rowSchema *schema.Schema, rowIndex int, rowValue
 
 		cell := b.TD().Class("grid-cell").Style(width) // nolint:scopeguard
 
-		if canEdit {
-			cell.Data("hx-get", widget.getURL("edit", rowIndex, colIndex)).Data("hx-trigger", "click")
+		if widget.Keyboard {
+			widget.keyboardCell(rowIndex, colIndex, cell)
+		}
+
+		// Nested tables handle their own clicks, so they never open this row's editor
+		if isSubTable(field) {
+
+			cellHTML, err := widget.subTableHTML(field, rowIndex)
+
+			if err != nil {
+				return derp.Wrap(err, location, "Rendering sub-table", field)
+			}
+
+			cell.InnerHTML(widget.treeCellPrefix(colIndex, rowIndex, depth, true) + cellHTML)
+			b.Close() // TD
+			continue
 		}
 
-		if err := field.View(&f, widget.LookupProvider, rowValue, b.SubTree()); err != nil {
+		if permissions.Edit {
+
+			// Clicking a cell edits the whole row, unless cells are edited on their own
+			action := "edit"
+			if widget.CellEdit {
+				if _, editable := widget.editableField(colIndex); editable {
+					action = "cell"
+				}
+			}
+
+			// Pressing Enter on a focused cell is the same as clicking it
+			trigger := "click"
+			if widget.Keyboard {
+				trigger = "click, keyup[key=='Enter']"
+			}
+
+			cell.Data("hx-get", widget.getURL(action, rowIndex, colIndex)).Data("hx-trigger", trigger)
+		}
+
+		cellHTML, err := widget.viewCell(&f, field, rowValue)
+
+		if err != nil {
 			return derp.Wrap(err, location, "Rendering field", field)
 		}
 
+		// Highlight any text that matches the current search
+		if widget.CanSearch {
+			cellHTML = highlightHTML(cellHTML, strings.TrimSpace(widget.view.Search))
+		}
+
+		cell.InnerHTML(widget.treeCellPrefix(colIndex, rowIndex, depth, true) + cellHTML)
 		b.Close() // TD
 	}
 
 	b.TD().Class("grid-cell", "grid-controls")
 
-	if canEdit {
+	if permissions.Move {
+		widget.drawMoveControls(rowIndex, b.SubTree())
+	}
+
+	if widget.hasDetails() {
+		widget.drawDetailsButton(rowIndex, b.SubTree())
+		b.Space()
+	}
+
+	if permissions.Insert {
+		b.Button().
+			Type("button").
+			Attr("aria-label", widget.rowLabel(MessageInsertAboveRow, rowIndex)).
+			Data("hx-get", widget.getURL("insert", rowIndex, 0)).
+			InnerHTML(widget.Icons.Get("insert-above")).
+			Close()
+		b.Space()
 		b.Button().
 			Type("button").
+			Attr("aria-label", widget.rowLabel(MessageInsertBelowRow, rowIndex)).
+			Data("hx-get", widget.getURL("insert", rowIndex+1, 0)).
+			InnerHTML(widget.Icons.Get("insert-below")).
+			Close()
+		b.Space()
+	}
+
+	if permissions.AddChild {
+		b.Button().
+			Type("button").
+			Attr("aria-label", widget.rowLabel(MessageAddChildRow, rowIndex)).
+			Data("hx-get", widget.getURL("add-child", rowIndex, 0)).
+			InnerHTML(widget.Icons.Get("add-child")).
+			Close()
+		b.Space()
+	}
+
+	if permissions.Duplicate {
+		b.Button().
+			Type("button").
+			Attr("aria-label", widget.rowLabel(MessageDuplicateRow, rowIndex)).
+			Data("hx-post", widget.getURL("duplicate", rowIndex, 0)).
+			InnerHTML(widget.Icons.Get("duplicate")).
+			Close()
+		b.Space()
+	}
+
+	if permissions.Edit {
+		b.Button().
+			Type("button").
+			Attr("aria-label", widget.rowLabel(MessageEditRow, rowIndex)).
 			Data("hx-get", widget.getURL("edit", rowIndex, 0)).
 			InnerHTML(widget.Icons.Get("edit")).
 			Close()
 	}
 
-	if canDelete {
+	if permissions.Delete {
 		b.Space()
 		b.Button().
 			Type("button").
+			Attr("aria-label", widget.rowLabel(MessageDeleteRow, rowIndex)).
 			Data("hx-post", widget.getURL("delete", rowIndex, 0)).
-			Data("hx-confirm", "Are you sure you want to delete this row?").
+			Data("hx-confirm", widget.message(MessageConfirmDeleteRow)).
 			InnerHTML(widget.Icons.Get("delete")).Close()
 	}
 
 	b.Close() // TD
 	b.Close() // TR
 
+	if widget.hasDetails() && widget.isExpanded(rowIndex) {
+		if err := widget.drawDetailsView(rowSchema, rowValue, b); err != nil {
+			return derp.Wrap(err, location, "Rendering details", rowIndex)
+		}
+	}
+
 	return nil
 }
@@ -251,14 +251,14 @@ func TestDrawColumnWidth_FloatIsTwoDecimals(t *testing.T) {
 // style at all.
 func TestDrawColumnWidth_Absent(t *testing.T) {
 	result := columnWidthTable(t, nil)
-	assert.Contains(t, result, `<td class="grid-cell"><div>Name</div>`) // header cell, no style attr
+	assert.Contains(t, result, `<th class="grid-cell" scope="col"><div>Name</div>`) // header cell, no style attr
 }
 
 // An empty-string width is treated as "no width": GetString returns "", which the
 // width != "" guard skips, so no width style is emitted.
 func TestDrawColumnWidth_EmptyString(t *testing.T) {
 	result := columnWidthTable(t, "")
-	assert.Contains(t, result, `<td class="grid-cell"><div>Name</div>`) // no style attr
+	assert.Contains(t, result, `<th class="grid-cell" scope="col"><div>Name</div>`) // no style attr
 }
 
 // sharedForm returns a schema + form whose columns carry non-nil Options maps,
@@ -403,6 +403,25 @@ func TestDrawViewString_MinLengthDisablesDelete(t *testing.T) {
 	assert.True(t, table.CanDelete, "render must not mutate the widget's configured permission") // ...without mutating the widget
 }
 
+func TestGetRowPermissions(t *testing.T) {
+
+	table := newTestTable()
+	table.CanDuplicate = true
+	table.CanInsert = true
+	element := schema.Array{MinLength: 1, MaxLength: 3}
+
+	// Between the bounds, every configured permission is offered
+	assert.Equal(t, rowPermissions{Add: true, Edit: true, Delete: true, Move: table.CanMove, Insert: true, Duplicate: true}, table.getRowPermissions(element, 2))
+
+	// At MaxLength, rows cannot be added, inserted, or duplicated
+	full := table.getRowPermissions(element, 3)
+	assert.False(t, full.Add || full.Insert || full.Duplicate)
+	assert.True(t, full.Delete)
+
+	// At MinLength, rows cannot be deleted
+	assert.False(t, table.getRowPermissions(element, 1).Delete)
+}
+
 func TestDrawViewString_Error(t *testing.T) {
 
 	table := newTestTable()
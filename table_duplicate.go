@@ -0,0 +1,87 @@
+package table
+
+import (
+	"strconv"
+
+	"github.com/benpate/derp"
+	"github.com/benpate/rosetta/convert"
+	"github.com/benpate/rosetta/list"
+	"github.com/benpate/rosetta/schema"
+)
+
+/******************************************
+ * Duplicate Methods
+ ******************************************/
+
+// DoDuplicate copies the row at the requested index into a new row immediately
+// after it.  The copy does not include the row's key (if the table has a KeyPath)
+// so that keys remain unique.
+func (widget Table) DoDuplicate(index int) error {
+
+	const location = "table.Widget.DoDuplicate"
+
+	if !widget.CanDuplicate {
+		return derp.BadRequest(location, widget.message(MessageDuplicateNotAllowed), widget.Path)
+	}
+
+	tableData, err := widget.Schema.Get(widget.Object, widget.Path)
+
+	if err != nil {
+		return derp.Wrap(err, location, "Locating table data", widget.Path)
+	}
+
+	length := convert.SliceLength(tableData)
+
+	if (index < 0) || (index >= length) {
+		return derp.BadRequest(location, widget.message(MessageRowNotFound), widget.Path, index, length)
+	}
+
+	if err := widget.checkMaxLength(length); err != nil {
+		return derp.Wrap(err, location, "Cannot add new row", widget.Path)
+	}
+
+	tableElement, err := widget.getTableElement()
+
+	if err != nil {
+		return derp.Wrap(err, location, "Getting table element")
+	}
+
+	// Copy the row, leaving out its key
+	rowValue, err := widget.Schema.Get(widget.Object, list.ByDot(widget.Path, strconv.Itoa(index)).String())
+
+	if err != nil {
+		return derp.Wrap(err, location, "Getting row data", widget.Path, index)
+	}
+
+	// Scalar values are copied as-is
+	duplicate := derefValue(rowValue)
+
+	if !isScalarElement(tableElement.Items) {
+
+		row := cloneRow(rowValue)
+
+		if widget.KeyPath != "" {
+			rowSchema := schema.New(tableElement.Items)
+			rowSchema.Remove(&row, widget.KeyPath)
+		}
+
+		duplicate = row
+	}
+
+	// Append the copy, then move it into place.  The copy's values came from a
+	// row that is already stored, so they are written without validation.
+	path := list.ByDot(widget.Path, strconv.Itoa(length)).String()
+
+	if err := schema.SetProperty(widget.Schema.Element, widget.Object, path, duplicate); err != nil {
+		return derp.Wrap(err, location, "Adding row", path)
+	}
+
+	if err := widget.moveRow(length, index+1); err != nil {
+
+		// Remove the copy, so that a failed duplicate changes nothing
+		_ = widget.Schema.Remove(widget.Object, path)
+		return derp.Wrap(err, location, "Moving row into position", widget.Path, index+1)
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package table
+
+import (
+	"bytes"
+	"html"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/******************************************
+ * DoDuplicate()
+ ******************************************/
+
+func TestDoDuplicate(t *testing.T) {
+	table := newMoveTable().AllowDuplicate()
+	require.NoError(t, table.DoDuplicate(1))
+	assert.Equal(t, []string{"A", "B", "B", "C", "D"}, testNames(table))
+
+	// The copy is independent of the original
+	db := table.Object.(*testDatabase)
+	db.Data[2]["name"] = "X"
+	assert.Equal(t, []string{"A", "B", "X", "C", "D"}, testNames(table))
+}
+
+func TestDoDuplicate_Last(t *testing.T) {
+	table := newMoveTable().AllowDuplicate()
+	require.NoError(t, table.DoDuplicate(3))
+	assert.Equal(t, []string{"A", "B", "C", "D", "D"}, testNames(table))
+}
+
+func TestDoDuplicate_NotAllowed(t *testing.T) {
+	table := newMoveTable()
+	require.Error(t, table.DoDuplicate(1))
+	assert.Equal(t, []string{"A", "B", "C", "D"}, testNames(table))
+}
+
+func TestDoDuplicate_OutOfRange(t *testing.T) {
+	table := newMoveTable().AllowDuplicate()
+	require.Error(t, table.DoDuplicate(-1))
+	require.Error(t, table.DoDuplicate(4))
+	assert.Equal(t, []string{"A", "B", "C", "D"}, testNames(table))
+}
+
+func TestDoDuplicate_MaxLength(t *testing.T) {
+	table := newMoveTable().AllowDuplicate() // testSchema() allows 6 rows
+	require.NoError(t, table.DoDuplicate(0))
+	require.NoError(t, table.DoDuplicate(0))
+	require.Error(t, table.DoDuplicate(0))
+	assert.Equal(t, []string{"A", "A", "A", "B", "C", "D"}, testNames(table))
+}
+
+// Keys are not copied, so that they remain unique
+func TestDoDuplicate_Key(t *testing.T) {
+	table := newKeyTable().AllowDuplicate()
+	db := table.Object.(*testDatabase)
+
+	require.NoError(t, table.Do(mustURL(t, "http://x?duplicate=abc"), nil))
+	require.Equal(t, 4, len(db.Data))
+	assert.Equal(t, "Kyle Reese", db.Data[3]["name"])
+	assert.Equal(t, 30, db.Data[3]["age"])
+	assert.Empty(t, db.Data[3].GetString("taskId"))
+	assert.Equal(t, "abc", db.Data[2]["taskId"])
+}
+
+// A failure at any step leaves the table unchanged, including a failed move
+// after the copy has already been appended
+func TestDoDuplicate_Rollback(t *testing.T) {
+
+	for failAt := 1; failAt < 100; failAt++ {
+
+		table := newMoveTable().AllowDuplicate()
+		faulty := table
+		faulty.Object = &faultyDatabase{testDatabase: table.Object.(*testDatabase), failAt: failAt}
+
+		if err := faulty.DoDuplicate(1); err == nil {
+			assert.Equal(t, []string{"A", "B", "B", "C", "D"}, testNames(table))
+			return
+		}
+
+		assert.Equal(t, []string{"A", "B", "C", "D"}, testNames(table), "failing call: %d", failAt)
+	}
+
+	t.Fatal("DoDuplicate never succeeded")
+}
+
+// faultyDatabase is a testDatabase that cannot find its data on one call to
+// GetPointer, so that tests can make any single read or write fail.
+type faultyDatabase struct {
+	*testDatabase
+	calls  int
+	failAt int
+}
+
+// GetPointer implements the schema.PointerGetter interface.
+func (d *faultyDatabase) GetPointer(name string) (any, bool) {
+
+	d.calls++
+
+	if d.calls == d.failAt {
+		return nil, false
+	}
+
+	return d.testDatabase.GetPointer(name)
+}
+
+/******************************************
+ * Drawing
+ ******************************************/
+
+func TestDraw_Duplicate(t *testing.T) {
+	table := newMoveTable().AllowDuplicate()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Do(mustURL(t, "http://x?duplicate=1"), nil))
+	require.NoError(t, table.Draw(mustURL(t, "http://x?duplicate=1"), &buffer))
+
+	// The copy (not the original) is opened for editing
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `hx-post="http://localhost/table?edit=2&focus=0"`)
+	assert.Less(t, strings.Index(result, ">B<"), strings.Index(result, `<input name="name"`))
+}
+
+func TestDraw_DuplicateControls(t *testing.T) {
+	table := newMoveTable()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+	assert.NotContains(t, buffer.String(), "duplicate") // duplicate controls are optional
+
+	table = table.AllowDuplicate()
+	buffer.Reset()
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+	assert.Contains(t, html.UnescapeString(buffer.String()), `hx-post="http://localhost/table?duplicate=1">duplicate`)
+
+	// Full tables cannot be duplicated into
+	require.NoError(t, table.DoDuplicate(0))
+	require.NoError(t, table.DoDuplicate(0))
+	buffer.Reset()
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+	assert.NotContains(t, buffer.String(), "duplicate")
+}
@@ -0,0 +1,145 @@
+package table
+
+import (
+	"net/url"
+
+	"github.com/benpate/derp"
+	"github.com/benpate/html"
+	"github.com/benpate/rosetta/null"
+	"github.com/benpate/rosetta/schema"
+)
+
+/******************************************
+ * Filter Methods
+ ******************************************/
+
+// ParseFilters returns the column filters requested by a set of query parameters,
+// so that callers can apply the same predicate to their own data.
+func (widget Table) ParseFilters(query url.Values) (FilterState, error) {
+
+	const location = "table.Widget.ParseFilters"
+
+	tableElement, err := widget.getTableElement()
+
+	if err != nil {
+		return FilterState{}, derp.Wrap(err, location, "Getting table element")
+	}
+
+	return ParseFilterState(newRowSchema(tableElement), widget.scalarColumns().Form.Children, query), nil
+}
+
+// filterRows removes rows that do not pass the column filters, keeping the
+// remaining rows in their existing order.  The row being edited is always
+// kept, so that it cannot vanish mid-edit.
+func (widget Table) filterRows(filters FilterState, rows []any, rowOrder []int, editRow null.Int) []int {
+
+	if filters.IsEmpty() {
+		return rowOrder
+	}
+
+	result := make([]int, 0, len(rowOrder))
+
+	for _, rowIndex := range rowOrder {
+		if (editRow.IsPresent() && (editRow.Int() == rowIndex)) || filters.Match(rows[rowIndex]) {
+			result = append(result, rowIndex)
+		}
+	}
+
+	return result
+}
+
+// hasFilters returns TRUE if any column in the table can be filtered
+func (widget Table) hasFilters(rowSchema *schema.Schema) bool {
+
+	for _, column := range widget.Form.Children {
+		if getFilterType(rowSchema, column) != filterTypeNone {
+			return true
+		}
+	}
+
+	return false
+}
+
+// drawFilters writes a second header row containing a filter control for each
+// column.  Changing any control reloads the table with every filter value.
+func (widget Table) drawFilters(rowSchema *schema.Schema, filters FilterState, b *html.Builder) {
+
+	b.TR().
+		Class("grid-filters").
+		Data("hx-get", widget.getURL("filter", 0, 0)).
+		Data("hx-include", "this").
+		Data("hx-trigger", "change")
+
+	if widget.selecting {
+		b.TD().Class("grid-cell", "grid-select").Close()
+	}
+
+	for _, column := range widget.Form.Children {
+
+		b.TD().Class("grid-cell")
+		filter := filters.Get(column.Path)
+
+		switch getFilterType(rowSchema, column) {
+
+		case filterTypeEnum:
+			element, _ := rowSchema.GetStringElement(column.Path)
+			b.Container("select").Attr("name", "filter."+column.Path).Attr("aria-label", column.Label)
+			drawFilterOption("", widget.message(MessageFilterAll), filter.Equal, b)
+			for _, value := range element.Enum {
+				drawFilterOption(value, value, filter.Equal, b)
+			}
+			b.Close() // Select
+
+		case filterTypeBoolean:
+			b.Container("select").Attr("name", "filter."+column.Path).Attr("aria-label", column.Label)
+			drawFilterOption("", widget.message(MessageFilterAll), filter.Equal, b)
+			drawFilterOption("true", widget.message(MessageFilterYes), filter.Equal, b)
+			drawFilterOption("false", widget.message(MessageFilterNo), filter.Equal, b)
+			b.Close() // Select
+
+		case filterTypeNumber:
+			drawFilterInput("number", "min."+column.Path, filter.Min, widget.message(MessageFilterMin), column.Label, b)
+			drawFilterInput("number", "max."+column.Path, filter.Max, widget.message(MessageFilterMax), column.Label, b)
+
+		case filterTypeDate:
+			drawFilterInput("date", "min."+column.Path, filter.Min, widget.message(MessageFilterFrom), column.Label, b)
+			drawFilterInput("date", "max."+column.Path, filter.Max, widget.message(MessageFilterTo), column.Label, b)
+		}
+
+		b.Close() // TD
+	}
+
+	b.TD().Class("grid-cell", "grid-controls").Close()
+	b.Close() // TR
+}
+
+// drawFilterOption writes a single option in a filter dropdown
+func drawFilterOption(value string, label string, selected string, b *html.Builder) {
+
+	option := b.Container("option").Attr("value", value)
+
+	if value == selected {
+		option.Attr("selected", "true")
+	}
+
+	option.InnerText(label)
+	b.Close() // Option
+}
+
+// drawFilterInput writes a single input for one end of a filter range.  The
+// column's label is included in the input's accessible label.
+func drawFilterInput(inputType string, name string, value string, placeholder string, columnLabel string, b *html.Builder) {
+
+	input := b.Empty("input").
+		Type(inputType).
+		Attr("name", name).
+		Attr("value", value).
+		Attr("placeholder", placeholder).
+		Attr("aria-label", columnLabel+" "+placeholder)
+
+	if inputType == "number" {
+		input.Attr("step", "any")
+	}
+
+	b.Close() // Input
+}
@@ -0,0 +1,165 @@
+package table
+
+import (
+	"bytes"
+	"html"
+	"net/url"
+	"testing"
+
+	"github.com/benpate/form"
+	"github.com/benpate/rosetta/mapof"
+	"github.com/benpate/rosetta/schema"
+	"github.com/benpate/rosetta/sliceof"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFilterTable returns a filterable Table with a status (enum) and age (integer) column.
+func newFilterTable() Table {
+
+	s := schema.Schema{
+		Element: schema.Object{
+			Properties: schema.ElementMap{
+				"data": schema.Array{
+					Items: schema.Object{
+						Properties: schema.ElementMap{
+							"name":   schema.String{},
+							"status": schema.String{Enum: []string{"New", "Done"}},
+							"age":    schema.Integer{},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	f := form.Element{
+		Type: "layout-vertical",
+		Children: []form.Element{
+			{Type: "text", Label: "Name", Path: "name"},
+			{Type: "select", Label: "Status", Path: "status"},
+			{Type: "number", Label: "Age", Path: "age"},
+		},
+	}
+
+	db := &testDatabase{
+		Data: sliceof.Object[mapof.Any]{
+			mapof.Any{"name": "John Connor", "status": "New", "age": 20},
+			mapof.Any{"name": "Sarah Connor", "status": "Done", "age": 45},
+			mapof.Any{"name": "Kyle Reese", "status": "Done", "age": 30},
+		},
+	}
+
+	return New(&s, &f, db, "data", testIconProvider{}, "http://localhost/table").AllowFilter()
+}
+
+func TestParseFilters(t *testing.T) {
+
+	table := newFilterTable()
+
+	filters, err := table.ParseFilters(url.Values{"filter.status": {"Done"}, "max.age": {"40"}})
+
+	require.NoError(t, err)
+	assert.True(t, filters.Match(mapof.Any{"status": "Done", "age": 30}))
+	assert.False(t, filters.Match(mapof.Any{"status": "Done", "age": 45}))
+}
+
+func TestParseFilters_Error(t *testing.T) {
+
+	table := newFilterTable()
+	table.Schema = nil
+
+	_, err := table.ParseFilters(url.Values{})
+
+	require.Error(t, err)
+}
+
+func TestDraw_Filtered(t *testing.T) {
+
+	table := newFilterTable()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?filter.status=Done&min.age=40"), &buffer)
+
+	require.NoError(t, err)
+	result := buffer.String()
+	assert.Contains(t, result, "Sarah Connor")
+	assert.NotContains(t, result, "John Connor")
+	assert.NotContains(t, result, "Kyle Reese")
+}
+
+// The filter row shows a control for each filterable column, with the current values selected.
+func TestDraw_FilterControls(t *testing.T) {
+
+	table := newFilterTable()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?filter.status=Done&min.age=40"), &buffer)
+
+	require.NoError(t, err)
+	result := buffer.String()
+	assert.Contains(t, result, "grid-filters")
+	assert.Contains(t, result, `<select name="filter.status" aria-label="Status">`)
+	assert.Contains(t, result, `<option value="Done" selected="true">Done</option>`)
+	assert.Contains(t, result, `name="min.age" value="40"`)
+	assert.Contains(t, result, `name="max.age" value=""`)
+	assert.Contains(t, result, `aria-label="Age Min"`)
+}
+
+// Valid filters are carried into every link, and invalid ones are dropped.
+func TestDraw_FiltersCarriedIntoLinks(t *testing.T) {
+
+	table := newFilterTable()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?filter.status=Done&filter.bogus=1&edit=1"), &buffer)
+
+	require.NoError(t, err)
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, "edit=1&filter.status=Done&focus=0") // form hx-post
+	assert.NotContains(t, result, "bogus")
+	assert.NotContains(t, result, "grid-filters") // filter row is hidden while editing
+}
+
+// The row being edited stays visible, even if it does not pass the filters.
+func TestDraw_FilterKeepsEditRow(t *testing.T) {
+
+	table := newFilterTable()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?filter.status=Done&edit=0"), &buffer)
+
+	require.NoError(t, err)
+	assert.Contains(t, buffer.String(), `value="John Connor"`)
+}
+
+// Filters are ignored unless the table allows filtering.
+func TestDraw_FilterNotAllowed(t *testing.T) {
+
+	table := newFilterTable()
+	table.CanFilter = false
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?filter.status=Done"), &buffer)
+
+	require.NoError(t, err)
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, "John Connor")
+	assert.NotContains(t, result, "grid-filters")
+	assert.NotContains(t, result, "filter.status") // not carried into links
+}
+
+// Tables without any filterable columns do not show an empty filter row.
+func TestDraw_NoFilterableColumns(t *testing.T) {
+
+	table := newTestTable().AllowFilter()
+	table.Form = pointerTo(form.Element{
+		Type:     "layout-vertical",
+		Children: []form.Element{{Type: "text", Label: "Name", Path: "name"}},
+	})
+
+	result, err := table.DrawViewString()
+
+	require.NoError(t, err)
+	assert.NotContains(t, result, "grid-filters")
+}
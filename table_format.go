@@ -0,0 +1,312 @@
+package table
+
+import (
+	stdhtml "html"
+	"maps"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benpate/form"
+	"github.com/benpate/rosetta/convert"
+)
+
+/******************************************
+ * Column Formats
+ ******************************************/
+
+// Column formats, set in the "column-format" option of each column in the Form.
+// The "column-decimals" option sets the number of decimal places for numbers,
+// currencies, and percents, the "column-currency" option sets the currency code
+// (such as "EUR") for currencies, and the "column-layout" option replaces the
+// locale's layout for dates and times (using Go's time layouts).
+const (
+	FormatNumber   = "number"   // 1,234.5
+	FormatCurrency = "currency" // $1,234.50
+	FormatPercent  = "percent"  // 12% (from a fraction, such as 0.12)
+	FormatDate     = "date"     // Jan 2, 2006
+	FormatTime     = "time"     // 3:04 PM
+	FormatDateTime = "datetime" // Jan 2, 2006 3:04 PM
+	FormatRelative = "relative" // 3 days ago
+)
+
+// LocaleFormat defines how numbers and dates are written in a locale
+type LocaleFormat struct {
+	Decimal       string // Separates whole numbers from fractions
+	Group         string // Separates each group of three digits in large numbers
+	CurrencyAfter bool   // If TRUE, then currency symbols follow the number ("1.234,50 €")
+	PercentSpace  bool   // If TRUE, then percent signs are separated from the number ("12 %")
+	DateLayout    string // Go time layout for dates
+	TimeLayout    string // Go time layout for times of day
+}
+
+// DefaultLocaleFormats returns a copy of the formats for each locale that the
+// table supports by default.  Changing the copy does not change the defaults:
+// to support other locales, pass their formats to UseLocaleFormats instead.
+func DefaultLocaleFormats() map[string]LocaleFormat {
+	return maps.Clone(localeFormats)
+}
+
+// localeFormats contains the default formats for each locale that the table supports
+var localeFormats = map[string]LocaleFormat{
+	"en":    {Decimal: ".", Group: ",", DateLayout: "Jan 2, 2006", TimeLayout: "3:04 PM"},
+	"en-GB": {Decimal: ".", Group: ",", DateLayout: "02/01/2006", TimeLayout: "15:04"},
+	"de":    {Decimal: ",", Group: ".", CurrencyAfter: true, PercentSpace: true, DateLayout: "02.01.2006", TimeLayout: "15:04"},
+	"es":    {Decimal: ",", Group: ".", CurrencyAfter: true, PercentSpace: true, DateLayout: "02/01/2006", TimeLayout: "15:04"},
+	"es-MX": {Decimal: ".", Group: ",", DateLayout: "02/01/2006", TimeLayout: "15:04"},
+	"fr":    {Decimal: ",", Group: "\u202f", CurrencyAfter: true, PercentSpace: true, DateLayout: "02/01/2006", TimeLayout: "15:04"},
+	"it":    {Decimal: ",", Group: ".", CurrencyAfter: true, DateLayout: "02/01/2006", TimeLayout: "15:04"},
+	"nl":    {Decimal: ",", Group: ".", DateLayout: "02-01-2006", TimeLayout: "15:04"},
+	"pt":    {Decimal: ",", Group: ".", DateLayout: "02/01/2006", TimeLayout: "15:04"},
+}
+
+// nbsp keeps currency symbols and percent signs on the same line as their numbers
+const nbsp = "\u00a0"
+
+// currencySymbols contains the symbols for common currencies.  Other
+// currencies are displayed with their currency code instead.
+var currencySymbols = map[string]string{
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+	"USD": "$",
+}
+
+// localeFormat returns the LocaleFormat for the table's locale.  Locales are
+// matched exactly first, then by their language alone (so "de-AT" uses "de"),
+// and then fall back to "en".
+func (widget Table) localeFormat() LocaleFormat {
+
+	if format, ok := widget.findLocaleFormat(widget.Locale); ok {
+		return format
+	}
+
+	if language, _, ok := strings.Cut(strings.ReplaceAll(widget.Locale, "_", "-"), "-"); ok {
+		if format, ok := widget.findLocaleFormat(language); ok {
+			return format
+		}
+	}
+
+	if format, ok := widget.findLocaleFormat("en"); ok {
+		return format
+	}
+
+	return localeFormats["en"]
+}
+
+// findLocaleFormat returns the format for a single locale, using the table's
+// LocaleFormats before the defaults
+func (widget Table) findLocaleFormat(locale string) (LocaleFormat, bool) {
+
+	if format, ok := widget.LocaleFormats[locale]; ok {
+		return format, true
+	}
+
+	format, ok := localeFormats[locale]
+	return format, ok
+}
+
+// formatCell returns the HTML for a cell whose column has a "column-format"
+// option, or FALSE if the cell should be drawn by the form instead (because the
+// column has no format, or because its value is empty or cannot be formatted).
+// Only view cells are formatted.  Edit cells always show the raw value, so that
+// it can be saved again without changing it.
+func (widget Table) formatCell(f *form.Form, field form.Element, rowValue any) (string, bool) {
+
+	if field.Options.GetString("column-format") == "" {
+		return "", false
+	}
+
+	value, err := f.Schema.Get(rowValue, field.Path)
+
+	if err != nil {
+		return "", false
+	}
+
+	return widget.formatValue(field, value)
+}
+
+// formatValue returns the HTML for a value in the format of its column, or FALSE
+// if the column has no format or the value is empty or cannot be formatted.
+func (widget Table) formatValue(field form.Element, value any) (string, bool) {
+
+	format := field.Options.GetString("column-format")
+
+	if (format == "") || (value == nil) || (value == "") {
+		return "", false
+	}
+
+	locale := widget.localeFormat()
+
+	switch format {
+
+	case FormatNumber, FormatCurrency, FormatPercent:
+
+		number, ok := convert.FloatOk(value, 0)
+
+		if !ok {
+			return "", false
+		}
+
+		return stdhtml.EscapeString(widget.formatNumber(locale, format, number, field)), true
+
+	case FormatDate, FormatTime, FormatDateTime, FormatRelative:
+
+		date, ok := convert.TimeOk(value, time.Time{})
+
+		if !ok {
+			return "", false
+		}
+
+		text := widget.formatTime(locale, format, date, field.Options.GetString("column-layout"))
+		return `<time datetime="` + date.Format(time.RFC3339) + `">` + stdhtml.EscapeString(text) + `</time>`, true
+	}
+
+	return "", false
+}
+
+// formatNumber writes a number as a plain number, a currency, or a percent
+func (widget Table) formatNumber(locale LocaleFormat, format string, number float64, field form.Element) string {
+
+	// Numbers use as many decimal places as they need, unless the column says otherwise
+	decimals := -1
+
+	switch format {
+	case FormatCurrency:
+		decimals = 2
+	case FormatPercent:
+		decimals = 0
+		number = number * 100
+	}
+
+	if value, ok := field.Options["column-decimals"]; ok {
+		decimals = convert.Int(value)
+	}
+
+	digits := strconv.FormatFloat(math.Abs(number), 'f', decimals, 64)
+	negative := (number < 0) && (strings.Trim(digits, "0.") != "") // "-0" is just "0"
+	result := groupDigits(locale, digits)
+
+	switch format {
+
+	case FormatCurrency:
+		code := strings.ToUpper(field.Options.GetString("column-currency"))
+		symbol, ok := currencySymbols[code]
+
+		if !ok {
+			symbol = code
+		}
+
+		switch {
+		case symbol == "":
+			// No currency, so just the number
+		case locale.CurrencyAfter:
+			result = result + nbsp + symbol
+		case ok:
+			result = symbol + result
+		default:
+			result = symbol + nbsp + result
+		}
+
+	case FormatPercent:
+		if locale.PercentSpace {
+			result = result + nbsp + "%"
+		} else {
+			result = result + "%"
+		}
+	}
+
+	if negative {
+		result = "-" + result
+	}
+
+	return result
+}
+
+// groupDigits rewrites the digits of a positive number (such as "1234.5") with
+// the locale's decimal and group separators
+func groupDigits(locale LocaleFormat, digits string) string {
+
+	whole, fraction, _ := strings.Cut(digits, ".")
+
+	var result strings.Builder
+
+	for index, digit := range whole {
+		if (index > 0) && ((len(whole)-index)%3 == 0) {
+			result.WriteString(locale.Group)
+		}
+		result.WriteRune(digit)
+	}
+
+	if fraction != "" {
+		result.WriteString(locale.Decimal)
+		result.WriteString(fraction)
+	}
+
+	return result.String()
+}
+
+// formatTime writes a date, a time, both, or the time relative to now
+func (widget Table) formatTime(locale LocaleFormat, format string, date time.Time, layout string) string {
+
+	if format == FormatRelative {
+		return widget.relativeTime(time.Since(date))
+	}
+
+	if layout == "" {
+		switch format {
+		case FormatDate:
+			layout = locale.DateLayout
+		case FormatTime:
+			layout = locale.TimeLayout
+		default:
+			layout = locale.DateLayout + " " + locale.TimeLayout
+		}
+	}
+
+	return date.Format(layout)
+}
+
+// relativeTime describes how long ago (or how far in the future) something happens,
+// in the largest whole unit
+func (widget Table) relativeTime(elapsed time.Duration) string {
+
+	future := elapsed < 0
+	elapsed = elapsed.Abs()
+
+	const day = 24 * time.Hour
+
+	var amount string
+
+	switch {
+	case elapsed < time.Minute:
+		return widget.message(MessageJustNow)
+	case elapsed < time.Hour:
+		amount = widget.relativeAmount(int(elapsed/time.Minute), MessageMinute, MessageMinutes)
+	case elapsed < day:
+		amount = widget.relativeAmount(int(elapsed/time.Hour), MessageHour, MessageHours)
+	case elapsed < 30*day:
+		amount = widget.relativeAmount(int(elapsed/day), MessageDay, MessageDays)
+	case elapsed < 365*day:
+		amount = widget.relativeAmount(int(elapsed/(30*day)), MessageMonth, MessageMonths)
+	default:
+		amount = widget.relativeAmount(int(elapsed/(365*day)), MessageYear, MessageYears)
+	}
+
+	if future {
+		return widget.message(MessageTimeFromNow, amount)
+	}
+
+	return widget.message(MessageTimeAgo, amount)
+}
+
+// relativeAmount writes a number of units, such as "1 day" or "3 days"
+func (widget Table) relativeAmount(count int, singularKey string, pluralKey string) string {
+
+	if count == 1 {
+		return widget.message(singularKey)
+	}
+
+	return widget.message(pluralKey, count)
+}
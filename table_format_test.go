@@ -0,0 +1,217 @@
+package table
+
+import (
+	"bytes"
+	"html"
+	"testing"
+	"time"
+
+	"github.com/benpate/form"
+	"github.com/benpate/rosetta/mapof"
+	"github.com/benpate/rosetta/schema"
+	"github.com/benpate/rosetta/sliceof"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFormatTable returns a Table with a formatted price, share, and due date column.
+func newFormatTable() Table {
+
+	s := schema.Schema{
+		Element: schema.Object{
+			Properties: schema.ElementMap{
+				"data": schema.Array{
+					Items: schema.Object{
+						Properties: schema.ElementMap{
+							"name":  schema.String{},
+							"price": schema.Number{},
+							"share": schema.Number{},
+							"due":   schema.String{Format: "date"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	f := form.Element{
+		Type: "layout-vertical",
+		Children: []form.Element{
+			{Type: "text", Label: "Name", Path: "name"},
+			{Type: "text", Label: "Price", Path: "price", Options: mapof.Any{"column-format": FormatCurrency, "column-currency": "EUR"}},
+			{Type: "text", Label: "Share", Path: "share", Options: mapof.Any{"column-format": FormatPercent, "column-decimals": 1}},
+			{Type: "text", Label: "Due", Path: "due", Options: mapof.Any{"column-format": FormatDate}},
+		},
+	}
+
+	db := &testDatabase{
+		Data: sliceof.Object[mapof.Any]{
+			mapof.Any{"name": "Rent", "price": 1234.5, "share": 0.125, "due": "2026-10-02"},
+			mapof.Any{"name": "Unknown", "due": "someday"},
+		},
+	}
+
+	return New(&s, &f, db, "data", testIconProvider{}, "http://localhost/table")
+}
+
+func TestDraw_Formatted(t *testing.T) {
+
+	table := newFormatTable()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, ">€1,234.50</td>")
+	assert.Contains(t, result, ">12.5%</td>")
+	assert.Contains(t, result, `<time datetime="2026-10-02T00:00:00Z">Oct 2, 2026</time>`)
+	assert.Contains(t, result, ">someday</td>") // values that cannot be formatted are drawn as-is
+}
+
+func TestDraw_FormattedLocale(t *testing.T) {
+
+	table := newFormatTable().UseLocale("de-DE")
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, ">1.234,50\u00a0€</td>")
+	assert.Contains(t, result, ">12,5\u00a0%</td>")
+	assert.Contains(t, result, ">02.10.2026</time>")
+}
+
+// Edit rows show the raw values, so that saving them does not change them
+func TestDraw_FormattedEdit(t *testing.T) {
+
+	table := newFormatTable().UseLocale("de")
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x?edit=0"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `name="due" value="2026-10-02"`)
+	assert.NotContains(t, result, "1.234")
+	assert.NotContains(t, result, "€")
+	assert.NotContains(t, result, "<time")
+}
+
+func TestFormatNumber(t *testing.T) {
+
+	table := newTestTable()
+
+	tests := []struct {
+		locale   string
+		options  mapof.Any
+		number   float64
+		expected string
+	}{
+		{"", mapof.Any{}, 1234567.25, "1,234,567.25"},
+		{"", mapof.Any{}, 12, "12"},
+		{"", mapof.Any{"column-decimals": 2}, 12, "12.00"},
+		{"", mapof.Any{"column-decimals": 0}, -999.5, "-1,000"},
+		{"", mapof.Any{"column-decimals": 0}, -0.1, "0"},
+		{"de", mapof.Any{}, 1234567.25, "1.234.567,25"},
+		{"fr", mapof.Any{}, 1234567.25, "1\u202f234\u202f567,25"},
+		{"xx-YY", mapof.Any{}, 1234.5, "1,234.5"}, // unknown locales use English
+	}
+
+	for _, test := range tests {
+		field := form.Element{Options: test.options}
+		assert.Equal(t, test.expected, table.UseLocale(test.locale).formatNumber(table.UseLocale(test.locale).localeFormat(), FormatNumber, test.number, field))
+	}
+}
+
+func TestFormatNumber_Currency(t *testing.T) {
+
+	table := newTestTable()
+
+	tests := []struct {
+		locale   string
+		currency string
+		number   float64
+		expected string
+	}{
+		{"en", "USD", 1234.5, "$1,234.50"},
+		{"en", "usd", -1234.5, "-$1,234.50"},
+		{"en", "CHF", 1234.5, "CHF\u00a01,234.50"},
+		{"en", "", 1234.5, "1,234.50"},
+		{"de", "EUR", 1234.5, "1.234,50\u00a0€"},
+		{"es-MX", "USD", 1234.5, "$1,234.50"},
+		{"es-ES", "EUR", 1234.5, "1.234,50\u00a0€"},
+	}
+
+	for _, test := range tests {
+		widget := table.UseLocale(test.locale)
+		field := form.Element{Options: mapof.Any{"column-currency": test.currency}}
+		assert.Equal(t, test.expected, widget.formatNumber(widget.localeFormat(), FormatCurrency, test.number, field))
+	}
+}
+
+// Formats passed to the table are used before the defaults, without changing them
+func TestUseLocaleFormats(t *testing.T) {
+
+	swiss := LocaleFormat{Decimal: ".", Group: "'", DateLayout: "02.01.2006", TimeLayout: "15:04"}
+	table := newTestTable().UseLocaleFormats(map[string]LocaleFormat{"de-CH": swiss, "en": swiss})
+
+	assert.Equal(t, swiss, table.UseLocale("de-CH").localeFormat())
+	assert.Equal(t, swiss, table.UseLocale("xx").localeFormat())
+	assert.Equal(t, ",", table.UseLocale("de-AT").localeFormat().Decimal) // other locales use the defaults
+	assert.Equal(t, ",", newTestTable().localeFormat().Group)
+
+	defaults := DefaultLocaleFormats()
+	defaults["en"] = swiss
+	assert.Equal(t, ",", DefaultLocaleFormats()["en"].Group)
+}
+
+func TestFormatTime(t *testing.T) {
+
+	table := newTestTable()
+	date := time.Date(2026, time.October, 2, 14, 30, 0, 0, time.UTC)
+	en := table.localeFormat()
+	de := table.UseLocale("de").localeFormat()
+
+	assert.Equal(t, "Oct 2, 2026", table.formatTime(en, FormatDate, date, ""))
+	assert.Equal(t, "2:30 PM", table.formatTime(en, FormatTime, date, ""))
+	assert.Equal(t, "Oct 2, 2026 2:30 PM", table.formatTime(en, FormatDateTime, date, ""))
+	assert.Equal(t, "02.10.2026 14:30", table.formatTime(de, FormatDateTime, date, ""))
+	assert.Equal(t, "2026-10-02", table.formatTime(de, FormatDate, date, "2006-01-02")) // layouts replace the locale's
+}
+
+func TestRelativeTime(t *testing.T) {
+
+	table := newTestTable()
+
+	tests := map[time.Duration]string{
+		10 * time.Second:      "just now",
+		-10 * time.Second:     "just now",
+		time.Minute:           "1 minute ago",
+		45 * time.Minute:      "45 minutes ago",
+		-3 * time.Hour:        "in 3 hours",
+		26 * time.Hour:        "1 day ago",
+		-72 * time.Hour:       "in 3 days",
+		60 * 24 * time.Hour:   "2 months ago",
+		800 * 24 * time.Hour:  "2 years ago",
+		-400 * 24 * time.Hour: "in 1 year",
+	}
+
+	for elapsed, expected := range tests {
+		assert.Equal(t, expected, table.relativeTime(elapsed), elapsed.String())
+	}
+
+	// Relative times are translated, too
+	german := table.UseTranslator(Messages{MessageTimeAgo: "vor %s", MessageDays: "%d Tagen"})
+	assert.Equal(t, "vor 3 Tagen", german.relativeTime(72*time.Hour))
+}
+
+func TestDraw_FormattedRelative(t *testing.T) {
+
+	table := newFormatTable()
+	table.Form.Children[3].Options = mapof.Any{"column-format": FormatRelative}
+	table.Object.(*testDatabase).Data[0]["due"] = time.Now().Add(-50 * time.Hour).Format(time.RFC3339)
+
+	result, err := table.DrawViewString()
+
+	require.NoError(t, err)
+	assert.Contains(t, result, ">2 days ago</time>")
+}
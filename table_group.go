@@ -0,0 +1,249 @@
+package table
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/benpate/form"
+	"github.com/benpate/html"
+	"github.com/benpate/rosetta/convert"
+	"github.com/benpate/rosetta/null"
+	"github.com/benpate/rosetta/schema"
+)
+
+/******************************************
+ * Row Groups
+ ******************************************/
+
+// rowGroup is a set of rows that share the same value in the GroupPath column
+type rowGroup struct {
+	Key       string // Value that every row in the group shares, as a string
+	Label     string // Text displayed in the group's header
+	Rows      []int  // Every row in the group (by index) in display order
+	Collapsed bool   // If TRUE, then the group's rows are hidden
+}
+
+// isGrouped returns TRUE if rows are grouped by a column in the row schema
+func (widget Table) isGrouped(rowSchema *schema.Schema) bool {
+
+	if widget.GroupPath == "" {
+		return false
+	}
+
+	_, ok := rowSchema.GetElement(widget.GroupPath)
+	return ok
+}
+
+// groupRows gathers the displayed rows into groups, ordering the groups by their
+// value (using the same rules as sorting) while keeping the current order of the
+// rows within each group.  It returns the groups, and the group of each row (by
+// index).  The group that contains the edit row is never collapsed, so that the
+// row cannot vanish mid-edit.
+func (widget Table) groupRows(rowSchema *schema.Schema, rows []any, rowOrder []int, editRow null.Int) ([]rowGroup, []int) {
+
+	element, _ := rowSchema.GetElement(widget.GroupPath)
+	f := form.New(*rowSchema, *widget.Form)
+	field, hasField := widget.groupField()
+
+	groups := make([]rowGroup, 0)
+	values := make([]any, 0)
+	rowGroups := make([]int, len(rows))
+	positions := make(map[string]int)
+
+	for _, rowIndex := range rowOrder {
+
+		value, _ := rowSchema.Get(rows[rowIndex], widget.GroupPath)
+		key := convert.String(value)
+		position, exists := positions[key]
+
+		if !exists {
+			position = len(groups)
+			positions[key] = position
+			groups = append(groups, rowGroup{
+				Key:       key,
+				Label:     widget.groupLabel(&f, field, hasField, key, rows[rowIndex]),
+				Collapsed: slices.Contains(widget.view.Collapsed, key),
+			})
+			values = append(values, value)
+		}
+
+		groups[position].Rows = append(groups[position].Rows, rowIndex)
+
+		if editRow.IsPresent() && (editRow.Int() == rowIndex) {
+			groups[position].Collapsed = false
+		}
+	}
+
+	// Put the groups in order, and update each row's group to match
+	order := make([]int, len(groups))
+	for index := range order {
+		order[index] = index
+	}
+
+	slices.SortStableFunc(order, func(a int, b int) int {
+		return compareValues(element, values[a], values[b])
+	})
+
+	result := make([]rowGroup, len(groups))
+	for position, index := range order {
+		result[position] = groups[index]
+		for _, rowIndex := range groups[index].Rows {
+			rowGroups[rowIndex] = position
+		}
+	}
+
+	return result, rowGroups
+}
+
+// groupedOrder returns every row in the groups, in display order
+func groupedOrder(groups []rowGroup) []int {
+
+	result := make([]int, 0)
+
+	for _, group := range groups {
+		result = append(result, group.Rows...)
+	}
+
+	return result
+}
+
+// expandedOrder returns the rows in the groups that are not collapsed, in display order
+func expandedOrder(groups []rowGroup) []int {
+
+	result := make([]int, 0)
+
+	for _, group := range groups {
+		if !group.Collapsed {
+			result = append(result, group.Rows...)
+		}
+	}
+
+	return result
+}
+
+// groupField returns the column that rows are grouped by, or FALSE if the
+// GroupPath is not displayed as a column.
+func (widget Table) groupField() (form.Element, bool) {
+
+	for _, field := range widget.Form.Children {
+		if field.Path == widget.GroupPath {
+			return field, true
+		}
+	}
+
+	return form.Element{}, false
+}
+
+// groupLabel returns the text for a group's header.  When the group column is
+// displayed, the header uses the same text as the column's cells (so select
+// fields show the labels from the LookupProvider, not their raw codes).
+func (widget Table) groupLabel(f *form.Form, field form.Element, hasField bool, key string, rowValue any) string {
+
+	label := key
+
+	if hasField {
+		if cellHTML, err := widget.viewCell(f, field, rowValue); err == nil {
+			label = htmlText(cellHTML)
+		}
+	}
+
+	if label == "" {
+		return widget.message(MessageGroupEmpty)
+	}
+
+	return label
+}
+
+// drawGroupStart writes the group header that comes before a row, if the row is
+// the first one from its group on this page.  When the group begins on this page,
+// the collapsed groups that come before it are written first.
+func (widget Table) drawGroupStart(rowSchema *schema.Schema, rows []any, rowIndex int, previousRow int, b *html.Builder) {
+
+	group := widget.rowGroups[rowIndex]
+
+	if (previousRow >= 0) && (widget.rowGroups[previousRow] == group) {
+		return
+	}
+
+	if widget.groups[group].Rows[0] == rowIndex {
+
+		first := group
+		for (first > 0) && widget.groups[first-1].Collapsed {
+			first--
+		}
+
+		for index := first; index < group; index++ {
+			widget.drawGroup(rowSchema, rows, index, b)
+		}
+	}
+
+	widget.drawGroupHeader(group, b)
+}
+
+// drawGroupEnd writes the group's subtotals after its last row
+func (widget Table) drawGroupEnd(rowSchema *schema.Schema, rows []any, rowIndex int, b *html.Builder) {
+
+	group := widget.groups[widget.rowGroups[rowIndex]]
+
+	if widget.GroupTotals && (group.Rows[len(group.Rows)-1] == rowIndex) {
+		widget.drawAggregates(rowSchema, rows, group.Rows, "grid-subtotals", b.SubTree())
+	}
+}
+
+// drawGroupsAfter writes the collapsed groups at the end of the table, after the
+// last group that is expanded
+func (widget Table) drawGroupsAfter(rowSchema *schema.Schema, rows []any, b *html.Builder) {
+
+	first := len(widget.groups)
+	for (first > 0) && widget.groups[first-1].Collapsed {
+		first--
+	}
+
+	for index := first; index < len(widget.groups); index++ {
+		widget.drawGroup(rowSchema, rows, index, b)
+	}
+}
+
+// drawGroup writes the header (and subtotals) of a collapsed group
+func (widget Table) drawGroup(rowSchema *schema.Schema, rows []any, group int, b *html.Builder) {
+
+	widget.drawGroupHeader(group, b)
+
+	if widget.GroupTotals {
+		widget.drawAggregates(rowSchema, rows, widget.groups[group].Rows, "grid-subtotals", b.SubTree())
+	}
+}
+
+// drawGroupHeader writes the full-width row that introduces a group, with a
+// button that collapses or expands it
+func (widget Table) drawGroupHeader(group int, b *html.Builder) {
+
+	header := widget.groups[group]
+
+	action, icon, labelKey, expanded := "collapse", "collapse", MessageCollapseGroup, "true"
+	if header.Collapsed {
+		action, icon, labelKey, expanded = "expand", "expand", MessageExpandGroup, "false"
+	}
+
+	b.TR().Class("grid-group")
+	b.Container("th").
+		Class("grid-cell").
+		Attr("scope", "rowgroup").
+		Attr("colspan", strconv.Itoa(widget.columnCount()))
+
+	b.Button().
+		Type("button").
+		Class("link").
+		Attr("aria-label", widget.message(labelKey, header.Label)).
+		Attr("aria-expanded", expanded).
+		Data("hx-get", widget.getURL(action, group, 0)).
+		InnerHTML(widget.Icons.Get(icon)).
+		Close()
+
+	b.Space()
+	b.Span().InnerText(header.Label).Close()
+	b.Space()
+	b.Span().Class("grid-group-count").InnerText(widget.message(MessageGroupCount, len(header.Rows))).Close()
+	b.Close() // TH
+	b.Close() // TR
+}
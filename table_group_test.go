@@ -0,0 +1,151 @@
+package table
+
+import (
+	"bytes"
+	"html"
+	"strings"
+	"testing"
+
+	"github.com/benpate/rosetta/mapof"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newGroupTable returns a table grouped by status, where grouping changes the order of the rows.
+func newGroupTable() Table {
+
+	table := newFilterTable().UseGroupPath("status")
+	table.Form.Children[2].Options = mapof.Any{"column-aggregate": AggregateSum}
+
+	db := table.Object.(*testDatabase)
+	db.Data = append(db.Data, mapof.Any{"name": "Marcus Wright", "status": "New", "age": 35})
+
+	return table
+}
+
+// drawGroupTable draws a grouped table and returns the unescaped HTML
+func drawGroupTable(t *testing.T, table Table, params string) string {
+	var buffer bytes.Buffer
+	require.NoError(t, table.Draw(mustURL(t, params), &buffer))
+	return html.UnescapeString(buffer.String())
+}
+
+// assertOrder checks that each value appears after the one before it
+func assertOrder(t *testing.T, result string, values ...string) {
+	position := 0
+	for _, value := range values {
+		index := strings.Index(result[position:], value)
+		require.GreaterOrEqual(t, index, 0, value)
+		position += index + len(value)
+	}
+}
+
+func TestDraw_Grouped(t *testing.T) {
+
+	result := drawGroupTable(t, newGroupTable(), "http://x")
+
+	// Groups are listed in the schema's enum order, and rows keep their order within each group
+	assertOrder(t, result, "grid-group", "New", "(2)", "John Connor", "Marcus Wright", "grid-group", "Done", "(2)", "Sarah Connor", "Kyle Reese")
+
+	assert.Contains(t, result, `<tr class="grid-group"><th class="grid-cell" scope="rowgroup" colspan="4"><button type="button" class="link" aria-label="collapse New" aria-expanded="true" hx-get="http://localhost/table?collapsed=New">collapse</button> <span>New</span> <span class="grid-group-count">(2)</span></th></tr>`)
+
+	// Links still address the underlying rows, but labels number rows as they are displayed
+	assert.Contains(t, result, `hx-get="http://localhost/table?edit=3&focus=0"`)
+	assertOrder(t, result, "Marcus Wright", `aria-label="delete row 2"`, "Sarah Connor")
+}
+
+func TestDraw_GroupCollapsed(t *testing.T) {
+
+	result := drawGroupTable(t, newGroupTable(), "http://x?collapsed=Done")
+
+	assert.Contains(t, result, `aria-label="expand Done" aria-expanded="false" hx-get="http://localhost/table">expand</button>`)
+	assert.NotContains(t, result, "Sarah Connor")
+	assert.NotContains(t, result, "Kyle Reese")
+
+	// Collapsed groups are carried into every link
+	assert.Contains(t, result, `hx-get="http://localhost/table?collapsed=Done&edit=3&focus=0"`)
+	assert.Contains(t, result, `hx-get="http://localhost/table?collapsed=Done&collapsed=New"`)
+}
+
+// The group with the edit row stays open
+func TestDraw_GroupCollapsedEdit(t *testing.T) {
+
+	result := drawGroupTable(t, newGroupTable(), "http://x?collapsed=Done&edit=1")
+
+	assert.Contains(t, result, `value="Sarah Connor"`)
+	assert.Contains(t, result, `aria-label="collapse Done"`)
+}
+
+func TestDraw_GroupTotals(t *testing.T) {
+
+	result := drawGroupTable(t, newGroupTable().UseGroupTotals(), "http://x?collapsed=Done")
+
+	// Collapsed groups still show their subtotals, and are still included in the total
+	assertOrder(t, result,
+		"collapse New", "Marcus Wright", `<tr class="grid-subtotals">`, ">55<",
+		"expand Done", `<tr class="grid-subtotals">`, ">75<",
+		`<tr class="grid-aggregates">`, ">130<")
+}
+
+// Collapsed groups keep their place between the pages of a table
+func TestDraw_GroupCollapsedPages(t *testing.T) {
+
+	table := newGroupTable().UsePageSize(1)
+
+	result := drawGroupTable(t, table, "http://x?collapsed=New")
+	assertOrder(t, result, "expand New", "collapse Done", "Sarah Connor")
+
+	result = drawGroupTable(t, table, "http://x?collapsed=New&page=2")
+	assertOrder(t, result, "collapse Done", "Kyle Reese")
+	assert.NotContains(t, result, "expand New")
+
+	result = drawGroupTable(t, table, "http://x?collapsed=Done")
+	assertOrder(t, result, "collapse New", "John Connor")
+	assert.NotContains(t, result, "expand Done")
+
+	result = drawGroupTable(t, table, "http://x?collapsed=Done&page=2")
+	assertOrder(t, result, "collapse New", "Marcus Wright", "expand Done")
+
+	// When every group is collapsed, every header is still displayed
+	result = drawGroupTable(t, table, "http://x?collapsed=Done&collapsed=New")
+	assertOrder(t, result, "expand New", "expand Done")
+}
+
+// Rows are displayed in groups, so they cannot be moved or inserted
+func TestDraw_GroupedMove(t *testing.T) {
+
+	result := drawGroupTable(t, newGroupTable().AllowMove().AllowInsert(), "http://x")
+
+	assert.NotContains(t, result, "move-up")
+	assert.NotContains(t, result, "insert-above")
+}
+
+func TestDraw_GroupEmpty(t *testing.T) {
+
+	table := newGroupTable()
+	table.Object.(*testDatabase).Data[0]["status"] = ""
+
+	result := drawGroupTable(t, table, "http://x")
+
+	assertOrder(t, result, "Marcus Wright", "Kyle Reese", "(none)", "John Connor") // empty values sort last in an enum
+}
+
+// Grouping by a column that does not exist is ignored
+func TestDraw_GroupMissing(t *testing.T) {
+
+	result := drawGroupTable(t, newGroupTable().UseGroupPath("missing"), "http://x")
+
+	assert.NotContains(t, result, "grid-group")
+	assertOrder(t, result, "John Connor", "Sarah Connor", "Kyle Reese", "Marcus Wright")
+}
+
+func TestUseGroupPath(t *testing.T) {
+	table := newTestTable()
+
+	result := table.UseGroupPath("status").UseGroupTotals()
+
+	assert.Equal(t, "status", result.GroupPath)
+	assert.True(t, result.GroupTotals)
+	assert.Empty(t, table.GroupPath) // the original is left unchanged
+	assert.False(t, table.GroupTotals)
+}
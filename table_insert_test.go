@@ -0,0 +1,148 @@
+package table
+
+import (
+	"bytes"
+	"html"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newInsertTable returns a table of four rows that allows inserting new rows
+func newInsertTable() Table {
+	return newMoveTable().AllowInsert()
+}
+
+/******************************************
+ * DoInsert()
+ ******************************************/
+
+func TestDoInsert_Middle(t *testing.T) {
+	table := newInsertTable()
+	require.NoError(t, table.DoInsert(map[string]any{"name": "X", "age": 9}, 1))
+	assert.Equal(t, []string{"A", "X", "B", "C", "D"}, testNames(table))
+}
+
+func TestDoInsert_First(t *testing.T) {
+	table := newInsertTable()
+	require.NoError(t, table.DoInsert(map[string]any{"name": "X", "age": 9}, 0))
+	assert.Equal(t, []string{"X", "A", "B", "C", "D"}, testNames(table))
+}
+
+func TestDoInsert_End(t *testing.T) {
+	table := newInsertTable()
+	require.NoError(t, table.DoInsert(map[string]any{"name": "X", "age": 9}, 4))
+	assert.Equal(t, []string{"A", "B", "C", "D", "X"}, testNames(table))
+}
+
+func TestDoInsert_OutOfRange(t *testing.T) {
+	table := newInsertTable()
+	require.Error(t, table.DoInsert(map[string]any{"name": "X", "age": 9}, -1))
+	require.Error(t, table.DoInsert(map[string]any{"name": "X", "age": 9}, 5))
+	assert.Equal(t, []string{"A", "B", "C", "D"}, testNames(table))
+}
+
+func TestDoInsert_NotAllowed(t *testing.T) {
+	table := newInsertTable()
+	table.CanAdd = false
+	require.Error(t, table.DoInsert(map[string]any{"name": "X", "age": 9}, 1))
+	assert.Equal(t, []string{"A", "B", "C", "D"}, testNames(table))
+}
+
+// Inserting needs its own permission, and only works while rows are in their stored order
+func TestDo_InsertNotAllowed(t *testing.T) {
+	require.Error(t, newMoveTable().DoInsert(map[string]any{"name": "X", "age": 9}, 1))
+	require.Error(t, newMoveTable().Do(mustURL(t, "http://x?insert=1"), map[string]any{"name": "X", "age": 9}))
+
+	table := newInsertTable().AllowSort()
+	require.Error(t, table.Do(mustURL(t, "http://x?insert=1&sort=name&dir=desc"), map[string]any{"name": "X", "age": 9}))
+	assert.Equal(t, []string{"A", "B", "C", "D"}, testNames(table))
+}
+
+func TestDoInsert_MaxLength(t *testing.T) {
+	table := newInsertTable() // testSchema() allows 6 rows
+	require.NoError(t, table.DoInsert(map[string]any{"name": "X", "age": 9}, 1))
+	require.NoError(t, table.DoInsert(map[string]any{"name": "Y", "age": 9}, 1))
+	require.Error(t, table.DoInsert(map[string]any{"name": "Z", "age": 9}, 1))
+	assert.Equal(t, []string{"A", "Y", "X", "B", "C", "D"}, testNames(table))
+}
+
+// Adding to the end honors MaxLength in the same way
+func TestDoAdd_MaxLength(t *testing.T) {
+	table := newInsertTable()
+	require.NoError(t, table.DoAdd(map[string]any{"name": "X", "age": 9}))
+	require.NoError(t, table.DoAdd(map[string]any{"name": "Y", "age": 9}))
+	require.Error(t, table.DoAdd(map[string]any{"name": "Z", "age": 9}))
+	assert.Equal(t, 6, len(testNames(table)))
+}
+
+func TestDo_Insert(t *testing.T) {
+	table := newInsertTable()
+	require.NoError(t, table.Do(mustURL(t, "http://x?insert=2"), map[string]any{"name": "X", "age": 9}))
+	assert.Equal(t, []string{"A", "B", "X", "C", "D"}, testNames(table))
+}
+
+func TestDo_InsertNotNumeric(t *testing.T) {
+	table := newInsertTable()
+	require.NoError(t, table.Do(mustURL(t, "http://x?insert=abc"), map[string]any{"name": "X", "age": 9}))
+	assert.Equal(t, []string{"A", "B", "C", "D"}, testNames(table))
+}
+
+/******************************************
+ * Drawing
+ ******************************************/
+
+func TestDraw_Insert(t *testing.T) {
+	table := newInsertTable()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x?insert=2"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `hx-post="http://localhost/table?insert=2"`)
+
+	// The new row appears between rows "B" and "C"
+	newRow := strings.Index(result, `<input name="name"`)
+	assert.Less(t, strings.Index(result, ">B<"), newRow)
+	assert.Greater(t, strings.Index(result, ">C<"), newRow)
+}
+
+// Inserting after the last row is the same as adding a row
+func TestDraw_InsertAtEnd(t *testing.T) {
+	table := newInsertTable()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x?insert=4"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `hx-post="http://localhost/table?add=true"`)
+	assert.Less(t, strings.Index(result, ">D<"), strings.Index(result, `<input name="name"`))
+}
+
+// Requests to insert rows where they cannot be inserted are ignored
+func TestDraw_InsertNotAllowed(t *testing.T) {
+	result := drawGroupTable(t, newMoveTable(), "http://x?insert=2")
+	assert.NotContains(t, result, `<input name="name"`)
+
+	result = drawGroupTable(t, newInsertTable().AllowSort(), "http://x?insert=2&sort=name")
+	assert.NotContains(t, result, `<input name="name"`)
+}
+
+func TestDraw_InsertControls(t *testing.T) {
+	table := newMoveTable()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+	assert.NotContains(t, buffer.String(), "insert-above") // insert controls are optional
+
+	table = table.AllowInsert()
+	buffer.Reset()
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `hx-get="http://localhost/table?insert=1">insert-above`)
+	assert.Contains(t, result, `hx-get="http://localhost/table?insert=2">insert-below`)
+}
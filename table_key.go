@@ -0,0 +1,124 @@
+package table
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/benpate/derp"
+	"github.com/benpate/rosetta/convert"
+	"github.com/benpate/rosetta/list"
+	"github.com/benpate/rosetta/schema"
+)
+
+/******************************************
+ * Row Identity Methods
+ ******************************************/
+
+// lookupRow converts a row identifier from the query string into the row's
+// current index.  When the table has a KeyPath, the identifier is the row's key
+// (or "#" and the index of a row without a key), and a key that no longer exists
+// returns a NotFound error.  Otherwise, the
+// identifier is the row's index, and non-numeric values are not OK.
+func (widget Table) lookupRow(rowID string) (int, bool, error) {
+
+	const location = "table.Widget.lookupRow"
+
+	if widget.KeyPath == "" {
+		index, err := strconv.Atoi(rowID)
+		return index, (err == nil), nil
+	}
+
+	index, err := widget.findKey(rowID)
+
+	if err != nil {
+
+		// Rows that do not have a key yet (such as duplicates) are addressed by
+		// index, but rows that have a key can only be addressed by their key
+		if indexString, ok := strings.CutPrefix(rowID, "#"); ok && derp.IsNotFound(err) {
+			if index, err := strconv.Atoi(indexString); (err == nil) && widget.hasEmptyKey(index) {
+				return index, true, nil
+			}
+		}
+
+		return -1, false, derp.Wrap(err, location, "Finding row by key", widget.Path, rowID)
+	}
+
+	return index, true, nil
+}
+
+// findKey returns the current index of the row whose KeyPath value matches the
+// given key, or a NotFound error if no such row exists (for instance, because
+// another user has deleted it since the table was rendered).
+func (widget Table) findKey(key string) (int, error) {
+
+	const location = "table.Widget.findKey"
+
+	tableValue, err := widget.Schema.Get(widget.Object, widget.Path)
+
+	if err != nil {
+		return -1, derp.Wrap(err, location, "Getting table data", widget.Path)
+	}
+
+	if key != "" {
+		for index := range convert.SliceLength(tableValue) {
+
+			// Rows without a key cannot match, so errors reading the key are ignored
+			keyValue, _ := widget.Schema.Get(widget.Object, list.ByDot(widget.Path, strconv.Itoa(index), widget.KeyPath).String())
+
+			if convert.String(keyValue) == key {
+				return index, nil
+			}
+		}
+	}
+
+	return -1, derp.NotFound(location, widget.message(MessageRowNotFound), widget.Path, key)
+}
+
+// hasEmptyKey returns TRUE if the row at rowIndex exists but does not have a
+// KeyPath value yet
+func (widget Table) hasEmptyKey(rowIndex int) bool {
+
+	tableValue, err := widget.Schema.Get(widget.Object, widget.Path)
+
+	if (err != nil) || (rowIndex < 0) || (rowIndex >= convert.SliceLength(tableValue)) {
+		return false
+	}
+
+	keyValue, _ := widget.Schema.Get(widget.Object, list.ByDot(widget.Path, strconv.Itoa(rowIndex), widget.KeyPath).String())
+	return convert.String(keyValue) == ""
+}
+
+// getRowKeys returns the KeyPath value of each row, or nil if the table does
+// not use keys.  Rows without a key have an empty value here.
+func (widget Table) getRowKeys(rowSchema *schema.Schema, rows []any) []string {
+
+	if widget.KeyPath == "" {
+		return nil
+	}
+
+	result := make([]string, len(rows))
+
+	for index, row := range rows {
+		keyValue, _ := rowSchema.Get(row, widget.KeyPath)
+		result[index] = convert.String(keyValue)
+	}
+
+	return result
+}
+
+// rowID returns the identifier used to address a row in generated URLs: the
+// row's key when the table has a KeyPath, or the row's index otherwise.  Rows
+// that do not have a key yet are addressed by their index with a "#" prefix.
+func (widget Table) rowID(rowIndex int) string {
+
+	if (widget.KeyPath != "") && (rowIndex >= 0) && (rowIndex < len(widget.rowKeys)) {
+
+		if key := widget.rowKeys[rowIndex]; key != "" {
+			return key
+		}
+
+		return "#" + strconv.Itoa(rowIndex)
+	}
+
+	return strconv.Itoa(rowIndex)
+}
@@ -0,0 +1,321 @@
+package table
+
+import (
+	"bytes"
+	"html"
+	"testing"
+
+	"github.com/benpate/derp"
+	"github.com/benpate/form"
+	"github.com/benpate/rosetta/mapof"
+	"github.com/benpate/rosetta/schema"
+	"github.com/benpate/rosetta/sliceof"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newKeyTable returns a Table whose rows are addressed by their "taskId" field.
+// The keys are deliberately NOT the same as the row indexes.
+func newKeyTable() Table {
+
+	s := schema.Schema{
+		Element: schema.Object{
+			Properties: schema.ElementMap{
+				"data": schema.Array{
+					Items: schema.Object{
+						Properties: schema.ElementMap{
+							"taskId": schema.String{},
+							"name":   schema.String{},
+							"age":    schema.Integer{},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	f := testForm()
+
+	db := &testDatabase{
+		Data: sliceof.Object[mapof.Any]{
+			mapof.Any{"taskId": "1", "name": "John Connor", "age": 20},
+			mapof.Any{"taskId": "0", "name": "Sarah Connor", "age": 45},
+			mapof.Any{"taskId": "abc", "name": "Kyle Reese", "age": 30},
+		},
+	}
+
+	return New(&s, &f, db, "data", testIconProvider{}, "http://localhost/table").UseKeyPath("taskId")
+}
+
+/******************************************
+ * lookupRow()
+ ******************************************/
+
+func TestLookupRow_Index(t *testing.T) {
+
+	table := newTestTable() // no KeyPath
+
+	index, ok, err := table.lookupRow("1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, index)
+
+	// Non-numeric indexes are not OK, but are not errors either
+	_, ok, err = table.lookupRow("abc")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestLookupRow_Key(t *testing.T) {
+
+	table := newKeyTable()
+
+	check := func(key string, expected int) {
+		index, ok, err := table.lookupRow(key)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, expected, index, "key: %s", key)
+	}
+
+	check("1", 0) // keys are never mistaken for indexes
+	check("0", 1)
+	check("abc", 2)
+}
+
+func TestLookupRow_KeyNotFound(t *testing.T) {
+
+	table := newKeyTable()
+
+	_, ok, err := table.lookupRow("999")
+
+	require.Error(t, err)
+	assert.True(t, derp.IsNotFound(err))
+	assert.False(t, ok)
+}
+
+// An empty key never matches a row, even a row with an empty key.
+func TestLookupRow_EmptyKey(t *testing.T) {
+
+	table := newKeyTable()
+	db := table.Object.(*testDatabase)
+	db.Data = append(db.Data, mapof.Any{"name": "No Key"})
+
+	_, _, err := table.lookupRow("")
+
+	require.Error(t, err)
+	assert.True(t, derp.IsNotFound(err))
+}
+
+/******************************************
+ * rowID()
+ ******************************************/
+
+func TestRowID(t *testing.T) {
+
+	table := newTestTable()
+	assert.Equal(t, "2", table.rowID(2)) // no KeyPath uses indexes
+
+	table.KeyPath = "taskId"
+	table.rowKeys = []string{"x", "y"}
+	assert.Equal(t, "y", table.rowID(1))
+	assert.Equal(t, "2", table.rowID(2)) // out of range (e.g. a new row) falls back to the index
+
+	table.rowKeys = []string{"x", ""}
+	assert.Equal(t, "#1", table.rowID(1)) // rows without a key are addressed by index
+}
+
+// Rows without a key are found by their "#" prefixed index
+func TestLookupRow_NoKey(t *testing.T) {
+
+	table := newKeyTable()
+	db := table.Object.(*testDatabase)
+	db.Data = append(db.Data, mapof.Any{"name": "No Key"})
+
+	index, ok, err := table.lookupRow("#3")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 3, index)
+
+	_, _, err = table.lookupRow("#abc")
+	require.Error(t, err)
+
+	// Rows that have a key (or do not exist) cannot be addressed by index
+	_, ok, err = table.lookupRow("#0")
+	require.Error(t, err)
+	assert.True(t, derp.IsNotFound(err))
+	assert.False(t, ok)
+
+	_, _, err = table.lookupRow("#9")
+	assert.True(t, derp.IsNotFound(err))
+}
+
+func TestDo_IndexForKeyedRow(t *testing.T) {
+
+	table := newKeyTable()
+	db := table.Object.(*testDatabase)
+
+	err := table.Do(mustURL(t, "http://x?edit=%230"), withVersion(t, table, 0, map[string]any{"name": "Changed", "age": 1}))
+
+	require.Error(t, err)
+	assert.True(t, derp.IsNotFound(err))
+	assert.Equal(t, "John Connor", db.Data[0]["name"])
+}
+
+/******************************************
+ * Draw() - Keys
+ ******************************************/
+
+func TestDraw_KeyLinks(t *testing.T) {
+
+	table := newKeyTable()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x"), &buffer)
+
+	require.NoError(t, err)
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, "edit=abc&focus=0")
+	assert.Contains(t, result, "delete=abc")
+	assert.NotContains(t, result, "delete=2")
+}
+
+func TestDraw_EditByKey(t *testing.T) {
+
+	table := newKeyTable()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?edit=0"), &buffer) // the key "0" is row 1
+
+	require.NoError(t, err)
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `value="Sarah Connor"`)
+	assert.Contains(t, result, "edit=0&focus=0") // form posts back the key
+}
+
+// A key that no longer exists falls back to view-only mode.
+func TestDraw_EditByMissingKey(t *testing.T) {
+
+	table := newKeyTable()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?edit=999"), &buffer)
+
+	require.NoError(t, err)
+	assert.NotContains(t, buffer.String(), "<form")
+}
+
+// New rows have no key yet, so the add form posts an "add" action instead.
+func TestDraw_AddPostsAdd(t *testing.T) {
+
+	table := newKeyTable()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?add=true"), &buffer)
+
+	require.NoError(t, err)
+	assert.Contains(t, buffer.String(), "http://localhost/table?add=true")
+}
+
+/******************************************
+ * Do() - Keys
+ ******************************************/
+
+func TestDo_EditByKey(t *testing.T) {
+
+	table := newKeyTable()
+	db := table.Object.(*testDatabase)
+
+	err := table.Do(mustURL(t, "http://x?edit=0"), withVersion(t, table, 1, map[string]any{"name": "Miles Dyson", "age": 40}))
+
+	require.NoError(t, err)
+	assert.Equal(t, "John Connor", db.Data[0]["name"]) // index 0 is untouched...
+	assert.Equal(t, "Miles Dyson", db.Data[1]["name"]) // ...because key "0" is row 1
+}
+
+func TestDo_DeleteByKey(t *testing.T) {
+
+	table := newKeyTable()
+	db := table.Object.(*testDatabase)
+
+	err := table.Do(mustURL(t, "http://x?delete=1&version="+testRowVersion(t, table, 0)), nil) // the key "1" is row 0
+
+	require.NoError(t, err)
+	require.Equal(t, 2, len(db.Data))
+	assert.Equal(t, "Sarah Connor", db.Data[0]["name"])
+}
+
+// If a row is deleted between rendering and clicking, Do fails with NotFound
+// instead of editing (or deleting) whichever row now sits at that position.
+func TestDo_EditDeletedKey(t *testing.T) {
+
+	table := newKeyTable()
+	db := table.Object.(*testDatabase)
+	db.Data = db.Data[1:] // someone else deleted the row with key "1"
+
+	err := table.Do(mustURL(t, "http://x?edit=1"), map[string]any{"name": "Wrong Row", "age": 1})
+
+	require.Error(t, err)
+	assert.True(t, derp.IsNotFound(err))
+	assert.Equal(t, "Sarah Connor", db.Data[0]["name"])
+}
+
+func TestDo_DeleteDeletedKey(t *testing.T) {
+
+	table := newKeyTable()
+
+	err := table.Do(mustURL(t, "http://x?delete=999"), nil)
+
+	require.Error(t, err)
+	assert.True(t, derp.IsNotFound(err))
+}
+
+/******************************************
+ * Do() / DoAdd() - Adding
+ ******************************************/
+
+func TestDo_Add(t *testing.T) {
+
+	table := newTestTable()
+	db := table.Object.(*testDatabase)
+
+	err := table.Do(mustURL(t, "http://x?add=true"), map[string]any{"name": "T-800", "age": 0})
+
+	require.NoError(t, err)
+	require.Equal(t, 3, len(db.Data))
+	assert.Equal(t, "T-800", db.Data[2]["name"])
+}
+
+func TestDoAdd_NotAllowed(t *testing.T) {
+
+	table := newTestTable()
+	table.CanAdd = false
+
+	err := table.DoAdd(map[string]any{"name": "T-800", "age": 0})
+
+	require.Error(t, err)
+}
+
+func TestDoAdd_BadObject(t *testing.T) {
+
+	table := newTestTable()
+	table.Object = "not a pointer-getter"
+
+	err := table.DoAdd(map[string]any{"name": "T-800", "age": 0})
+
+	require.Error(t, err)
+}
+
+// Rows are still addressed by key when the key is not one of the displayed columns.
+func TestDraw_KeyNotInForm(t *testing.T) {
+
+	table := newKeyTable()
+	table.Form = pointerTo(form.Element{
+		Type:     "layout-vertical",
+		Children: []form.Element{{Type: "text", Label: "Name", Path: "name"}},
+	})
+
+	result, err := table.DrawViewString()
+
+	require.NoError(t, err)
+	assert.Contains(t, result, "delete=abc")
+}
@@ -0,0 +1,139 @@
+package table
+
+import (
+	"strconv"
+
+	"github.com/benpate/html"
+)
+
+/******************************************
+ * Keyboard Navigation
+ ******************************************/
+
+// keyboardScript moves focus between the cells of a table with the arrow keys,
+// and saves the edit row when Tab (or Shift-Tab) leaves its last (or first)
+// field, opening the next (or previous) row for editing.  Every other keyboard
+// action (Enter and Escape) is handled by htmx triggers on the table itself.
+// The script is included with every table, but only listens once per page.
+const keyboardScript = `if (!window.tableKeyboard) {
+	window.tableKeyboard = true;
+	document.addEventListener("keydown", function(event) {
+		var target = event.target;
+
+		if (target.matches("td[data-col]")) {
+			var next = null;
+			var row = target.parentElement;
+			var selector = "td[data-col='" + target.dataset.col + "']";
+
+			switch (event.key) {
+			case "ArrowLeft":
+				next = target.previousElementSibling;
+				break;
+			case "ArrowRight":
+				next = target.nextElementSibling;
+				break;
+			case "ArrowUp":
+				for (row = row.previousElementSibling; row && !next; row = row.previousElementSibling) {
+					next = row.querySelector(selector);
+				}
+				break;
+			case "ArrowDown":
+				for (row = row.nextElementSibling; row && !next; row = row.nextElementSibling) {
+					next = row.querySelector(selector);
+				}
+				break;
+			}
+
+			if (next && next.matches("td[data-col]")) {
+				event.preventDefault();
+				target.tabIndex = -1;
+				next.tabIndex = 0;
+				next.focus();
+			}
+			return;
+		}
+
+		var editRow = target.closest("tr[data-next], tr[data-previous]");
+
+		if ((event.key == "Tab") && editRow) {
+			var fields = editRow.querySelectorAll("input:not([type=hidden]), select, textarea");
+			var url = null;
+
+			if (!event.shiftKey && (target == fields[fields.length - 1])) {
+				url = editRow.dataset.next;
+			} else if (event.shiftKey && (target == fields[0])) {
+				url = editRow.dataset.previous;
+			}
+
+			if (url) {
+				event.preventDefault();
+				var form = target.closest("form");
+				htmx.ajax("POST", url, {source: form, target: form, swap: "outerHTML"});
+			}
+		}
+	});
+}`
+
+// firstDisplayedRow returns the index of the first row on the current page, or
+// -1 if no rows are displayed.
+func (widget Table) firstDisplayedRow() int {
+
+	page := getPage(widget.rowOrder, widget.view.Page, widget.pageSize())
+
+	if len(page) == 0 {
+		return -1
+	}
+
+	return page[0]
+}
+
+// keyboardCell adds the attributes that let a view cell receive focus from the
+// keyboard.  Only the first cell is reachable with Tab.  The arrow keys move
+// focus from there to the other cells.
+func (widget Table) keyboardCell(rowIndex int, colIndex int, cell *html.Element) {
+
+	tabIndex := "-1"
+	if (colIndex == 0) && (rowIndex == widget.firstDisplayedRow()) {
+		tabIndex = "0"
+	}
+
+	cell.Attr("tabindex", tabIndex).
+		Attr("data-col", strconv.Itoa(colIndex))
+}
+
+// keyboardEditRow adds the URLs that save the edit row and then open the next
+// (or previous) displayed row for editing.
+func (widget Table) keyboardEditRow(rowIndex int, row *html.Element) {
+
+	previous, next := widget.moveTargets(rowIndex)
+
+	if next >= 0 {
+		row.Attr("data-next", widget.getURL("edit-next", rowIndex, next))
+	}
+
+	if previous >= 0 {
+		row.Attr("data-previous", widget.getURL("edit-previous", rowIndex, previous))
+	}
+}
+
+// drawCancelButton writes the button that closes an editor without saving.
+// With keyboard navigation, pressing Escape anywhere in the form does the same.
+func (widget Table) drawCancelButton(b *html.Builder) {
+
+	button := b.Button().
+		Type("button").
+		Data("hx-get", widget.getURL("view", 0, 0))
+
+	if widget.Keyboard {
+		button.Data("hx-trigger", "click, keyup[key=='Escape'] from:closest form")
+	}
+
+	button.Attr("aria-label", widget.message(MessageCancel)).
+		InnerHTML(widget.Icons.Get("cancel")).
+		Close()
+}
+
+// drawKeyboardScript writes the script that handles the arrow keys and Tab
+func (widget Table) drawKeyboardScript(b *html.Builder) {
+	b.Container("script").InnerHTML(keyboardScript).Close()
+}
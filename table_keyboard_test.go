@@ -0,0 +1,104 @@
+package table
+
+import (
+	"bytes"
+	"html"
+	"strings"
+	"testing"
+
+	"github.com/benpate/derp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseKeyboard(t *testing.T) {
+	table := newTestTable()
+
+	result := table.UseKeyboard()
+
+	assert.True(t, result.Keyboard)
+	assert.False(t, table.Keyboard) // the original is left unchanged
+}
+
+func TestDraw_KeyboardCells(t *testing.T) {
+	table := newMoveTable()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+	assert.NotContains(t, buffer.String(), "tabindex") // keyboard navigation is optional
+	assert.NotContains(t, buffer.String(), "<script")
+
+	table = table.UseKeyboard()
+	buffer.Reset()
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Equal(t, 1, strings.Count(result, `tabindex="0"`)) // only the first cell is reachable with Tab
+	assert.Equal(t, 7, strings.Count(result, `tabindex="-1"`))
+	assert.Contains(t, result, `tabindex="0" data-col="0" hx-get="http://localhost/table?edit=0&focus=0" hx-trigger="click, keyup[key=='Enter']"`)
+	assert.Contains(t, result, `data-col="1" hx-get="http://localhost/table?edit=3&focus=1"`)
+	assert.Equal(t, 1, strings.Count(result, "<script>"))
+}
+
+// The first cell on the current page is reachable with Tab
+func TestDraw_KeyboardPage(t *testing.T) {
+	table := newMoveTable().UseKeyboard().UsePageSize(2)
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x?page=2"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `tabindex="0" data-col="0" hx-get="http://localhost/table?edit=2&focus=0&page=2"`)
+}
+
+func TestDraw_KeyboardEditRow(t *testing.T) {
+	table := newMoveTable().UseKeyboard()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x?edit=1"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `data-next="http://localhost/table?edit=1&next=2"`)
+	assert.Contains(t, result, `data-previous="http://localhost/table?edit=1&previous=0"`)
+	assert.Contains(t, result, `hx-trigger="click, keyup[key=='Escape'] from:closest form"`)
+}
+
+// The last row has no row after it
+func TestDraw_KeyboardLastRow(t *testing.T) {
+	table := newMoveTable().UseKeyboard()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x?edit=3"), &buffer))
+
+	result := buffer.String()
+	assert.NotContains(t, result, `data-next="`)
+	assert.Contains(t, result, `data-previous="`)
+}
+
+// Saving with Tab edits the next row, and Shift-Tab edits the previous row from its last column
+func TestDraw_KeyboardNextPrevious(t *testing.T) {
+	table := newMoveTable().UseKeyboard()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Do(mustURL(t, "http://x?edit=1&next=2"), withVersion(t, table, 1, map[string]any{"name": "X", "age": 9})))
+	assert.Equal(t, []string{"A", "X", "C", "D"}, testNames(table))
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x?next=2"), &buffer))
+	assert.Contains(t, autofocusedInput(html.UnescapeString(buffer.String())), `name="name" value="C"`)
+
+	buffer.Reset()
+	require.NoError(t, table.Draw(mustURL(t, "http://x?previous=0"), &buffer))
+	assert.Contains(t, autofocusedInput(html.UnescapeString(buffer.String())), `name="age" value="1"`)
+
+	// The URL that saved the row can be redrawn as-is
+	buffer.Reset()
+	require.NoError(t, table.Draw(mustURL(t, "http://x?edit=1&next=2"), &buffer))
+	assert.Contains(t, autofocusedInput(html.UnescapeString(buffer.String())), `name="name" value="C"`)
+
+	// ...unless the row could not be saved, which stays open
+	buffer.Reset()
+	require.NoError(t, table.DrawErrors(mustURL(t, "http://x?edit=1&next=2"), map[string]any{"name": "", "age": 9}, derp.BadRequest("test", "Invalid"), &buffer))
+	assert.Contains(t, html.UnescapeString(buffer.String()), `name="name" value=""`)
+	assert.NotContains(t, html.UnescapeString(buffer.String()), `name="name" value="C"`)
+}
@@ -0,0 +1,248 @@
+package table
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/benpate/derp"
+	"github.com/benpate/html"
+	"github.com/benpate/rosetta/convert"
+	"github.com/benpate/rosetta/list"
+	"github.com/benpate/rosetta/schema"
+)
+
+/******************************************
+ * Move Methods
+ ******************************************/
+
+// DoMove moves the row at index "from" so that it ends up at index "to",
+// shifting the rows in between by one position.
+func (widget Table) DoMove(from int, to int) error {
+
+	const location = "table.Widget.DoMove"
+
+	if !widget.CanMove {
+		return derp.BadRequest(location, widget.message(MessageMoveNotAllowed), widget.Path)
+	}
+
+	if err := widget.moveRow(from, to); err != nil {
+		return derp.Wrap(err, location, "Moving row", widget.Path, from, to)
+	}
+
+	return nil
+}
+
+// moveRow moves a row to a new position, without checking permissions
+func (widget Table) moveRow(from int, to int) error {
+
+	const location = "table.Widget.moveRow"
+
+	tableData, err := widget.Schema.Get(widget.Object, widget.Path)
+
+	if err != nil {
+		return derp.Wrap(err, location, "Locating table data", widget.Path)
+	}
+
+	length := convert.SliceLength(tableData)
+
+	if (from < 0) || (from >= length) || (to < 0) || (to >= length) {
+		return derp.BadRequest(location, widget.message(MessageRowNotFound), widget.Path, from, to, length)
+	}
+
+	if from == to {
+		return nil
+	}
+
+	// Collect a copy of every row that changes position
+	first, last := min(from, to), max(from, to)
+	rows := make([]any, 0, last-first+1)
+
+	for index := first; index <= last; index++ {
+
+		rowValue, err := widget.Schema.Get(widget.Object, list.ByDot(widget.Path, strconv.Itoa(index)).String())
+
+		if err != nil {
+			return derp.Wrap(err, location, "Getting row data", widget.Path, index)
+		}
+
+		rows = append(rows, derefValue(rowValue))
+	}
+
+	// Rearrange the rows, then write them back into their new positions
+	original := slices.Clone(rows)
+	moved := rows[from-first]
+	rows = slices.Delete(rows, from-first, from-first+1)
+	rows = slices.Insert(rows, to-first, moved)
+
+	// Rows are written without validation because their values have not changed.
+	// (Validating would also reject rows whose empty values were never stored)
+	if err := widget.setRows(first, rows); err != nil {
+
+		// Put back the original rows, so that a failed move changes nothing
+		_ = widget.setRows(first, original)
+		return derp.Wrap(err, location, "Setting row data", widget.Path, from, to)
+	}
+
+	return nil
+}
+
+// setRows writes a list of rows into the table, beginning at index "first"
+func (widget Table) setRows(first int, rows []any) error {
+
+	const location = "table.Widget.setRows"
+
+	for offset, rowValue := range rows {
+
+		path := list.ByDot(widget.Path, strconv.Itoa(first+offset)).String()
+
+		if err := schema.SetProperty(widget.Schema.Element, widget.Object, path, rowValue); err != nil {
+			return derp.Wrap(err, location, "Setting row data", path)
+		}
+	}
+
+	return nil
+}
+
+// moveTargets returns the positions that a row moves to when it is moved up or
+// down: the positions of the rows displayed immediately before and after it.
+// A value of -1 means that the row cannot move in that direction.
+func (widget Table) moveTargets(rowIndex int) (int, int) {
+
+	position, ok := widget.rowPositions[rowIndex]
+
+	if !ok {
+		return -1, -1
+	}
+
+	up, down := -1, -1
+
+	if position > 0 {
+		up = widget.rowOrder[position-1]
+	}
+
+	if position < len(widget.rowOrder)-1 {
+		down = widget.rowOrder[position+1]
+	}
+
+	return up, down
+}
+
+// drawMoveControls writes the buttons that move a row up or down, along with
+// the drag handle (if enabled).
+func (widget Table) drawMoveControls(rowIndex int, b *html.Builder) {
+
+	up, down := widget.moveTargets(rowIndex)
+
+	if widget.DragHandle {
+		b.Span().
+			Class("grid-drag-handle").
+			Attr("draggable", "true").
+			Attr("ondragstart", "event.dataTransfer.setData('text/plain', this.dataset.row)").
+			Attr("data-row", widget.rowID(rowIndex)).
+			Attr("aria-hidden", "true").
+			InnerHTML(widget.Icons.Get("drag")).
+			Close()
+		b.Space()
+	}
+
+	if up >= 0 {
+		b.Button().
+			Type("button").
+			Attr("aria-label", widget.rowLabel(MessageMoveUpRow, rowIndex)).
+			Data("hx-post", widget.getURL("move", rowIndex, up)).
+			InnerHTML(widget.Icons.Get("move-up")).
+			Close()
+		b.Space()
+	}
+
+	if down >= 0 {
+		b.Button().
+			Type("button").
+			Attr("aria-label", widget.rowLabel(MessageMoveDownRow, rowIndex)).
+			Data("hx-post", widget.getURL("move", rowIndex, down)).
+			InnerHTML(widget.Icons.Get("move-down")).
+			Close()
+		b.Space()
+	}
+}
+
+// canMoveRows returns TRUE if rows can be moved in the current view.  Rows are
+// only moved while every row is displayed in its stored order, so that each
+// row lands where the user dropped it.
+func (widget Table) canMoveRows() bool {
+
+	if !widget.CanMove || widget.isTree() {
+		return false
+	}
+
+	tableElement, err := widget.getTableElement()
+
+	if err != nil {
+		return false
+	}
+
+	rowSchema := newRowSchema(tableElement)
+
+	if (widget.sortColumn(&rowSchema) >= 0) || widget.isGrouped(&rowSchema) {
+		return false
+	}
+
+	// Searching or filtering hides rows that would be moved past unseen
+	if widget.CanSearch && (strings.TrimSpace(widget.view.Search) != "") {
+		return false
+	}
+
+	return ParseFilterState(rowSchema, widget.Form.Children, widget.view.Filters).IsEmpty()
+}
+
+// canInsertRows returns TRUE if new rows can be inserted between existing rows.
+// Rows are only inserted while they are displayed in their stored order, so
+// that each new row appears where the user asked for it.
+func (widget Table) canInsertRows() bool {
+
+	if !widget.CanAdd || !widget.CanInsert || widget.isTree() {
+		return false
+	}
+
+	tableElement, err := widget.getTableElement()
+
+	if err != nil {
+		return false
+	}
+
+	rowSchema := newRowSchema(tableElement)
+	return (widget.sortColumn(&rowSchema) < 0) && !widget.isGrouped(&rowSchema)
+}
+
+/******************************************
+ * Helper Functions
+ ******************************************/
+
+// getRowPositions returns the position of each row (by index) in rowOrder, so
+// that the rows before and after it can be found without searching rowOrder.
+func getRowPositions(rowOrder []int) map[int]int {
+
+	result := make(map[int]int, len(rowOrder))
+
+	for position, rowIndex := range rowOrder {
+		result[rowIndex] = position
+	}
+
+	return result
+}
+
+// derefValue returns a copy of the value that a pointer points to, or the value
+// itself if it is not a pointer.  Rows are read from the table as pointers into
+// the underlying array, so they must be copied before the array is rearranged.
+func derefValue(value any) any {
+
+	reflected := reflect.ValueOf(value)
+
+	if (reflected.Kind() == reflect.Pointer) && !reflected.IsNil() {
+		return reflected.Elem().Interface()
+	}
+
+	return value
+}
@@ -0,0 +1,168 @@
+package table
+
+import (
+	"bytes"
+	"html"
+	"testing"
+
+	"github.com/benpate/rosetta/mapof"
+	"github.com/benpate/rosetta/sliceof"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMoveTable returns a Table with four rows that can be moved
+func newMoveTable() Table {
+	table := newTestTable().AllowMove()
+	table.Object = &testDatabase{
+		Data: sliceof.Object[mapof.Any]{
+			mapof.Any{"name": "A", "age": 1},
+			mapof.Any{"name": "B", "age": 2},
+			mapof.Any{"name": "C", "age": 3},
+			mapof.Any{"name": "D", "age": 4},
+		},
+	}
+	return table
+}
+
+// testNames returns the name of each row in a test table, in order
+func testNames(table Table) []string {
+	result := make([]string, 0)
+	for _, row := range table.Object.(*testDatabase).Data {
+		result = append(result, row.GetString("name"))
+	}
+	return result
+}
+
+/******************************************
+ * DoMove()
+ ******************************************/
+
+func TestDoMove_Down(t *testing.T) {
+	table := newMoveTable()
+	require.NoError(t, table.DoMove(0, 2))
+	assert.Equal(t, []string{"B", "C", "A", "D"}, testNames(table))
+}
+
+func TestDoMove_Up(t *testing.T) {
+	table := newMoveTable()
+	require.NoError(t, table.DoMove(3, 1))
+	assert.Equal(t, []string{"A", "D", "B", "C"}, testNames(table))
+}
+
+func TestDoMove_SamePosition(t *testing.T) {
+	table := newMoveTable()
+	require.NoError(t, table.DoMove(1, 1))
+	assert.Equal(t, []string{"A", "B", "C", "D"}, testNames(table))
+}
+
+func TestDoMove_NotAllowed(t *testing.T) {
+	table := newMoveTable()
+	table.CanMove = false
+	require.Error(t, table.DoMove(0, 1))
+	assert.Equal(t, []string{"A", "B", "C", "D"}, testNames(table))
+}
+
+func TestDoMove_OutOfRange(t *testing.T) {
+	table := newMoveTable()
+	require.Error(t, table.DoMove(-1, 1))
+	require.Error(t, table.DoMove(0, 4))
+	require.Error(t, table.DoMove(4, 0))
+	assert.Equal(t, []string{"A", "B", "C", "D"}, testNames(table))
+}
+
+/******************************************
+ * Do()
+ ******************************************/
+
+func TestDo_Move(t *testing.T) {
+	table := newMoveTable()
+	require.NoError(t, table.Do(mustURL(t, "http://x?move=2&to=0"), nil))
+	assert.Equal(t, []string{"C", "A", "B", "D"}, testNames(table))
+}
+
+// Drag-and-drop posts the moved row in the form data
+func TestDo_MoveDropped(t *testing.T) {
+	table := newMoveTable()
+	require.NoError(t, table.Do(mustURL(t, "http://x?to=3"), map[string]any{"move": "0"}))
+	assert.Equal(t, []string{"B", "C", "D", "A"}, testNames(table))
+}
+
+func TestDo_MoveByKey(t *testing.T) {
+	table := newKeyTable().AllowMove() // keys are "1", "0", "abc"
+
+	// The target row is identified by its key, too
+	require.NoError(t, table.Do(mustURL(t, "http://x?move=abc&to=1"), nil))
+	assert.Equal(t, []string{"Kyle Reese", "John Connor", "Sarah Connor"}, testNames(table))
+}
+
+// Moves are refused unless rows are displayed in their stored order
+func TestDo_MoveNotAllowed(t *testing.T) {
+	table := newMoveTable().AllowSort().AllowSearch()
+
+	require.Error(t, table.Do(mustURL(t, "http://x?move=2&to=0&sort=name"), nil))
+	require.Error(t, table.Do(mustURL(t, "http://x?move=2&to=0&q=A"), nil))
+	assert.Equal(t, []string{"A", "B", "C", "D"}, testNames(table))
+}
+
+func TestDo_MoveBadTarget(t *testing.T) {
+	table := newMoveTable()
+	require.NoError(t, table.Do(mustURL(t, "http://x?move=1&to=abc"), nil)) // nothing to do
+	assert.Equal(t, []string{"A", "B", "C", "D"}, testNames(table))
+}
+
+/******************************************
+ * Drawing
+ ******************************************/
+
+func TestMoveTargets(t *testing.T) {
+	table := newMoveTable()
+	table.rowOrder = []int{0, 2, 3} // row 1 is not displayed
+	table.rowPositions = getRowPositions(table.rowOrder)
+
+	up, down := table.moveTargets(0)
+	assert.Equal(t, -1, up)
+	assert.Equal(t, 2, down) // skips the row that is not displayed
+
+	up, down = table.moveTargets(3)
+	assert.Equal(t, 2, up)
+	assert.Equal(t, -1, down)
+
+	up, down = table.moveTargets(1)
+	assert.Equal(t, -1, up)
+	assert.Equal(t, -1, down)
+}
+
+func TestDraw_MoveControls(t *testing.T) {
+	table := newMoveTable()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, "move=1&to=0")
+	assert.Contains(t, result, "move=1&to=2")
+	assert.NotContains(t, result, "move=0&to=-1")
+	assert.NotContains(t, result, "grid-drag-handle")
+}
+
+func TestDraw_MoveControlsHiddenWhenSorted(t *testing.T) {
+	table := newMoveTable().AllowSort()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x?sort=name"), &buffer))
+
+	assert.NotContains(t, html.UnescapeString(buffer.String()), "move=")
+}
+
+func TestDraw_DragHandle(t *testing.T) {
+	table := newMoveTable().UseDragHandle()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `class="grid-drag-handle" draggable="true"`)
+	assert.Contains(t, result, `data-row="2"`)
+	assert.Contains(t, result, `hx-post="http://localhost/table?to=2" hx-trigger="drop"`)
+}
@@ -0,0 +1,302 @@
+package table
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/benpate/derp"
+	"github.com/benpate/form"
+	"github.com/benpate/rosetta/convert"
+	"github.com/benpate/rosetta/list"
+	"github.com/benpate/rosetta/mapof"
+	"github.com/benpate/rosetta/schema"
+	"github.com/benpate/rosetta/sliceof"
+)
+
+/******************************************
+ * Sub-Tables
+ ******************************************/
+
+// SubTableType is the form.Element type of a column that embeds a nested table,
+// such as the line items in each order.  The column's Path points to an array
+// in each row, and its Children are the columns of the nested table.  Nested
+// rows are added, edited, and deleted on their own (without editing the row
+// that contains them) using the same TargetURL, with a "path" parameter (such
+// as "path=data.2.items") that routes Draw and Do to the nested table.
+const SubTableType = "table"
+
+// isSubTable returns TRUE if a column embeds a nested table
+func isSubTable(field form.Element) bool {
+	return field.Type == SubTableType
+}
+
+// formFields returns every field in a form element that users can write, like
+// form.Element.AllElements, but without sub-tables or the columns inside them.
+// Sub-tables save their own rows, so they are never written with the row that
+// contains them.
+func formFields(element form.Element) []form.Element {
+
+	result := make([]form.Element, 0)
+
+	if isSubTable(element) {
+		return result
+	}
+
+	if (element.Path != "") && !element.ReadOnly {
+		result = append(result, element)
+	}
+
+	for _, child := range element.Children {
+		result = append(result, formFields(child)...)
+	}
+
+	return result
+}
+
+// subTableRow is the Object of a nested table: the row that contains the nested
+// array.  Rows that are stored in maps have no nested array until it is first
+// written, so reading never changes the row (or its version).
+type subTableRow struct {
+	row     any          // Pointer to the row in the parent table
+	path    string       // Path to the nested array in the row
+	element schema.Array // Schema of the nested array
+}
+
+// GetPointer implements the schema.PointerGetter interface.  A missing nested
+// array is returned as a detached empty array, which is not stored in the row.
+func (object subTableRow) GetPointer(name string) (any, bool) {
+	return object.getPointer(name, false)
+}
+
+// SetObject implements the schema.ObjectSetter interface.  It creates the
+// nested array if it does not exist yet, then writes into it one step at a
+// time.  (Maps such as mapof.Any set whole paths themselves, and would replace
+// the nested array with a new map instead.)
+func (object subTableRow) SetObject(element schema.Element, path list.List, value any) error {
+
+	const location = "table.subTableRow.SetObject"
+
+	head, tail := path.Split()
+	subElement, ok := element.GetElement(head)
+
+	if !ok {
+		return derp.Internal(location, "Property does not exist in schema", head)
+	}
+
+	pointer, ok := object.getPointer(head, true)
+
+	if !ok {
+		return derp.Internal(location, "Row must be a PointerGetter", head)
+	}
+
+	return schema.SetProperty(subElement, pointer, tail.String(), value)
+}
+
+// getPointer returns a pointer to one property of the row.  If create is TRUE
+// then a missing nested array is also stored in the row, so that writes to it
+// are kept.
+func (object subTableRow) getPointer(name string, create bool) (any, bool) {
+
+	getter, ok := object.row.(schema.PointerGetter)
+
+	if !ok {
+		return nil, false
+	}
+
+	if result, ok := getter.GetPointer(name); ok && (result != nil) {
+		return result, true
+	}
+
+	row, ok := object.row.(*mapof.Any)
+
+	if !ok || (name != object.path) {
+		return nil, false
+	}
+
+	array := newArray(object.element)
+
+	if create {
+		(*row)[name] = array
+	}
+
+	return array, true
+}
+
+// newArray returns an empty array for a nested table, in a type that rosetta
+// can read, write, and validate
+func newArray(element schema.Array) any {
+
+	if _, ok := element.Items.(schema.Object); ok {
+		return &sliceof.Object[mapof.Any]{}
+	}
+
+	return &sliceof.Any{}
+}
+
+// fullPath returns the path to the table's data from the Object of the
+// outermost table, which is how nested tables are addressed in URLs
+func (widget Table) fullPath() string {
+
+	if widget.parentPath == "" {
+		return widget.Path
+	}
+
+	return list.ByDot(widget.parentPath, widget.Path).String()
+}
+
+// subTable returns the nested table embedded in one column of a row.  The
+// nested table shares its parent's icons, lookups, translations, and
+// permissions.
+func (widget Table) subTable(field form.Element, rowIndex int) (Table, error) {
+
+	const location = "table.Widget.subTable"
+
+	tableElement, err := widget.getTableElement()
+
+	if err != nil {
+		return Table{}, derp.Wrap(err, location, "Getting table element")
+	}
+
+	rowPath := list.ByDot(widget.Path, strconv.Itoa(rowIndex)).String()
+	row, err := widget.Schema.Get(widget.Object, rowPath)
+
+	if err != nil {
+		return Table{}, derp.Wrap(err, location, "Getting row", rowPath)
+	}
+
+	rowSchema := schema.New(tableElement.Items)
+	element, _ := rowSchema.GetElement(field.Path)
+	array, _ := element.(schema.Array)
+	parentPath := list.ByDot(widget.fullPath(), strconv.Itoa(rowIndex)).String()
+
+	columns := form.Element{
+		Type:     "layout-vertical",
+		Label:    field.Label,
+		Children: field.Children,
+	}
+
+	return Table{
+		Schema:         &rowSchema,
+		Form:           &columns,
+		Object:         subTableRow{row: row, path: field.Path, element: array},
+		Path:           field.Path,
+		TargetURL:      subTableURL(widget.TargetURL, list.ByDot(parentPath, field.Path).String()),
+		Icons:          widget.Icons,
+		LookupProvider: widget.LookupProvider,
+		CanAdd:         widget.CanAdd,
+		CanEdit:        widget.CanEdit,
+		CanDelete:      widget.CanDelete,
+		CanMove:        widget.CanMove,
+		CanInsert:      widget.CanInsert,
+		CanDuplicate:   widget.CanDuplicate,
+		Translator:     widget.Translator,
+		Locale:         widget.Locale,
+		LocaleFormats:  widget.LocaleFormats,
+		announcement:   widget.announcement,
+		parentPath:     parentPath,
+	}, nil
+}
+
+// subTableURL returns the TargetURL of a nested table, which routes every
+// request back to the nested table with a "path" parameter
+func subTableURL(targetURL string, path string) string {
+
+	parsed, err := url.Parse(targetURL)
+
+	// If the TargetURL can't be parsed, fall back to returning it unchanged
+	if err != nil {
+		return targetURL
+	}
+
+	query := parsed.Query()
+	query.Set("path", path)
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// isSubTablePath returns TRUE if a request's "path" parameter addresses a
+// nested table, instead of this table
+func (widget Table) isSubTablePath(path string) bool {
+	return (path != "") && (path != widget.fullPath())
+}
+
+// findSubTable returns the nested table at a path (such as "data.2.items"),
+// searching through every level of nesting.  Paths must use the same form as
+// subTable, so each nested table has exactly one address.
+func (widget Table) findSubTable(path string) (Table, error) {
+
+	const location = "table.Widget.findSubTable"
+
+	if path == widget.fullPath() {
+		return widget, nil
+	}
+
+	// Split the path into a row index, and the path within that row
+	remainder, ok := strings.CutPrefix(path, widget.fullPath()+".")
+
+	if !ok {
+		return Table{}, derp.BadRequest(location, widget.message(MessagePathNotInTable), widget.fullPath(), path)
+	}
+
+	index, remainder, _ := strings.Cut(remainder, ".")
+	rowIndex, err := strconv.Atoi(index)
+
+	if (err != nil) || (strconv.Itoa(rowIndex) != index) {
+		return Table{}, derp.BadRequest(location, widget.message(MessageInvalidRowIndex), widget.fullPath(), path)
+	}
+
+	tableData, err := widget.Schema.Get(widget.Object, widget.Path)
+
+	if err != nil {
+		return Table{}, derp.Wrap(err, location, "Getting table data", widget.Path)
+	}
+
+	if (rowIndex < 0) || (rowIndex >= convert.SliceLength(tableData)) {
+		return Table{}, derp.NotFound(location, widget.message(MessageRowNotFound), widget.fullPath(), path)
+	}
+
+	for _, field := range widget.Form.Children {
+		if isSubTable(field) && ((remainder == field.Path) || strings.HasPrefix(remainder, field.Path+".")) {
+
+			subTable, err := widget.subTable(field, rowIndex)
+
+			if err != nil {
+				return Table{}, derp.Wrap(err, location, "Getting sub-table", path)
+			}
+
+			return subTable.findSubTable(path)
+		}
+	}
+
+	return Table{}, derp.BadRequest(location, widget.message(MessageNotSubTable), widget.fullPath(), path)
+}
+
+// subTableHTML renders the nested table in one column of a row.  Nested tables
+// are view-only while this table is drawn as a form, because forms cannot
+// contain other forms.
+func (widget Table) subTableHTML(field form.Element, rowIndex int) (string, error) {
+
+	const location = "table.Widget.subTableHTML"
+
+	subTable, err := widget.subTable(field, rowIndex)
+
+	if err != nil {
+		return "", derp.Wrap(err, location, "Getting sub-table", field.Path)
+	}
+
+	if widget.drawingForm {
+		subTable = subTable.AllowNone()
+	}
+
+	// Announcements belong to the live region of the outer table
+	subTable.announcement = nil
+
+	result, err := subTable.DrawViewString()
+
+	if err != nil {
+		return "", derp.Wrap(err, location, "Drawing sub-table", subTable.fullPath())
+	}
+
+	return result, nil
+}
@@ -0,0 +1,238 @@
+package table
+
+import (
+	"bytes"
+	"html"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/benpate/derp"
+	"github.com/benpate/form"
+	"github.com/benpate/rosetta/convert"
+	"github.com/benpate/rosetta/mapof"
+	"github.com/benpate/rosetta/schema"
+	"github.com/benpate/rosetta/sliceof"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newNestedTable returns a table of orders, where each order contains a nested table of line items
+func newNestedTable() Table {
+
+	s := schema.Schema{
+		Element: schema.Object{
+			Properties: schema.ElementMap{
+				"data": schema.Array{
+					Items: schema.Object{
+						Properties: schema.ElementMap{
+							"customer": schema.String{},
+							"items": schema.Array{
+								Items: schema.Object{
+									Properties: schema.ElementMap{
+										"product":  schema.String{Required: true},
+										"quantity": schema.Integer{},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	f := form.Element{
+		Type: "layout-vertical",
+		Children: []form.Element{
+			{Type: "text", Label: "Customer", Path: "customer"},
+			{Type: SubTableType, Label: "Items", Path: "items", Children: []form.Element{
+				{Type: "text", Label: "Product", Path: "product"},
+				{Type: "number", Label: "Quantity", Path: "quantity"},
+			}},
+		},
+	}
+
+	db := &testDatabase{
+		Data: sliceof.Object[mapof.Any]{
+			mapof.Any{"customer": "John Connor", "items": &sliceof.Object[mapof.Any]{
+				mapof.Any{"product": "Motorcycle", "quantity": 1},
+			}},
+			mapof.Any{"customer": "Sarah Connor", "items": &sliceof.Object[mapof.Any]{
+				mapof.Any{"product": "Shotgun", "quantity": 2},
+				mapof.Any{"product": "Sunglasses", "quantity": 3},
+			}},
+		},
+	}
+
+	return New(&s, &f, db, "data", testIconProvider{}, "http://localhost/table")
+}
+
+// testSubTable returns the nested table at the requested path
+func testSubTable(t *testing.T, table Table, path string) Table {
+	subTable, err := table.findSubTable(path)
+	require.NoError(t, err)
+	return subTable
+}
+
+// nestedItems returns the line items of one order
+func nestedItems(t *testing.T, table Table, rowIndex int) sliceof.Object[mapof.Any] {
+	items, err := table.Schema.Get(table.Object, "data."+strconv.Itoa(rowIndex)+".items")
+	require.NoError(t, err)
+	return *items.(*sliceof.Object[mapof.Any])
+}
+
+func TestDraw_SubTable(t *testing.T) {
+
+	result := drawGroupTable(t, newNestedTable(), "http://x")
+
+	// Each order contains its own table, which links back to itself with a "path" parameter
+	assert.Equal(t, 3, strings.Count(result, "<table"))
+	assertOrder(t, result, "Sarah Connor", "<table", "<caption>Items</caption>", "Shotgun", "Sunglasses", "</table>")
+	assert.Contains(t, result, `hx-get="http://localhost/table?edit=0&focus=0&path=data.1.items"`)
+	assert.Contains(t, result, `hx-post="http://localhost/table?delete=1&path=data.1.items&version=`)
+
+	// Clicking a nested table never edits the order that contains it
+	assert.NotContains(t, result, `hx-get="http://localhost/table?edit=1&focus=1"`)
+}
+
+func TestDraw_SubTablePath(t *testing.T) {
+
+	// Requests with a "path" draw only the nested table
+	result := drawGroupTable(t, newNestedTable(), "http://x?path=data.1.items&edit=0")
+
+	assert.Equal(t, 1, strings.Count(result, "<table"))
+	assert.Contains(t, result, `hx-post="http://localhost/table?edit=0&focus=0&path=data.1.items"`)
+	assert.Contains(t, result, `name="product" value="Shotgun"`)
+	assert.NotContains(t, result, "Sarah Connor")
+}
+
+func TestDraw_SubTableReadOnly(t *testing.T) {
+
+	// Nested tables cannot be changed while their order is edited, because forms cannot be nested
+	result := drawGroupTable(t, newNestedTable(), "http://x?edit=1")
+
+	assert.Equal(t, 1, strings.Count(result, "<form"))
+	assertOrder(t, result, `name="customer" value="Sarah Connor"`, "Shotgun", "Sunglasses")
+	assert.NotContains(t, result, "path=data.1.items")
+}
+
+func TestDo_SubTable(t *testing.T) {
+
+	table := newNestedTable()
+
+	require.NoError(t, table.Do(mustURL(t, "http://x?path=data.1.items&add=true"), map[string]any{"product": "Radio", "quantity": "4"}))
+	require.NoError(t, table.Do(mustURL(t, "http://x?path=data.1.items&edit=0"), withVersion(t, testSubTable(t, table, "data.1.items"), 0, map[string]any{"product": "Shotgun", "quantity": "5"})))
+	require.NoError(t, table.Do(mustURL(t, "http://x?path=data.0.items&delete=0&version="+testRowVersion(t, testSubTable(t, table, "data.0.items"), 0)), nil))
+
+	items := nestedItems(t, table, 1)
+	require.Len(t, items, 3)
+	assert.Equal(t, "Radio", items[2]["product"])
+	assert.Equal(t, 5, convert.Int(items[0]["quantity"]))
+	assert.Empty(t, nestedItems(t, table, 0))
+}
+
+func TestDo_SubTableParentEdit(t *testing.T) {
+
+	table := newNestedTable()
+
+	// Saving an order never replaces its line items
+	require.NoError(t, table.Do(mustURL(t, "http://x?edit=1"), withVersion(t, table, 1, map[string]any{"customer": "Sarah Reese", "items": "gone"})))
+
+	db := table.Object.(*testDatabase)
+	assert.Equal(t, "Sarah Reese", db.Data[1]["customer"])
+	assert.Len(t, nestedItems(t, table, 1), 2)
+}
+
+func TestDo_SubTableInvalidPath(t *testing.T) {
+
+	table := newNestedTable()
+
+	for _, path := range []string{"data.9.items", "data.01.items", "data.x.items", "data.0.customer", "other.0.items"} {
+		require.Error(t, table.Do(mustURL(t, "http://x?add=true&path="+path), map[string]any{"product": "Radio"}), path)
+	}
+
+	// The table's own path addresses the table itself
+	require.NoError(t, table.Do(mustURL(t, "http://x?add=true&path=data"), map[string]any{"customer": "Kyle Reese"}))
+
+	db := table.Object.(*testDatabase)
+	assert.Len(t, db.Data, 3)
+}
+
+func TestDo_SubTableInvalidPathTranslated(t *testing.T) {
+
+	table := newNestedTable().UseTranslator(Messages{
+		MessagePathNotInTable:  "Pfad liegt nicht in dieser Tabelle",
+		MessageInvalidRowIndex: "Ungültige Zeilennummer",
+		MessageNotSubTable:     "Pfad ist keine Untertabelle",
+	})
+
+	tests := map[string]string{
+		"other.0.items":   "Pfad liegt nicht in dieser Tabelle",
+		"data.x.items":    "Ungültige Zeilennummer",
+		"data.0.customer": "Pfad ist keine Untertabelle",
+	}
+
+	for path, expected := range tests {
+		err := table.Do(mustURL(t, "http://x?add=true&path="+path), map[string]any{"product": "Radio"})
+		assert.Equal(t, expected, derp.RootMessage(err), path)
+	}
+}
+
+func TestDo_SubTableNewRow(t *testing.T) {
+
+	table := newNestedTable()
+
+	// New orders have no line items until the first one is added
+	require.NoError(t, table.Do(mustURL(t, "http://x?add=true"), map[string]any{"customer": "Kyle Reese"}))
+	assert.Contains(t, drawGroupTable(t, table, "http://x"), "add=true&path=data.2.items")
+
+	require.NoError(t, table.Do(mustURL(t, "http://x?path=data.2.items&add=true"), map[string]any{"product": "Photo", "quantity": "1"}))
+
+	items := nestedItems(t, table, 2)
+	require.Len(t, items, 1)
+	assert.Equal(t, "Photo", items[0]["product"])
+}
+
+// Drawing an order with no line items must not change it, or its version
+func TestDraw_SubTableMissingArray(t *testing.T) {
+
+	table := newNestedTable()
+	require.NoError(t, table.Do(mustURL(t, "http://x?add=true"), map[string]any{"customer": "Kyle Reese"}))
+
+	version := testRowVersion(t, table, 2)
+	drawGroupTable(t, table, "http://x")
+	drawGroupTable(t, table, "http://x?path=data.2.items")
+
+	row, err := table.Schema.Get(table.Object, "data.2")
+	require.NoError(t, err)
+	assert.NotContains(t, *row.(*mapof.Any), "items")
+	assert.Equal(t, version, testRowVersion(t, table, 2))
+
+	require.NoError(t, table.Do(mustURL(t, "http://x?edit=2"), map[string]any{"customer": "Kyle Reese", versionField: version}))
+}
+
+func TestDrawErrors_SubTable(t *testing.T) {
+
+	table := newNestedTable()
+	params := mustURL(t, "http://x?path=data.1.items&edit=0")
+	submitted := withVersion(t, testSubTable(t, table, "data.1.items"), 0, map[string]any{"product": "", "quantity": "2"})
+
+	err := table.Do(params, submitted)
+	require.Error(t, err)
+
+	var buffer bytes.Buffer
+	require.NoError(t, table.DrawErrors(params, submitted, err, &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `<div class="grid-error">Value is required</div>`)
+	assert.NotContains(t, result, "Sarah Connor")
+}
+
+func TestAnnounce_SubTable(t *testing.T) {
+
+	table := newNestedTable().Announce(mustURL(t, "http://x?path=data.1.items&edit=1"))
+
+	result := drawGroupTable(t, table, "http://x?path=data.1.items")
+	assert.Equal(t, 1, strings.Count(result, "Row 2 saved"))
+}
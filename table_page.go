@@ -0,0 +1,103 @@
+package table
+
+import (
+	"slices"
+
+	"github.com/benpate/html"
+	"github.com/benpate/rosetta/null"
+)
+
+// maxPageSize is the largest page size that a client can request via the "size"
+// query parameter, so that untrusted input cannot defeat paging altogether.
+const maxPageSize = 1000
+
+/******************************************
+ * Paging Methods
+ ******************************************/
+
+// pageSize returns the number of rows to display on each page, or zero if
+// paging is disabled.  Clients may request a different page size, but only
+// when the Table itself is configured for paging.
+func (widget Table) pageSize() int {
+
+	if widget.PageSize <= 0 {
+		return 0
+	}
+
+	if widget.view.PageSize > 0 {
+		return min(widget.view.PageSize, maxPageSize)
+	}
+
+	return widget.PageSize
+}
+
+// currentPage returns the (1-based) page to display.  The requested page is
+// clamped to the pages that exist, and an edited row is always shown, even if
+// that means switching to the page that contains it.
+func (widget Table) currentPage(rowOrder []int, editRow null.Int, pageSize int, pageCount int) int {
+
+	if editRow.IsPresent() && (pageSize > 0) {
+		if position := slices.Index(rowOrder, editRow.Int()); position >= 0 {
+			return (position / pageSize) + 1
+		}
+	}
+
+	return min(max(widget.view.Page, 1), pageCount)
+}
+
+// getPageCount returns the number of pages required to display all rows.
+// There is always at least one page, even when the table is empty.
+func getPageCount(rowCount int, pageSize int) int {
+
+	if (pageSize <= 0) || (rowCount == 0) {
+		return 1
+	}
+
+	return ((rowCount - 1) / pageSize) + 1
+}
+
+// getPage returns the window of rows that appear on the requested (1-based) page.
+func getPage(rowOrder []int, page int, pageSize int) []int {
+
+	if pageSize <= 0 {
+		return rowOrder
+	}
+
+	start := min((page-1)*pageSize, len(rowOrder))
+	end := min(start+pageSize, len(rowOrder))
+
+	return rowOrder[start:end]
+}
+
+// drawPager writes the first/previous/next/last controls beneath a paged table
+func (widget Table) drawPager(pageCount int, b *html.Builder) {
+
+	page := widget.view.Page
+
+	b.Div().Class("grid-pager")
+	widget.drawPagerButton("first", MessageFirstPage, 1, page > 1, b)
+	widget.drawPagerButton("previous", MessagePreviousPage, page-1, page > 1, b)
+	b.Space()
+	b.Span().InnerText(widget.message(MessagePageStatus, page, pageCount)).Close()
+	b.Space()
+	widget.drawPagerButton("next", MessageNextPage, page+1, page < pageCount, b)
+	widget.drawPagerButton("last", MessageLastPage, pageCount, page < pageCount, b)
+	b.Close() // Div
+}
+
+// drawPagerButton writes a single pager control, which is disabled when
+// it would not move to a different page.
+func (widget Table) drawPagerButton(icon string, labelKey string, page int, enabled bool, b *html.Builder) {
+
+	button := b.Button().Type("button").Class("link")
+
+	if enabled {
+		button.Data("hx-get", widget.getURL("page", page, 0))
+	} else {
+		button.Attr("disabled", "true")
+	}
+
+	button.Attr("aria-label", widget.message(labelKey)).
+		InnerHTML(widget.Icons.Get(icon))
+	b.Close() // Button
+}
@@ -0,0 +1,198 @@
+package table
+
+import (
+	"bytes"
+	"html"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/benpate/rosetta/mapof"
+	"github.com/benpate/rosetta/null"
+	"github.com/benpate/rosetta/sliceof"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/******************************************
+ * Paging Helpers
+ ******************************************/
+
+func TestGetPageCount(t *testing.T) {
+	assert.Equal(t, 1, getPageCount(0, 10)) // empty tables still have one page
+	assert.Equal(t, 1, getPageCount(25, 0)) // paging disabled
+	assert.Equal(t, 1, getPageCount(10, 10))
+	assert.Equal(t, 2, getPageCount(11, 10))
+	assert.Equal(t, 3, getPageCount(25, 10))
+}
+
+func TestGetPage(t *testing.T) {
+
+	rowOrder := []int{4, 3, 2, 1, 0}
+
+	assert.Equal(t, []int{4, 3}, getPage(rowOrder, 1, 2))
+	assert.Equal(t, []int{2, 1}, getPage(rowOrder, 2, 2))
+	assert.Equal(t, []int{0}, getPage(rowOrder, 3, 2)) // partial last page
+	assert.Equal(t, []int{}, getPage(rowOrder, 9, 2))  // past the end
+	assert.Equal(t, rowOrder, getPage(rowOrder, 1, 0)) // paging disabled
+	assert.Equal(t, rowOrder, getPage(rowOrder, 7, 0)) // paging disabled ignores the page
+	assert.Equal(t, []int{4, 3, 2}, getPage(rowOrder, 1, 3))
+}
+
+func TestPageSize(t *testing.T) {
+
+	table := newTestTable()
+	assert.Equal(t, 0, table.pageSize()) // paging is disabled by default
+
+	table.view.PageSize = 5
+	assert.Equal(t, 0, table.pageSize()) // clients cannot enable paging by themselves
+
+	table.PageSize = 10
+	assert.Equal(t, 5, table.pageSize()) // clients can choose a different size
+
+	table.view.PageSize = 0
+	assert.Equal(t, 10, table.pageSize()) // otherwise the table's default applies
+
+	table.view.PageSize = 999999
+	assert.Equal(t, maxPageSize, table.pageSize()) // but cannot defeat paging altogether
+}
+
+func TestCurrentPage(t *testing.T) {
+
+	table := newTestTable()
+	rowOrder := []int{0, 1, 2, 3, 4, 5, 6}
+
+	table.view.Page = 0
+	assert.Equal(t, 1, table.currentPage(rowOrder, null.Int{}, 3, 3)) // zero means the first page
+
+	table.view.Page = 2
+	assert.Equal(t, 2, table.currentPage(rowOrder, null.Int{}, 3, 3))
+
+	table.view.Page = 99
+	assert.Equal(t, 3, table.currentPage(rowOrder, null.Int{}, 3, 3)) // clamped to the last page
+
+	// An edited row is always visible, so its page wins over the requested one
+	table.view.Page = 1
+	assert.Equal(t, 3, table.currentPage(rowOrder, null.NewInt(6), 3, 3))
+
+	// Adding a row (edit index past the end) keeps the requested page
+	table.view.Page = 2
+	assert.Equal(t, 2, table.currentPage(rowOrder, null.NewInt(7), 3, 3))
+}
+
+/******************************************
+ * Draw() - Paging
+ ******************************************/
+
+// pagedTable returns a Table with five rows ("Row 0" through "Row 4") and two rows per page.
+func pagedTable() Table {
+	table := newTestTable().UsePageSize(2)
+	db := table.Object.(*testDatabase)
+	db.Data = sliceof.Object[mapof.Any]{}
+	for index := range 5 {
+		db.Data = append(db.Data, mapof.Any{"name": "Row " + strconv.Itoa(index), "age": index})
+	}
+	return table
+}
+
+func TestDraw_FirstPage(t *testing.T) {
+
+	table := pagedTable()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x"), &buffer)
+
+	require.NoError(t, err)
+	result := buffer.String()
+	assert.Contains(t, result, "Row 0")
+	assert.Contains(t, result, "Row 1")
+	assert.NotContains(t, result, "Row 2")
+	assert.Contains(t, result, "Page 1 of 3")
+	assert.Contains(t, result, "grid-pager")
+}
+
+// Rows on later pages still link to their absolute index in the data.
+func TestDraw_LaterPage(t *testing.T) {
+
+	table := pagedTable()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?page=3"), &buffer)
+
+	require.NoError(t, err)
+	result := html.UnescapeString(buffer.String())
+	assert.NotContains(t, result, "Row 3")
+	assert.Contains(t, result, "Row 4")
+	assert.Contains(t, result, "Page 3 of 3")
+	assert.Contains(t, result, "delete=4&page=3") // absolute index, and the page survives the round trip
+}
+
+// A page past the end is clamped to the last page.
+func TestDraw_PageTooLarge(t *testing.T) {
+
+	table := pagedTable()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?page=99"), &buffer)
+
+	require.NoError(t, err)
+	assert.Contains(t, buffer.String(), "Page 3 of 3")
+}
+
+// The client can request a different page size.
+func TestDraw_PageSizeParam(t *testing.T) {
+
+	table := pagedTable()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?size=3&page=2"), &buffer)
+
+	require.NoError(t, err)
+	result := buffer.String()
+	assert.NotContains(t, result, "Row 2")
+	assert.Contains(t, result, "Row 3")
+	assert.Contains(t, result, "Page 2 of 2")
+}
+
+// Editing a row on another page switches to the page that contains it.
+func TestDraw_EditOtherPage(t *testing.T) {
+
+	table := pagedTable()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?edit=2&page=1"), &buffer)
+
+	require.NoError(t, err)
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `value="Row 2"`)
+	assert.Contains(t, result, "Page 2 of 3")
+	assert.Contains(t, result, "edit=2&focus=0&page=2") // form hx-post
+}
+
+// The first/previous controls are disabled on the first page, and next/last on the last.
+func TestDraw_PagerButtons(t *testing.T) {
+
+	table := pagedTable()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x"), &buffer)
+
+	require.NoError(t, err)
+	pager := buffer.String()[strings.Index(buffer.String(), "grid-pager"):]
+	pager = html.UnescapeString(pager)
+
+	assert.Equal(t, 2, strings.Count(pager, "disabled"))
+	assert.Contains(t, pager, "page=2") // next
+	assert.Contains(t, pager, "page=3") // last
+}
+
+// Tables that fit on a single page do not show a pager.
+func TestDraw_SinglePage(t *testing.T) {
+
+	table := newTestTable().UsePageSize(10)
+
+	result, err := table.DrawViewString()
+
+	require.NoError(t, err)
+	assert.NotContains(t, result, "grid-pager")
+}
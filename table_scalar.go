@@ -0,0 +1,107 @@
+package table
+
+import (
+	"strconv"
+
+	"github.com/benpate/form"
+	"github.com/benpate/rosetta/list"
+	"github.com/benpate/rosetta/mapof"
+	"github.com/benpate/rosetta/schema"
+)
+
+/******************************************
+ * Scalar Tables
+ ******************************************/
+
+// scalarField is the name of the value in each row of a scalar table (such as
+// a list of tags or email addresses).  Scalar tables have a single column with
+// an empty path, but browsers never post inputs without a name, so the table
+// stages each row as an object with its value in this field.
+const scalarField = "value"
+
+// isScalarElement returns TRUE if each row of a table is a single value (such
+// as a string or a number) instead of an object
+func isScalarElement(element schema.Element) bool {
+
+	switch element.(type) {
+	case schema.String, schema.Integer, schema.Number, schema.Boolean:
+		return true
+	}
+
+	return false
+}
+
+// newRowSchema returns the schema of each row in the table.  The rows of a
+// scalar table are objects with their value in scalarField (see newRowValue).
+func newRowSchema(tableElement schema.Array) schema.Schema {
+
+	if isScalarElement(tableElement.Items) {
+		return schema.New(schema.Object{
+			Properties: schema.ElementMap{scalarField: tableElement.Items},
+		})
+	}
+
+	return schema.New(tableElement.Items)
+}
+
+// newRowValue returns a row as it is drawn and staged: rows of scalar tables
+// are wrapped in an object, and all other rows are returned unchanged.
+func newRowValue(tableElement schema.Array, rowValue any) any {
+
+	if isScalarElement(tableElement.Items) {
+		return mapof.Any{scalarField: derefValue(rowValue)}
+	}
+
+	return rowValue
+}
+
+// getRow returns the row at the requested index, ready to be staged (see newRowValue)
+func (widget Table) getRow(tableElement schema.Array, rowIndex int) (any, error) {
+
+	rowValue, err := widget.Schema.Get(widget.Object, list.ByDot(widget.Path, strconv.Itoa(rowIndex)).String())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newRowValue(tableElement, rowValue), nil
+}
+
+// fieldPath returns the path (from widget.Object) to one field in a row.  The
+// value of a scalar row is written at the row's index directly.
+func (widget Table) fieldPath(tableElement schema.Array, rowIndex int, path string) string {
+
+	if isScalarElement(tableElement.Items) && (path == scalarField) {
+		return list.ByDot(widget.Path, strconv.Itoa(rowIndex)).String()
+	}
+
+	return list.ByDot(widget.Path, strconv.Itoa(rowIndex), path).String()
+}
+
+// scalarColumns returns a copy of the table where (in a scalar table) every
+// column with an empty path uses scalarField instead, so that its values are
+// drawn, posted, and validated like any other field.  Other tables are
+// returned unchanged.  The Form is copied, so the caller's Form never changes.
+func (widget Table) scalarColumns() Table {
+
+	tableElement, err := widget.getTableElement()
+
+	if (err != nil) || (widget.Form == nil) || !isScalarElement(tableElement.Items) {
+		return widget
+	}
+
+	columns := *widget.Form
+	columns.Children = make([]form.Element, len(widget.Form.Children))
+
+	for index, field := range widget.Form.Children {
+
+		if field.Path == "" {
+			field.Path = scalarField
+		}
+
+		columns.Children[index] = field
+	}
+
+	widget.Form = &columns
+	return widget
+}
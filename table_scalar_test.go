@@ -0,0 +1,173 @@
+package table
+
+import (
+	"bytes"
+	"html"
+	"testing"
+
+	"github.com/benpate/form"
+	"github.com/benpate/rosetta/mapof"
+	"github.com/benpate/rosetta/schema"
+	"github.com/benpate/rosetta/sliceof"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scalarDatabase stores lists of single values, instead of lists of objects
+type scalarDatabase struct {
+	Tags  sliceof.String
+	Ports sliceof.Int
+}
+
+// GetPointer implements the schema.PointerGetter interface.
+func (d *scalarDatabase) GetPointer(name string) (any, bool) {
+
+	switch name {
+	case "tags":
+		return &d.Tags, true
+	case "ports":
+		return &d.Ports, true
+	}
+
+	return nil, false
+}
+
+// newScalarTable returns a single-column table of tags (or ports, if path is "ports")
+func newScalarTable(path string) Table {
+
+	s := schema.Schema{
+		Element: schema.Object{
+			Properties: schema.ElementMap{
+				"tags":  schema.Array{Items: schema.String{Required: true}},
+				"ports": schema.Array{Items: schema.Integer{}},
+			},
+		},
+	}
+
+	f := form.Element{
+		Type: "layout-vertical",
+		Children: []form.Element{
+			{Type: "text", Label: "Value"},
+		},
+	}
+
+	db := &scalarDatabase{
+		Tags:  sliceof.String{"red", "green", "blue"},
+		Ports: sliceof.Int{80, 443},
+	}
+
+	return New(&s, &f, db, path, testIconProvider{}, "http://localhost/table")
+}
+
+func TestDraw_Scalar(t *testing.T) {
+
+	result := drawGroupTable(t, newScalarTable("tags"), "http://x")
+
+	assertOrder(t, result, ">red<", ">green<", ">blue<")
+	assert.Contains(t, result, `hx-get="http://localhost/table?edit=1&focus=0"`)
+	assert.Contains(t, result, `hx-post="http://localhost/table?delete=2&version=`)
+}
+
+func TestDraw_ScalarEdit(t *testing.T) {
+
+	table := newScalarTable("tags")
+
+	// Scalar values are edited (and posted) in a field named "value"
+	assert.Contains(t, drawGroupTable(t, table, "http://x?edit=1"), `name="value" value="green"`)
+	assert.Contains(t, drawGroupTable(t, table, "http://x?add=true"), `name="value" value=""`)
+
+	// The caller's Form keeps its empty path
+	assert.Empty(t, table.Form.Children[0].Path)
+}
+
+func TestDo_Scalar(t *testing.T) {
+
+	table := newScalarTable("tags")
+	db := table.Object.(*scalarDatabase)
+
+	require.NoError(t, table.Do(mustURL(t, "http://x?add=true"), map[string]any{"value": "yellow"}))
+	require.NoError(t, table.Do(mustURL(t, "http://x?edit=0"), withVersion(t, table, 0, map[string]any{"value": "purple"})))
+	require.NoError(t, table.Do(mustURL(t, "http://x?delete=1&version="+testRowVersion(t, table, 1)), nil))
+
+	assert.Equal(t, sliceof.String{"purple", "blue", "yellow"}, db.Tags)
+}
+
+func TestDo_ScalarInteger(t *testing.T) {
+
+	table := newScalarTable("ports").AllowDuplicate()
+	db := table.Object.(*scalarDatabase)
+
+	require.NoError(t, table.Do(mustURL(t, "http://x?cell=1&focus=0"), withVersion(t, table, 1, map[string]any{"value": "8443"})))
+	require.NoError(t, table.Do(mustURL(t, "http://x?duplicate=0"), nil))
+
+	assert.Equal(t, sliceof.Int{80, 80, 8443}, db.Ports)
+}
+
+func TestDrawErrors_Scalar(t *testing.T) {
+
+	table := newScalarTable("tags")
+	params := mustURL(t, "http://x?edit=0")
+	submitted := withVersion(t, table, 0, map[string]any{"value": ""})
+
+	err := table.Do(params, submitted)
+	require.Error(t, err)
+
+	var buffer bytes.Buffer
+	require.NoError(t, table.DrawErrors(params, submitted, err, &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assertOrder(t, result, `name="value" value=""`, `<div class="grid-error">Value is required</div>`)
+	assert.Equal(t, "red", table.Object.(*scalarDatabase).Tags[0])
+}
+
+func TestDraw_ScalarSearch(t *testing.T) {
+
+	result := drawGroupTable(t, newScalarTable("tags").AllowSearch().AllowSort(), "http://x?q=re&sort=value&dir=desc")
+
+	assertOrder(t, result, "<mark>re</mark>d", "g<mark>re</mark>en")
+	assert.NotContains(t, result, ">blue<")
+}
+
+func TestDo_ScalarSubTable(t *testing.T) {
+
+	s := schema.Schema{
+		Element: schema.Object{
+			Properties: schema.ElementMap{
+				"data": schema.Array{
+					Items: schema.Object{
+						Properties: schema.ElementMap{
+							"name": schema.String{},
+							"tags": schema.Array{Items: schema.String{}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	f := form.Element{
+		Type: "layout-vertical",
+		Children: []form.Element{
+			{Type: "text", Label: "Name", Path: "name"},
+			{Type: SubTableType, Label: "Tags", Path: "tags", Children: []form.Element{
+				{Type: "text", Label: "Tag"},
+			}},
+		},
+	}
+
+	db := &testDatabase{
+		Data: sliceof.Object[mapof.Any]{
+			mapof.Any{"name": "John Connor", "tags": &sliceof.String{"leader"}},
+			mapof.Any{"name": "Sarah Connor"},
+		},
+	}
+
+	table := New(&s, &f, db, "data", testIconProvider{}, "http://localhost/table")
+
+	// Each row can contain its own list of values, which starts empty
+	require.NoError(t, table.Do(mustURL(t, "http://x?path=data.0.tags&add=true"), map[string]any{"value": "survivor"}))
+	require.NoError(t, table.Do(mustURL(t, "http://x?path=data.1.tags&add=true"), map[string]any{"value": "mother"}))
+
+	assert.Equal(t, &sliceof.String{"leader", "survivor"}, db.Data[0]["tags"])
+	assert.Contains(t, drawGroupTable(t, table, "http://x"), `hx-get="http://localhost/table?edit=0&focus=0&path=data.1.tags" hx-trigger="click">mother</td>`)
+}
@@ -0,0 +1,226 @@
+package table
+
+import (
+	stdhtml "html"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/benpate/derp"
+	"github.com/benpate/form"
+	"github.com/benpate/html"
+	"github.com/benpate/rosetta/null"
+	"github.com/benpate/rosetta/schema"
+)
+
+/******************************************
+ * Search Methods
+ ******************************************/
+
+// searchRows removes rows that do not match the current search text, keeping the
+// remaining rows in their existing order.  Rows are matched against the text that
+// each column displays (so select fields match their labels, not their raw codes).
+// The row being edited is always kept, so that it cannot vanish mid-edit.
+func (widget Table) searchRows(rowSchema *schema.Schema, rows []any, rowOrder []int, editRow null.Int) ([]int, error) {
+
+	const location = "table.Widget.searchRows"
+
+	search := strings.TrimSpace(widget.view.Search)
+
+	if !widget.CanSearch || (search == "") {
+		return rowOrder, nil
+	}
+
+	f := form.New(*rowSchema, *widget.Form)
+	result := make([]int, 0, len(rowOrder))
+
+	for _, rowIndex := range rowOrder {
+
+		if editRow.IsPresent() && (editRow.Int() == rowIndex) {
+			result = append(result, rowIndex)
+			continue
+		}
+
+		for _, field := range widget.Form.Children {
+
+			// Nested tables are not searched
+			if isSubTable(field) {
+				continue
+			}
+
+			cellHTML, err := widget.viewCell(&f, field, rows[rowIndex])
+
+			if err != nil {
+				return nil, derp.Wrap(err, location, "Rendering field", field)
+			}
+
+			if start, _ := indexFold(htmlText(cellHTML), search); start >= 0 {
+				result = append(result, rowIndex)
+				break
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// viewCell renders the VIEW ONLY representation of a single cell into a string
+func (widget Table) viewCell(f *form.Form, field form.Element, rowValue any) (string, error) {
+
+	const location = "table.Widget.viewCell"
+
+	// Columns with a format are drawn by the table instead of the form
+	if cellHTML, ok := widget.formatCell(f, field, rowValue); ok {
+		return cellHTML, nil
+	}
+
+	b := html.New()
+
+	if err := field.View(f, widget.LookupProvider, rowValue, b); err != nil {
+		return "", derp.Wrap(err, location, "Rendering field", field)
+	}
+
+	return string(b.Bytes()), nil
+}
+
+// drawSearch writes the free-text search box that appears above the table.
+// Searching starts again from the first page of results.
+func (widget Table) drawSearch(b *html.Builder) {
+
+	b.Div().Class("grid-search")
+	b.Empty("input").
+		Type("search").
+		Attr("name", "q").
+		Attr("value", widget.view.Search).
+		Attr("placeholder", widget.message(MessageSearch)).
+		Attr("aria-label", widget.message(MessageSearch)).
+		Data("hx-get", widget.getURL("search", 0, 0)).
+		Data("hx-trigger", "change, search").
+		Close()
+	b.Close() // Div
+}
+
+/******************************************
+ * Text Helpers
+ ******************************************/
+
+// htmlText returns the plain text of an HTML fragment, removing all tags and
+// decoding all entities.
+func htmlText(value string) string {
+
+	var result strings.Builder
+
+	for value != "" {
+
+		start := strings.IndexByte(value, '<')
+
+		if start < 0 {
+			result.WriteString(value)
+			break
+		}
+
+		result.WriteString(value[:start])
+		end := strings.IndexByte(value[start:], '>')
+
+		if end < 0 {
+			break
+		}
+
+		value = value[start+end+1:]
+	}
+
+	return stdhtml.UnescapeString(result.String())
+}
+
+// highlightHTML wraps every case-insensitive match of the search text in a <mark>
+// tag.  Only text between tags is searched, so that tag names and attributes are
+// never changed.
+func highlightHTML(value string, search string) string {
+
+	if search == "" {
+		return value
+	}
+
+	var result strings.Builder
+
+	for value != "" {
+
+		// Everything up to the next tag is text that may be highlighted
+		start := strings.IndexByte(value, '<')
+
+		if start < 0 {
+			start = len(value)
+		}
+
+		result.WriteString(highlightText(stdhtml.UnescapeString(value[:start]), search))
+		value = value[start:]
+
+		// Copy the tag itself unchanged
+		end := strings.IndexByte(value, '>')
+
+		if end < 0 {
+			result.WriteString(value)
+			break
+		}
+
+		result.WriteString(value[:end+1])
+		value = value[end+1:]
+	}
+
+	return result.String()
+}
+
+// highlightText escapes a plain-text string, wrapping every case-insensitive
+// match of the search text in a <mark> tag.
+func highlightText(value string, search string) string {
+
+	var result strings.Builder
+
+	for {
+		start, end := indexFold(value, search)
+
+		if start < 0 {
+			result.WriteString(stdhtml.EscapeString(value))
+			return result.String()
+		}
+
+		result.WriteString(stdhtml.EscapeString(value[:start]))
+		result.WriteString("<mark>")
+		result.WriteString(stdhtml.EscapeString(value[start:end]))
+		result.WriteString("</mark>")
+		value = value[end:]
+	}
+}
+
+// indexFold returns the byte positions of the first case-insensitive match of
+// search within value, or (-1, -1) if there is no match.  Positions are measured
+// in the original string, even when upper and lower case runes differ in length.
+func indexFold(value string, search string) (int, int) {
+
+	if search == "" {
+		return -1, -1
+	}
+
+	for start := range value {
+
+		end := start
+		matched := true
+
+		for _, searchRune := range search {
+
+			valueRune, size := utf8.DecodeRuneInString(value[end:])
+
+			if (size == 0) || !strings.EqualFold(string(valueRune), string(searchRune)) {
+				matched = false
+				break
+			}
+
+			end += size
+		}
+
+		if matched {
+			return start, end
+		}
+	}
+
+	return -1, -1
+}
@@ -0,0 +1,153 @@
+package table
+
+import (
+	"bytes"
+	"html"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/******************************************
+ * Text Helpers
+ ******************************************/
+
+func TestIndexFold(t *testing.T) {
+
+	check := func(value string, search string, start int, end int) {
+		actualStart, actualEnd := indexFold(value, search)
+		assert.Equal(t, start, actualStart, "indexFold(%q, %q)", value, search)
+		assert.Equal(t, end, actualEnd, "indexFold(%q, %q)", value, search)
+	}
+
+	check("John Connor", "connor", 5, 11)
+	check("John Connor", "JOHN", 0, 4)
+	check("John Connor", "Sarah", -1, -1)
+	check("John Connor", "", -1, -1)
+	check("", "x", -1, -1)
+	check("Straße", "SSE", -1, -1) // no multi-rune folding
+	check("ÉCOLE", "école", 0, 6)  // multi-byte runes are measured in the original string
+}
+
+func TestHTMLText(t *testing.T) {
+	assert.Equal(t, "John Connor", htmlText("John Connor"))
+	assert.Equal(t, "Tom & Jerry", htmlText(`<span class="x">Tom &amp; Jerry</span>`))
+	assert.Equal(t, "ab", htmlText("a<br>b"))
+	assert.Equal(t, "a", htmlText("a<unterminated"))
+}
+
+func TestHighlightHTML(t *testing.T) {
+
+	assert.Equal(t, "John <mark>Connor</mark>", highlightHTML("John Connor", "connor"))
+	assert.Equal(t, "<mark>a</mark>b<mark>A</mark>", highlightHTML("abA", "a"))
+
+	// Tags and attributes are never highlighted, only the text between them
+	assert.Equal(t, `<span title="connor"><mark>Connor</mark></span>`, highlightHTML(`<span title="connor">Connor</span>`, "connor"))
+
+	// Entities are matched by their decoded text, and stay escaped
+	assert.Equal(t, "Tom <mark>&amp;</mark> Jerry", highlightHTML("Tom &amp; Jerry", "&"))
+
+	// No search means no change
+	assert.Equal(t, "John Connor", highlightHTML("John Connor", ""))
+}
+
+/******************************************
+ * Draw() - Searching
+ ******************************************/
+
+func TestDraw_Search(t *testing.T) {
+
+	table := newTestTable().AllowSearch()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?q=sarah"), &buffer)
+
+	require.NoError(t, err)
+	result := buffer.String()
+	assert.NotContains(t, result, "John Connor")
+	assert.Contains(t, result, "<mark>Sarah</mark> Connor") // matches are highlighted
+	assert.Contains(t, result, "grid-search")
+	assert.Contains(t, result, `value="sarah"`) // search box shows the current search
+}
+
+// Searching matches every column, not just the first
+func TestDraw_SearchOtherColumn(t *testing.T) {
+
+	table := newTestTable().AllowSearch()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?q=45"), &buffer)
+
+	require.NoError(t, err)
+	result := buffer.String()
+	assert.NotContains(t, result, "John Connor")
+	assert.Contains(t, result, "Sarah Connor")
+}
+
+// Every link carries the search forward, so it survives edit/cancel round trips.
+func TestDraw_SearchCarriedIntoLinks(t *testing.T) {
+
+	table := newTestTable().AllowSearch()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?q=Sarah+C&edit=1"), &buffer)
+
+	require.NoError(t, err)
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, "edit=1&focus=0&q=Sarah+C")         // form hx-post
+	assert.Contains(t, result, "http://localhost/table?q=Sarah+C") // cancel button
+	assert.NotContains(t, result, "grid-search")                   // search box is hidden while editing
+}
+
+// The row being edited stays visible, even if it does not match the search.
+func TestDraw_SearchKeepsEditRow(t *testing.T) {
+
+	table := newTestTable().AllowSearch()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?q=sarah&edit=0"), &buffer)
+
+	require.NoError(t, err)
+	assert.Contains(t, buffer.String(), `value="John Connor"`)
+}
+
+func TestDraw_SearchNoMatches(t *testing.T) {
+
+	table := newTestTable().AllowSearch()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?q=skynet"), &buffer)
+
+	require.NoError(t, err)
+	result := buffer.String()
+	assert.NotContains(t, result, "Connor")
+	assert.Equal(t, 0, strings.Count(result, "grid-row"))
+}
+
+// A search is ignored unless the table allows searching.
+func TestDraw_SearchNotAllowed(t *testing.T) {
+
+	table := newTestTable()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?q=sarah"), &buffer)
+
+	require.NoError(t, err)
+	result := buffer.String()
+	assert.Contains(t, result, "John Connor")
+	assert.NotContains(t, result, "<mark>")
+	assert.NotContains(t, result, "grid-search")
+}
+
+func TestDraw_SearchFieldError(t *testing.T) {
+
+	table := newTestTable().AllowSearch()
+	breakForm(&table)
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?q=sarah"), &buffer)
+
+	require.Error(t, err)
+}
@@ -0,0 +1,342 @@
+package table
+
+import (
+	stdhtml "html"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/benpate/derp"
+	"github.com/benpate/form"
+	"github.com/benpate/html"
+	"github.com/benpate/rosetta/convert"
+	"github.com/benpate/rosetta/list"
+	"github.com/benpate/rosetta/schema"
+)
+
+// selectedField is the name of the checkbox that selects each row.  Bulk actions
+// post the ID and version token of every selected row in this form value.
+const selectedField = "selected"
+
+/******************************************
+ * Bulk Action Methods
+ ******************************************/
+
+// SelectedRow identifies a row that was chosen for a bulk action, along with
+// the version token that the row had when it was selected (see IsConflict).
+type SelectedRow struct {
+	Index   int    // Current index of the row in the table
+	Version string // Version token that was drawn with the row's checkbox
+}
+
+// DoBulkDelete removes every requested row from the table in a single pass.
+// Rows are removed from the highest index down, so that removing one row never
+// changes the index of another.  Either every row is removed, or (if any row has
+// changed since it was selected, or the table would end up shorter than its
+// schema allows) none of them are.
+func (widget Table) DoBulkDelete(rows []SelectedRow) error {
+
+	const location = "table.Widget.DoBulkDelete"
+
+	if !widget.CanDelete {
+		return derp.BadRequest(location, widget.message(MessageDeleteNotAllowed), widget.Path)
+	}
+
+	tableData, err := widget.Schema.Get(widget.Object, widget.Path)
+
+	if err != nil {
+		return derp.Wrap(err, location, "Locating table data", widget.Path)
+	}
+
+	length := convert.SliceLength(tableData)
+	indexes := make([]int, 0, len(rows))
+
+	// Check every row before removing any of them
+	for _, row := range rows {
+
+		if (row.Index < 0) || (row.Index >= length) {
+			return derp.BadRequest(location, widget.message(MessageRowNotFound), widget.Path, row.Index, length)
+		}
+
+		if err := widget.checkVersion(row.Index, row.Version); err != nil {
+			return derp.Wrap(err, location, "Checking row version", widget.Path, row.Index)
+		}
+
+		indexes = append(indexes, row.Index)
+	}
+
+	if err := widget.deleteRows(indexes); err != nil {
+		return derp.Wrap(err, location, "Removing rows", widget.Path)
+	}
+
+	return nil
+}
+
+// DoBulkEdit sets the same value into one field of every requested row.  Like
+// DoEdit, the update is atomic: every changed row is validated before anything
+// is written, so a value that is not valid for any one row (or a row that has
+// changed since it was selected) leaves all of them unchanged.
+func (widget Table) DoBulkEdit(rows []SelectedRow, path string, value any) error {
+
+	const location = "table.Widget.DoBulkEdit"
+
+	widget = widget.scalarColumns()
+
+	if !widget.CanEdit {
+		return derp.BadRequest(location, widget.message(MessageEditNotAllowed), widget.Path)
+	}
+
+	// Only fields that can be edited in the Form can be changed in bulk
+	if !slices.ContainsFunc(formFields(*widget.Form), func(field form.Element) bool { return field.Path == path }) {
+		return derp.BadRequest(location, widget.message(MessageFieldNotEditable), widget.Path, path)
+	}
+
+	tableElement, err := widget.getTableElement()
+
+	if err != nil {
+		return derp.Wrap(err, location, "Getting table element")
+	}
+
+	tableData, err := widget.Schema.Get(widget.Object, widget.Path)
+
+	if err != nil {
+		return derp.Wrap(err, location, "Locating table data", widget.Path)
+	}
+
+	length := convert.SliceLength(tableData)
+	rowSchema := newRowSchema(tableElement)
+
+	// Stage and validate the change on a copy of every row before writing any of them
+	staged := make([]any, len(rows))
+
+	for stagedIndex, selected := range rows {
+
+		index := selected.Index
+
+		if (index < 0) || (index >= length) {
+			return derp.BadRequest(location, widget.message(MessageRowNotFound), widget.Path, index, length)
+		}
+
+		if err := widget.checkVersion(index, selected.Version); err != nil {
+			return derp.Wrap(err, location, "Checking row version", widget.Path, index)
+		}
+
+		rowPath := list.ByDot(widget.Path, strconv.Itoa(index)).String()
+		rowValue, err := widget.getRow(tableElement, index)
+
+		if err != nil {
+			return derp.Wrap(err, location, "Getting row data", rowPath)
+		}
+
+		row := cloneRow(rowValue)
+
+		if err := rowSchema.Set(&row, path, value); err != nil {
+			return derp.Wrap(err, location, "Setting value in row", rowPath, path)
+		}
+
+		row = completeRow(rowSchema.Element, row)
+
+		if _, err := rowSchema.Validate(&row); err != nil {
+			return derp.Wrap(err, location, "Validating row", rowPath)
+		}
+
+		if staged[stagedIndex], err = rowSchema.Get(&row, path); err != nil {
+			return derp.Wrap(err, location, "Getting staged value", rowPath, path)
+		}
+	}
+
+	// Write the staged values back into the table
+	for stagedIndex, selected := range rows {
+
+		fieldPath := widget.fieldPath(tableElement, selected.Index, path)
+
+		if err := widget.Schema.Set(widget.Object, fieldPath, staged[stagedIndex]); err != nil {
+			return derp.Wrap(err, location, "Setting value in table", fieldPath)
+		}
+	}
+
+	return nil
+}
+
+// selectedRows returns every row selected in the submitted data.  Rows that
+// no longer exist (such as keys for rows that someone else has already
+// deleted) return a NotFound error, so that a bulk action is never applied to
+// only some of the rows that the user selected.
+func (widget Table) selectedRows(data map[string]any) ([]SelectedRow, error) {
+
+	const location = "table.Widget.selectedRows"
+
+	values := convert.SliceOfString(data[selectedField])
+	result := make([]SelectedRow, 0, len(values))
+
+	for _, value := range values {
+
+		rowID, version := parseSelected(value)
+		index, ok, err := widget.lookupRow(rowID)
+
+		if err != nil {
+			return nil, derp.Wrap(err, location, "Locating selected row", widget.Path, rowID)
+		}
+
+		if !ok {
+			return nil, derp.NotFound(location, widget.message(MessageRowNotFound), widget.Path, rowID)
+		}
+
+		result = append(result, SelectedRow{Index: index, Version: version})
+	}
+
+	return result, nil
+}
+
+// selectedValue returns the value of a row's checkbox: the row's ID, followed
+// by a colon and the row's version token (see parseSelected)
+func (widget Table) selectedValue(rowIndex int) string {
+
+	version := ""
+	if (rowIndex >= 0) && (rowIndex < len(widget.rowVersions)) {
+		version = widget.rowVersions[rowIndex]
+	}
+
+	// Keys may contain colons, so the row ID is escaped
+	return url.QueryEscape(widget.rowID(rowIndex)) + ":" + version
+}
+
+// isSelected returns TRUE if the row was selected in the submitted data
+func (widget Table) isSelected(rowIndex int) bool {
+
+	rowID := widget.rowID(rowIndex)
+
+	return slices.ContainsFunc(widget.selected, func(value string) bool {
+		selectedID, _ := parseSelected(value)
+		return selectedID == rowID
+	})
+}
+
+/******************************************
+ * Drawing Methods
+ ******************************************/
+
+// drawSelectHeader writes the header cell for the selection column, which
+// contains a checkbox that selects (or clears) every displayed row.
+func (widget Table) drawSelectHeader(b *html.Builder) {
+
+	b.Container("th").Class("grid-cell", "grid-select").Attr("scope", "col")
+	b.Empty("input").
+		Type("checkbox").
+		Attr("aria-label", widget.message(MessageSelectAllRows)).
+		Attr("onclick", "this.closest('table').querySelectorAll('input[name="+selectedField+"]').forEach(checkbox => checkbox.checked = this.checked)").
+		Close()
+	b.Close() // TH
+}
+
+// drawSelectCell writes the checkbox that selects a single row
+func (widget Table) drawSelectCell(rowIndex int, b *html.Builder) {
+
+	b.TD().Class("grid-cell", "grid-select")
+	checkbox := b.Empty("input").
+		Type("checkbox").
+		Attr("aria-label", widget.rowLabel(MessageSelectRow, rowIndex)).
+		Attr("name", selectedField).
+		Attr("value", widget.selectedValue(rowIndex))
+
+	if widget.isSelected(rowIndex) {
+		checkbox.Attr("checked", "true")
+	}
+
+	checkbox.Close()
+	b.Close() // TD
+}
+
+// drawBulkActions writes the controls that act on every selected row
+func (widget Table) drawBulkActions(permissions rowPermissions, b *html.Builder) {
+
+	b.Div().Class("grid-bulk-actions")
+
+	// Choosing a column opens an editor that changes it in every selected row
+	if permissions.Edit {
+		b.Container("select").
+			Attr("name", "column").
+			Data("hx-get", widget.getURL("bulk-edit", 0, -1)).
+			Data("hx-include", "closest .grid").
+			Data("hx-trigger", "change").
+			Attr("aria-label", widget.message(MessageChangeSelectedRows))
+
+		b.Container("option").Attr("value", "").InnerText(widget.message(MessageChangeSelected)).Close()
+
+		for column := range widget.Form.Children {
+			if field, ok := widget.editableField(column); ok {
+				b.Container("option").Attr("value", strconv.Itoa(column)).InnerText(field.Label).Close()
+			}
+		}
+
+		b.Close() // Select
+		b.Space()
+	}
+
+	if permissions.Delete {
+		b.Button().
+			Type("button").
+			Class("link").
+			Data("hx-post", widget.getURL("bulk-delete", 0, 0)).
+			Data("hx-include", "closest .grid").
+			Data("hx-confirm", widget.message(MessageConfirmDeleteSelected)).
+			InnerHTML(widget.Icons.Get("delete") + " " + stdhtml.EscapeString(widget.message(MessageDeleteSelected)))
+		b.Close() // Button
+	}
+
+	b.Close() // Div
+}
+
+// drawBulkEdit writes the editor for a single column, whose value is applied to
+// every selected row when the form is submitted.
+func (widget Table) drawBulkEdit(rowSchema *schema.Schema, field form.Element, b *html.Builder) error {
+
+	const location = "table.Widget.drawBulkEdit"
+
+	f := form.New(*rowSchema, *widget.Form)
+
+	// The editor starts empty, unless the user has already submitted a value
+	var value any
+	if widget.submitted != nil {
+		value = widget.submittedRow(rowSchema, nil)
+	}
+
+	b.Div().Class("grid-bulk-edit")
+	b.Span().InnerText(field.Label).Close()
+	b.Space()
+
+	if err := focusField(field).Edit(&f, widget.LookupProvider, value, b.SubTree()); err != nil {
+		return derp.Wrap(err, location, "Rendering field", field)
+	}
+
+	widget.drawFieldError(field.Path, b.SubTree())
+
+	if widget.rowError != "" {
+		b.Div().Class("grid-error").InnerText(widget.rowError).Close()
+	}
+
+	b.Button().Type("submit").Class("text-green").InnerHTML(widget.Icons.Get("save") + " " + stdhtml.EscapeString(widget.message(MessageApplyToSelected))).Close()
+	b.Space()
+	widget.drawCancelButton(b.SubTree())
+	b.Close() // Div
+
+	return nil
+}
+
+/******************************************
+ * Helper Functions
+ ******************************************/
+
+// parseSelected splits the value of a row's checkbox into the row's ID and
+// its version token (see selectedValue)
+func parseSelected(value string) (string, string) {
+
+	escapedID, version, _ := strings.Cut(value, ":")
+
+	if rowID, err := url.QueryUnescape(escapedID); err == nil {
+		return rowID, version
+	}
+
+	return escapedID, version
+}
@@ -0,0 +1,334 @@
+package table
+
+import (
+	"bytes"
+	"html"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/benpate/derp"
+	"github.com/benpate/rosetta/mapof"
+	"github.com/benpate/rosetta/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSelected returns the requested rows along with their current version tokens
+func testSelected(t *testing.T, table Table, indexes ...int) []SelectedRow {
+	t.Helper()
+
+	result := make([]SelectedRow, len(indexes))
+
+	for position, index := range indexes {
+		result[position] = SelectedRow{Index: index, Version: testRowVersion(t, table, index)}
+	}
+
+	return result
+}
+
+// testSelectedValue returns the checkbox value that selects a row in its current version
+func testSelectedValue(t *testing.T, table Table, rowID string, rowIndex int) string {
+	t.Helper()
+	return rowID + ":" + testRowVersion(t, table, rowIndex)
+}
+
+/******************************************
+ * DoBulkDelete()
+ ******************************************/
+
+func TestDoBulkDelete(t *testing.T) {
+	table := newMoveTable()
+	require.NoError(t, table.DoBulkDelete(testSelected(t, table, 0, 2, 3)))
+	assert.Equal(t, []string{"B"}, testNames(table))
+}
+
+// Rows are removed from the highest index down, so the order of the request does not matter
+func TestDoBulkDelete_Unordered(t *testing.T) {
+	table := newMoveTable()
+	require.NoError(t, table.DoBulkDelete(testSelected(t, table, 1, 3, 1, 0)))
+	assert.Equal(t, []string{"C"}, testNames(table))
+}
+
+func TestDoBulkDelete_NotAllowed(t *testing.T) {
+	table := newMoveTable()
+	table.CanDelete = false
+	require.Error(t, table.DoBulkDelete(testSelected(t, table, 0, 1)))
+	assert.Equal(t, []string{"A", "B", "C", "D"}, testNames(table))
+}
+
+func TestDoBulkDelete_OutOfRange(t *testing.T) {
+	table := newMoveTable()
+	require.Error(t, table.DoBulkDelete(append(testSelected(t, table, 0), SelectedRow{Index: 4})))
+	assert.Equal(t, []string{"A", "B", "C", "D"}, testNames(table))
+}
+
+// A row that has changed since it was selected stops every row from being removed
+func TestDoBulkDelete_StaleVersion(t *testing.T) {
+	table := newMoveTable()
+	db := table.Object.(*testDatabase)
+	selected := testSelected(t, table, 0, 1)
+
+	db.Data[1]["name"] = "X"
+
+	err := table.DoBulkDelete(selected)
+	require.Error(t, err)
+	assert.True(t, IsConflict(err))
+	assert.Equal(t, []string{"A", "X", "C", "D"}, testNames(table))
+}
+
+func TestDoBulkDelete_MinLength(t *testing.T) {
+	table := newMoveTable()
+	element := table.Schema.Element.(schema.Object)
+	array := element.Properties["data"].(schema.Array)
+	array.MinLength = 2
+	table.Schema = &schema.Schema{Element: schema.Object{Properties: schema.ElementMap{"data": array}}}
+
+	// The whole batch is refused, not just the rows that go past the limit
+	require.Error(t, table.DoBulkDelete(testSelected(t, table, 0, 1, 2)))
+	assert.Equal(t, []string{"A", "B", "C", "D"}, testNames(table))
+
+	require.NoError(t, table.DoBulkDelete(testSelected(t, table, 0, 1)))
+	assert.Equal(t, []string{"C", "D"}, testNames(table))
+}
+
+func TestDo_BulkDelete(t *testing.T) {
+	table := newMoveTable()
+	selected := []string{testSelectedValue(t, table, "1", 1), testSelectedValue(t, table, "2", 2)}
+	require.NoError(t, table.Do(mustURL(t, "http://x?bulk=delete"), map[string]any{"selected": selected}))
+	assert.Equal(t, []string{"A", "D"}, testNames(table))
+
+	// A single selected row is posted as a single value
+	require.NoError(t, table.Do(mustURL(t, "http://x?bulk=delete"), map[string]any{"selected": testSelectedValue(t, table, "0", 0)}))
+	assert.Equal(t, []string{"D"}, testNames(table))
+}
+
+func TestDo_BulkDeleteKeys(t *testing.T) {
+	table := newKeyTable()
+	db := table.Object.(*testDatabase)
+
+	selected := []string{testSelectedValue(t, table, "abc", 2), testSelectedValue(t, table, "1", 0)}
+
+	// Rows that no longer exist stop every row from being removed
+	err := table.Do(mustURL(t, "http://x?bulk=delete"), map[string]any{"selected": append(selected, "missing:x")})
+	require.Error(t, err)
+	assert.True(t, derp.IsNotFound(err))
+	require.Equal(t, 3, len(db.Data))
+
+	// Rows without a version token are refused, too
+	err = table.Do(mustURL(t, "http://x?bulk=delete"), map[string]any{"selected": append(selected, "0")})
+	require.Error(t, err)
+	assert.True(t, IsConflict(err))
+	require.Equal(t, 3, len(db.Data))
+
+	require.NoError(t, table.Do(mustURL(t, "http://x?bulk=delete"), map[string]any{"selected": selected}))
+	require.Equal(t, 1, len(db.Data))
+	assert.Equal(t, "Sarah Connor", db.Data[0]["name"])
+}
+
+// Checkbox values carry an escaped row ID and the row's version token
+func TestParseSelected(t *testing.T) {
+
+	rowID, version := parseSelected(url.QueryEscape("urn:a#1") + ":v:2")
+	assert.Equal(t, "urn:a#1", rowID)
+	assert.Equal(t, "v:2", version)
+
+	rowID, version = parseSelected("3")
+	assert.Equal(t, "3", rowID)
+	assert.Empty(t, version)
+}
+
+func TestDo_BulkNothingSelected(t *testing.T) {
+	table := newMoveTable()
+	require.NoError(t, table.Do(mustURL(t, "http://x?bulk=delete"), map[string]any{}))
+	assert.Equal(t, []string{"A", "B", "C", "D"}, testNames(table))
+}
+
+/******************************************
+ * Drawing
+ ******************************************/
+
+func TestDraw_Select(t *testing.T) {
+	table := newMoveTable()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+	assert.NotContains(t, buffer.String(), "grid-select") // selection is optional
+
+	table = table.AllowSelect()
+	buffer.Reset()
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Equal(t, 5, strings.Count(result, `class="grid-cell grid-select"`)) // header + four rows
+	assert.Contains(t, result, `<input type="checkbox" aria-label="select row 3" name="selected" value="`+testSelectedValue(t, table, "2", 2)+`">`)
+	assert.Contains(t, result, `hx-post="http://localhost/table?bulk=delete"`)
+	assert.Contains(t, result, `hx-include="closest .grid"`)
+}
+
+func TestDraw_SelectWhileEditing(t *testing.T) {
+	table := newMoveTable().AllowSelect()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x?edit=1"), &buffer))
+
+	result := buffer.String()
+	assert.NotContains(t, result, "grid-select")
+	assert.NotContains(t, result, "grid-bulk-actions")
+}
+
+func TestDraw_SelectWithoutDelete(t *testing.T) {
+	table := newMoveTable().AllowSelect()
+	table.CanDelete = false
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+	assert.NotContains(t, buffer.String(), "bulk=delete")
+}
+
+func TestAllowSelect(t *testing.T) {
+	table := newTestTable()
+
+	result := table.AllowSelect()
+
+	assert.True(t, result.CanSelect)
+	assert.False(t, table.CanSelect) // the original is left unchanged
+}
+
+/******************************************
+ * DoBulkEdit()
+ ******************************************/
+
+func TestDoBulkEdit(t *testing.T) {
+	table := newMoveTable()
+	db := table.Object.(*testDatabase)
+
+	require.NoError(t, table.DoBulkEdit(testSelected(t, table, 0, 2), "age", "99"))
+	assert.Equal(t, 99, db.Data[0]["age"])
+	assert.Equal(t, 2, db.Data[1]["age"])
+	assert.Equal(t, 99, db.Data[2]["age"])
+	assert.Equal(t, []string{"A", "B", "C", "D"}, testNames(table)) // other fields are unchanged
+}
+
+func TestDoBulkEdit_NotAllowed(t *testing.T) {
+	table := newMoveTable()
+	table.CanEdit = false
+	require.Error(t, table.DoBulkEdit(testSelected(t, table, 0), "age", 99))
+}
+
+// Only fields in the Form can be changed
+func TestDoBulkEdit_UnknownField(t *testing.T) {
+	table := newMoveTable()
+	require.Error(t, table.DoBulkEdit(testSelected(t, table, 0), "secret", "x"))
+}
+
+func TestDoBulkEdit_InvalidValue(t *testing.T) {
+	table := newConstrainedTable()
+	db := table.Object.(*testDatabase)
+
+	err := table.DoBulkEdit(testSelected(t, table, 0, 1), "age", "not-a-number")
+	require.Error(t, err)
+	assert.Equal(t, 20, db.Data[0]["age"])
+	assert.Equal(t, 45, db.Data[1]["age"])
+}
+
+// A row that fails validation stops every row from changing, even rows that were valid
+func TestDoBulkEdit_AllOrNothing(t *testing.T) {
+	table := newConstrainedTable()
+	db := table.Object.(*testDatabase)
+	db.Data = append(db.Data, mapof.Any{"age": 3}) // missing its required name
+
+	err := table.DoBulkEdit(testSelected(t, table, 0, 2), "age", 50)
+	require.Error(t, err)
+	assert.Equal(t, 20, db.Data[0]["age"])
+	assert.Equal(t, 3, db.Data[2]["age"])
+
+	require.Error(t, table.DoBulkEdit(append(testSelected(t, table, 0), SelectedRow{Index: 3}), "age", 50)) // out of range
+	assert.Equal(t, 20, db.Data[0]["age"])
+}
+
+// A row that has changed since it was selected stops every row from changing
+func TestDoBulkEdit_StaleVersion(t *testing.T) {
+	table := newMoveTable()
+	db := table.Object.(*testDatabase)
+	selected := testSelected(t, table, 0, 1)
+
+	db.Data[1]["name"] = "X"
+
+	err := table.DoBulkEdit(selected, "age", 50)
+	require.Error(t, err)
+	assert.True(t, IsConflict(err))
+	assert.Equal(t, 1, db.Data[0]["age"])
+}
+
+func TestDo_BulkEdit(t *testing.T) {
+	table := newKeyTable()
+	db := table.Object.(*testDatabase)
+
+	selected := []string{testSelectedValue(t, table, "abc", 2), testSelectedValue(t, table, "0", 1)}
+	require.NoError(t, table.Do(mustURL(t, "http://x?bulk=edit&column=1"), map[string]any{"selected": selected, "age": "7"}))
+	assert.Equal(t, 20, db.Data[0]["age"])
+	assert.Equal(t, 7, db.Data[1]["age"])
+	assert.Equal(t, 7, db.Data[2]["age"])
+
+	// Columns must exist
+	require.Error(t, table.Do(mustURL(t, "http://x?bulk=edit&column=9"), map[string]any{"selected": "abc", "age": "8"}))
+}
+
+/******************************************
+ * Drawing (Bulk Edit)
+ ******************************************/
+
+func TestDraw_BulkActions(t *testing.T) {
+	table := newMoveTable().AllowSelect()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `<select name="column" hx-get="http://localhost/table?bulk=edit"`)
+	assert.Contains(t, result, `<option value="1">Age</option>`)
+}
+
+func TestDraw_BulkEdit(t *testing.T) {
+	table := newMoveTable().AllowSelect()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x?bulk=edit&column=1&selected=1&selected=3"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `<form class="grid" hx-post="http://localhost/table?bulk=edit&column=1"`)
+	assert.Contains(t, result, `<input name="age" value="" autofocus="true">`)
+	assert.NotContains(t, result, `<input name="name"`) // only the chosen column is edited
+	assert.Contains(t, result, `name="selected" value="`+testSelectedValue(t, table, "1", 1)+`" checked="true"`)
+	assert.Contains(t, result, `name="selected" value="`+testSelectedValue(t, table, "3", 3)+`" checked="true"`)
+	assert.Contains(t, result, `name="selected" value="`+testSelectedValue(t, table, "2", 2)+`">`)
+	assert.NotContains(t, result, "grid-bulk-actions")
+}
+
+func TestDraw_BulkEditNotAllowed(t *testing.T) {
+	table := newMoveTable().AllowSelect()
+	table.CanEdit = false
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x?bulk=edit&column=1&selected=1"), &buffer))
+	assert.NotContains(t, buffer.String(), "<form")
+}
+
+func TestDrawErrors_BulkEdit(t *testing.T) {
+	table := newConstrainedTable().AllowSelect()
+	var buffer bytes.Buffer
+
+	params := mustURL(t, "http://x?bulk=edit&column=1")
+	submitted := map[string]any{"selected": []string{testSelectedValue(t, table, "0", 0), testSelectedValue(t, table, "1", 1)}, "age": "x"}
+	err := table.Do(params, submitted)
+	require.Error(t, err)
+
+	require.NoError(t, table.DrawErrors(params, submitted, err, &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `<div class="grid-error">Value must be an integer</div>`)
+	assert.Equal(t, 1, strings.Count(result, "grid-error")) // the missing "name" is not an error here
+	assert.Equal(t, 2, strings.Count(result, `checked="true"`))
+}
@@ -0,0 +1,128 @@
+package table
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/benpate/form"
+	"github.com/benpate/rosetta/compare"
+	"github.com/benpate/rosetta/convert"
+	"github.com/benpate/rosetta/schema"
+)
+
+/******************************************
+ * Sorting Methods
+ ******************************************/
+
+// sortColumn returns the index of the column that rows are sorted by, or -1 if
+// the table is shown in its original order.  The sort path comes from the query
+// string, so it is only accepted if it names a sortable column in the Form.
+func (widget Table) sortColumn(rowSchema *schema.Schema) int {
+
+	if !widget.CanSort || (widget.view.SortPath == "") {
+		return -1
+	}
+
+	for index, field := range widget.Form.Children {
+		if (field.Path == widget.view.SortPath) && isSortable(rowSchema, field) {
+			return index
+		}
+	}
+
+	return -1
+}
+
+// sortRows returns the order in which rows should be displayed, as a list of
+// indexes into the original data.  Sorting is a view-only permutation, so the
+// data itself is never reordered and every index still addresses the same row
+// when it is sent back to Do.
+func (widget Table) sortRows(rowSchema *schema.Schema, rows []any) []int {
+
+	result := make([]int, len(rows))
+	for index := range rows {
+		result[index] = index
+	}
+
+	column := widget.sortColumn(rowSchema)
+
+	if column < 0 {
+		return result
+	}
+
+	path := widget.Form.Children[column].Path
+	element, _ := rowSchema.GetElement(path)
+
+	// Read each sort value once, rather than once per comparison.
+	// Values that cannot be read sort as empty values.
+	values := make([]any, len(rows))
+	for index, row := range rows {
+		values[index], _ = rowSchema.Get(row, path)
+	}
+
+	slices.SortStableFunc(result, func(a int, b int) int {
+		if widget.view.SortDesc {
+			return compareValues(element, values[b], values[a])
+		}
+		return compareValues(element, values[a], values[b])
+	})
+
+	return result
+}
+
+// isSortable returns TRUE if rows can be ordered by the values in this field
+func isSortable(rowSchema *schema.Schema, field form.Element) bool {
+
+	if field.Path == "" {
+		return false
+	}
+
+	element, ok := rowSchema.GetElement(field.Path)
+
+	if !ok {
+		return false
+	}
+
+	switch element.(type) {
+	case schema.String, schema.Integer, schema.Number, schema.Boolean:
+		return true
+	}
+
+	return false
+}
+
+// compareValues orders two values using the rules of their schema element.
+// Strings compare without regard to case, except for enumerations which
+// sort in the order that their values are listed in the schema.
+func compareValues(element schema.Element, value1 any, value2 any) int {
+
+	switch typed := element.(type) {
+
+	case schema.String:
+		if len(typed.Enum) > 0 {
+			return compare.Int(enumIndex(typed.Enum, value1), enumIndex(typed.Enum, value2))
+		}
+		return compare.String(strings.ToLower(convert.String(value1)), strings.ToLower(convert.String(value2)))
+
+	case schema.Integer:
+		return compare.Int64(convert.Int64(value1), convert.Int64(value2))
+
+	case schema.Number:
+		return compare.Float64(convert.Float(value1), convert.Float(value2))
+
+	case schema.Boolean:
+		return compare.Bool(convert.Bool(value1), convert.Bool(value2))
+	}
+
+	return 0
+}
+
+// enumIndex returns the position of a value within an enumeration.
+// Values that are not in the enumeration sort after all others.
+func enumIndex(enum []string, value any) int {
+
+	if index := slices.Index(enum, convert.String(value)); index >= 0 {
+		return index
+	}
+
+	return len(enum)
+}
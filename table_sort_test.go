@@ -0,0 +1,241 @@
+package table
+
+import (
+	"bytes"
+	"html"
+	"strings"
+	"testing"
+
+	"github.com/benpate/form"
+	"github.com/benpate/rosetta/mapof"
+	"github.com/benpate/rosetta/schema"
+	"github.com/benpate/rosetta/sliceof"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/******************************************
+ * compareValues()
+ ******************************************/
+
+func TestCompareValues_String(t *testing.T) {
+
+	element := schema.String{}
+
+	assert.Equal(t, -1, compareValues(element, "apple", "Banana")) // case-insensitive
+	assert.Equal(t, 1, compareValues(element, "banana", "Apple"))
+	assert.Equal(t, 0, compareValues(element, "Apple", "apple"))
+}
+
+// Enumerations sort in schema order, not alphabetically.  Unknown values sort last.
+func TestCompareValues_Enum(t *testing.T) {
+
+	element := schema.String{Enum: []string{"New", "Pending", "Complete"}}
+
+	assert.Equal(t, -1, compareValues(element, "Pending", "Complete")) // alphabetically, this would be 1
+	assert.Equal(t, 1, compareValues(element, "Complete", "New"))
+	assert.Equal(t, 1, compareValues(element, "Unknown", "Complete"))
+}
+
+func TestCompareValues_Integer(t *testing.T) {
+
+	element := schema.Integer{}
+
+	assert.Equal(t, -1, compareValues(element, 9, 10)) // numeric, not "10" < "9"
+	assert.Equal(t, 1, compareValues(element, "10", 9))
+	assert.Equal(t, 0, compareValues(element, 7, int64(7)))
+}
+
+func TestCompareValues_Number(t *testing.T) {
+
+	element := schema.Number{}
+
+	assert.Equal(t, -1, compareValues(element, 1.5, 10.25))
+	assert.Equal(t, 1, compareValues(element, 2, 1.99))
+}
+
+func TestCompareValues_Boolean(t *testing.T) {
+
+	element := schema.Boolean{}
+
+	assert.Equal(t, -1, compareValues(element, false, true))
+	assert.Equal(t, 0, compareValues(element, true, true))
+}
+
+func TestCompareValues_Unsortable(t *testing.T) {
+	assert.Equal(t, 0, compareValues(schema.Object{}, "a", "b"))
+}
+
+/******************************************
+ * sortRows()
+ ******************************************/
+
+// sortTable returns a Table with four rows whose names and ages sort differently.
+func sortTable() Table {
+	table := newTestTable().AllowSort()
+	db := table.Object.(*testDatabase)
+	db.Data = sliceof.Object[mapof.Any]{
+		mapof.Any{"name": "Charlie", "age": 9},
+		mapof.Any{"name": "alice", "age": 30},
+		mapof.Any{"name": "Bob", "age": 100},
+		mapof.Any{"name": "alice", "age": 1},
+	}
+	return table
+}
+
+// sortOrder returns the row order that the table would display.
+func sortOrder(t *testing.T, table Table) []int {
+	t.Helper()
+
+	tableElement, err := table.getTableElement()
+	require.NoError(t, err)
+
+	rowSchema := schema.New(tableElement.Items)
+	db := table.Object.(*testDatabase)
+
+	rows := make([]any, len(db.Data))
+	for index := range db.Data {
+		rows[index] = db.Data[index]
+	}
+
+	return table.sortRows(&rowSchema, rows)
+}
+
+func TestSortRows_Unsorted(t *testing.T) {
+	table := sortTable()
+	assert.Equal(t, []int{0, 1, 2, 3}, sortOrder(t, table))
+}
+
+// Equal values keep their original relative order (rows 1 and 3 are both "alice").
+func TestSortRows_StringAscending(t *testing.T) {
+	table := sortTable()
+	table.view = viewState{SortPath: "name"}
+	assert.Equal(t, []int{1, 3, 2, 0}, sortOrder(t, table))
+}
+
+func TestSortRows_StringDescending(t *testing.T) {
+	table := sortTable()
+	table.view = viewState{SortPath: "name", SortDesc: true}
+	assert.Equal(t, []int{0, 2, 1, 3}, sortOrder(t, table))
+}
+
+func TestSortRows_Integer(t *testing.T) {
+	table := sortTable()
+	table.view = viewState{SortPath: "age"}
+	assert.Equal(t, []int{3, 0, 1, 2}, sortOrder(t, table))
+}
+
+// Sorting must be explicitly allowed.
+func TestSortRows_NotAllowed(t *testing.T) {
+	table := sortTable()
+	table.CanSort = false
+	table.view = viewState{SortPath: "age"}
+	assert.Equal(t, []int{0, 1, 2, 3}, sortOrder(t, table))
+}
+
+// A sort path from the query string that is not a column is ignored.
+func TestSortRows_UnknownColumn(t *testing.T) {
+	table := sortTable()
+	table.view = viewState{SortPath: "notAColumn"}
+	assert.Equal(t, []int{0, 1, 2, 3}, sortOrder(t, table))
+}
+
+/******************************************
+ * isSortable()
+ ******************************************/
+
+func TestIsSortable(t *testing.T) {
+
+	rowSchema := schema.New(schema.Object{
+		Properties: schema.ElementMap{
+			"name":   schema.String{},
+			"tags":   schema.Array{Items: schema.String{}},
+			"active": schema.Boolean{},
+		},
+	})
+
+	assert.True(t, isSortable(&rowSchema, form.Element{Path: "name"}))
+	assert.True(t, isSortable(&rowSchema, form.Element{Path: "active"}))
+	assert.False(t, isSortable(&rowSchema, form.Element{Path: "tags"}))    // arrays have no natural order
+	assert.False(t, isSortable(&rowSchema, form.Element{Path: "missing"})) // not in the schema
+	assert.False(t, isSortable(&rowSchema, form.Element{Path: ""}))        // no data at all
+}
+
+/******************************************
+ * Draw() - Sorting
+ ******************************************/
+
+func TestDraw_Sorted(t *testing.T) {
+
+	table := sortTable()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?sort=age&dir=desc"), &buffer)
+
+	require.NoError(t, err)
+	result := buffer.String()
+
+	// Rows appear in descending age order: Bob(100), alice(30), Charlie(9)
+	assert.Less(t, strings.Index(result, "Bob"), strings.Index(result, "alice"))
+	assert.Less(t, strings.Index(result, "alice"), strings.Index(result, "Charlie"))
+	assert.Contains(t, result, "sort-descending") // sort indicator on the header
+}
+
+// Sorted rows still link to their ORIGINAL index, so Do edits the correct row.
+func TestDraw_SortedKeepsOriginalIndexes(t *testing.T) {
+
+	table := sortTable()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?sort=age"), &buffer)
+
+	require.NoError(t, err)
+	result := buffer.String()
+
+	// The first data row is "alice" (age 1), which is row 3 in the data
+	firstRow := result[strings.Index(result, `class="grid-row`):]
+	firstRow = firstRow[:strings.Index(firstRow, "</tr>")]
+	assert.Contains(t, firstRow, "edit=3")
+	assert.Contains(t, firstRow, "delete=3")
+}
+
+// Header cells link to their own sort order, and clicking the current sort
+// column again reverses the direction.
+func TestDraw_SortHeaderLinks(t *testing.T) {
+
+	table := sortTable()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?sort=name&dir=asc"), &buffer)
+
+	require.NoError(t, err)
+	result := buffer.String()
+	assert.Contains(t, result, "grid-sortable")
+	assert.Contains(t, html.UnescapeString(result), "dir=desc&sort=name") // reverse the current column
+	assert.Contains(t, html.UnescapeString(result), "dir=asc&sort=age")   // sort a new column
+}
+
+// Edit links carry the current sort forward, so the view survives the round trip.
+func TestDraw_SortCarriedIntoLinks(t *testing.T) {
+
+	table := sortTable()
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?sort=age&dir=desc&edit=0"), &buffer)
+
+	require.NoError(t, err)
+	assert.Contains(t, html.UnescapeString(buffer.String()), "dir=desc&edit=0&focus=0&sort=age") // form hx-post
+}
+
+func TestDraw_SortNotAllowed(t *testing.T) {
+
+	table := newTestTable() // CanSort is FALSE by default
+	var buffer bytes.Buffer
+
+	err := table.Draw(mustURL(t, "http://x?sort=name&dir=desc"), &buffer)
+
+	require.NoError(t, err)
+	result := buffer.String()
+	assert.NotContains(t, result, "grid-sortable")
+	assert.Less(t, strings.Index(result, "John Connor"), strings.Index(result, "Sarah Connor")) // original order
+}
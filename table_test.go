@@ -2,6 +2,7 @@ package table
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"testing"
 
@@ -201,6 +202,33 @@ func TestAllowDelete(t *testing.T) {
 	assert.False(t, table.CanDelete)
 }
 
+func TestAllowSort(t *testing.T) {
+	table := newTestTable() // New() does not allow sorting by default
+
+	result := table.AllowSort()
+
+	assert.True(t, result.CanSort)
+	assert.False(t, table.CanSort)
+}
+
+func TestAllowSearch(t *testing.T) {
+	table := newTestTable() // New() does not allow searching by default
+
+	result := table.AllowSearch()
+
+	assert.True(t, result.CanSearch)
+	assert.False(t, table.CanSearch)
+}
+
+func TestAllowFilter(t *testing.T) {
+	table := newTestTable() // New() does not allow filtering by default
+
+	result := table.AllowFilter()
+
+	assert.True(t, result.CanFilter)
+	assert.False(t, table.CanFilter)
+}
+
 func TestAllowAll(t *testing.T) {
 	table := newTestTable()
 	table.CanAdd = false
@@ -212,6 +240,8 @@ func TestAllowAll(t *testing.T) {
 	assert.True(t, result.CanAdd)
 	assert.True(t, result.CanEdit)
 	assert.True(t, result.CanDelete)
+	assert.True(t, result.CanMove)
+	assert.True(t, result.CanDuplicate)
 
 	// The original is left unchanged
 	assert.False(t, table.CanAdd)
@@ -227,6 +257,8 @@ func TestAllowNone(t *testing.T) {
 	assert.False(t, result.CanAdd)
 	assert.False(t, result.CanEdit)
 	assert.False(t, result.CanDelete)
+	assert.False(t, result.CanMove)
+	assert.False(t, result.CanDuplicate)
 
 	// The original is left unchanged
 	assert.True(t, table.CanAdd)
@@ -234,6 +266,60 @@ func TestAllowNone(t *testing.T) {
 	assert.True(t, table.CanDelete)
 }
 
+func TestAllowInsert(t *testing.T) {
+	table := newTestTable()
+
+	result := table.AllowInsert()
+
+	assert.True(t, result.CanInsert)
+	assert.False(t, table.CanInsert) // the original is left unchanged
+}
+
+func TestAllowDuplicate(t *testing.T) {
+	table := newTestTable()
+
+	result := table.AllowDuplicate()
+
+	assert.True(t, result.CanDuplicate)
+	assert.False(t, table.CanDuplicate) // the original is left unchanged
+}
+
+func TestAllowMove(t *testing.T) {
+	table := newTestTable()
+
+	result := table.AllowMove()
+
+	assert.True(t, result.CanMove)
+	assert.False(t, table.CanMove) // the original is left unchanged
+}
+
+func TestUseDragHandle(t *testing.T) {
+	table := newTestTable()
+
+	result := table.UseDragHandle()
+
+	assert.True(t, result.DragHandle)
+	assert.False(t, table.DragHandle) // the original is left unchanged
+}
+
+func TestUsePageSize(t *testing.T) {
+	table := newTestTable()
+
+	result := table.UsePageSize(25)
+
+	assert.Equal(t, 25, result.PageSize)
+	assert.Zero(t, table.PageSize) // the original is left unchanged
+}
+
+func TestUseKeyPath(t *testing.T) {
+	table := newTestTable()
+
+	result := table.UseKeyPath("taskId")
+
+	assert.Equal(t, "taskId", result.KeyPath)
+	assert.Empty(t, table.KeyPath) // the original is left unchanged
+}
+
 func TestUseLookupProvider(t *testing.T) {
 	table := newTestTable()
 	provider := testLookupProvider{}
@@ -244,6 +330,25 @@ func TestUseLookupProvider(t *testing.T) {
 	assert.Nil(t, table.LookupProvider) // the original is left unchanged
 }
 
+func TestUseTranslator(t *testing.T) {
+	table := newTestTable()
+	translator := Messages{MessageAddRow: "Zeile hinzufügen"}
+
+	result := table.UseTranslator(translator)
+
+	assert.Equal(t, translator, result.Translator)
+	assert.Nil(t, table.Translator) // the original is left unchanged
+}
+
+func TestUseLocale(t *testing.T) {
+	table := newTestTable()
+
+	result := table.UseLocale("de")
+
+	assert.Equal(t, "de", result.Locale)
+	assert.Empty(t, table.Locale) // the original is left unchanged
+}
+
 // The builders use value receivers so they can be chained directly off New
 // without the result escaping to the heap.
 func TestNew_BuildersChainOffConstructor(t *testing.T) {
@@ -282,6 +387,10 @@ func TestGetURL(t *testing.T) {
 	check("delete", 0, 0, "http://localhost/table?delete=0")
 	check("delete", 7, 4, "http://localhost/table?delete=7") // col ignored for "delete"
 
+	check("insert", 2, 0, "http://localhost/table?insert=2")
+	check("move", 2, 0, "http://localhost/table?move=2&to=0")
+	check("drop", 3, 0, "http://localhost/table?to=3") // the dropped row posts its own "move" value
+
 	// Unrecognized actions return the bare TargetURL
 	check("", 0, 0, "http://localhost/table")
 	check("unknown", 1, 1, "http://localhost/table")
@@ -307,6 +416,61 @@ func TestGetURL_TargetWithExistingQuery(t *testing.T) {
 	check("unknown", 0, 0, "http://localhost/table?section=tasks")
 }
 
+// The current view options are carried forward into every action, and the
+// "sort" action toggles direction only when the column is already sorted.
+func TestGetURL_ViewState(t *testing.T) {
+
+	table := newTestTable()
+	table.view = viewState{SortPath: "name"}
+
+	check := func(action string, row int, col int, expected string) {
+		assert.Equal(t, expected, table.getURL(action, row, col), "action=%s row=%d col=%d", action, row, col)
+	}
+
+	check("view", 0, 0, "http://localhost/table?dir=asc&sort=name")
+	check("edit", 1, 0, "http://localhost/table?dir=asc&edit=1&focus=0&sort=name")
+	check("sort", 0, 0, "http://localhost/table?dir=desc&sort=name") // same column => reverse
+	check("sort", 0, 1, "http://localhost/table?dir=asc&sort=age")   // new column => ascending
+	check("page", 3, 0, "http://localhost/table?dir=asc&page=3&sort=name")
+
+	table.view.Search = "connor"
+	check("edit", 1, 0, "http://localhost/table?dir=asc&edit=1&focus=0&q=connor&sort=name")
+	check("search", 0, 0, "http://localhost/table?dir=asc&sort=name") // the search box supplies "q"
+	table.view.Search = ""
+
+	table.view.Filters = url.Values{"filter.status": {"Done"}}
+	check("delete", 1, 0, "http://localhost/table?delete=1&dir=asc&filter.status=Done&sort=name")
+	check("filter", 0, 0, "http://localhost/table?dir=asc&sort=name") // the filter controls supply their own values
+	table.view.Filters = nil
+
+	table.view.Page = 2
+	check("edit", 1, 0, "http://localhost/table?dir=asc&edit=1&focus=0&page=2&sort=name")
+	check("sort", 0, 1, "http://localhost/table?dir=asc&sort=age") // a new sort starts from the first page
+	table.view.Page = 0
+
+	table.view.SortDesc = true
+	check("sort", 0, 0, "http://localhost/table?dir=asc&sort=name") // descending => back to ascending
+}
+
+/******************************************
+ * parseViewState()
+ ******************************************/
+
+func TestParseViewState(t *testing.T) {
+
+	assert.Equal(t, viewState{}, parseViewState(url.Values{}))
+	assert.Equal(t, viewState{SortPath: "age"}, parseViewState(url.Values{"sort": {"age"}, "dir": {"asc"}}))
+	assert.Equal(t, viewState{SortPath: "age", SortDesc: true}, parseViewState(url.Values{"sort": {"age"}, "dir": {"desc"}}))
+	assert.Equal(t, viewState{SortPath: "age"}, parseViewState(url.Values{"sort": {"age"}, "dir": {"sideways"}}))
+	assert.Equal(t, viewState{Page: 3, PageSize: 20}, parseViewState(url.Values{"page": {"3"}, "size": {"20"}}))
+	assert.Equal(t, viewState{}, parseViewState(url.Values{"page": {"-3"}, "size": {"abc"}})) // untrusted input
+	assert.Equal(t, viewState{Search: "connor"}, parseViewState(url.Values{"q": {"connor"}}))
+	assert.Equal(t,
+		viewState{Filters: url.Values{"filter.status": {"Done"}, "min.age": {"1"}, "max.age": {"9"}}},
+		parseViewState(url.Values{"filter.status": {"Done"}, "min.age": {"1"}, "max.age": {"9"}, "other": {"x"}}),
+	)
+}
+
 /******************************************
  * getTableElement()
  ******************************************/
@@ -0,0 +1,472 @@
+package table
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/benpate/derp"
+	"github.com/benpate/html"
+	"github.com/benpate/rosetta/convert"
+	"github.com/benpate/rosetta/list"
+	"github.com/benpate/rosetta/null"
+	"github.com/benpate/rosetta/schema"
+)
+
+/******************************************
+ * Tree Grids
+ ******************************************/
+
+// rowTree is the hierarchy of the displayed rows, where each row's ParentPath
+// value is the KeyPath value of its parent
+type rowTree struct {
+	Order    []int  // Every row in the tree (by index) in display order
+	Visible  []int  // Rows that are not hidden inside a collapsed row, in display order
+	Depths   []int  // Depth of each row (by index).  Top-level rows are zero, and rows outside the tree are -1
+	Children []int  // Number of children of each row (by index)
+	Closed   []bool // If TRUE, then the children of each row (by index) are hidden
+}
+
+// isTree returns TRUE if rows are displayed as a tree
+func (widget Table) isTree() bool {
+	return (widget.KeyPath != "") && (widget.ParentPath != "")
+}
+
+// treeParents returns the parent (by index) of each row (by index), or -1 for
+// rows that are at the top of the tree.  Rows whose parent cannot be found are
+// also at the top, so that they are never lost.
+func (widget Table) treeParents(rowSchema *schema.Schema, rows []any, keys []string) []int {
+
+	positions := make(map[string]int)
+
+	for index, key := range keys {
+		if _, exists := positions[key]; (key != "") && !exists {
+			positions[key] = index
+		}
+	}
+
+	result := make([]int, len(rows))
+
+	for index, row := range rows {
+
+		result[index] = -1
+		value, _ := rowSchema.Get(row, widget.ParentPath)
+
+		if parent, ok := positions[convert.String(value)]; ok && (parent != index) {
+			result[index] = parent
+		}
+	}
+
+	return result
+}
+
+// treeRows arranges the displayed rows into a tree, with each row followed by
+// its children, and the children kept in the current sort order.  The ancestors
+// of every displayed row are displayed too (even when they do not match the
+// search or filters) so that each row keeps its place in the tree.  The edit
+// row, and the parent of a new row, are never hidden inside a collapsed row.
+func (widget Table) treeRows(rowSchema *schema.Schema, rows []any, sortOrder []int, rowOrder []int, editRow null.Int) rowTree {
+
+	parents := widget.treeParents(rowSchema, rows, widget.rowKeys)
+
+	// Display the ancestors of every displayed row
+	displayed := make([]bool, len(rows))
+	for _, rowIndex := range rowOrder {
+		for index := rowIndex; (index >= 0) && !displayed[index]; index = parents[index] {
+			displayed[index] = true
+		}
+	}
+
+	// Open every row above the edit row, and the parent of a new row
+	open := make([]bool, len(rows))
+	opened := make([]int, 0, 2)
+
+	if editRow.IsPresent() && (editRow.Int() < len(rows)) {
+		opened = append(opened, parents[editRow.Int()])
+	}
+
+	if widget.addParent.IsPresent() {
+		opened = append(opened, widget.addParent.Int())
+	}
+
+	for _, rowIndex := range opened {
+		for index := rowIndex; (index >= 0) && !open[index]; index = parents[index] {
+			open[index] = true
+		}
+	}
+
+	// Collect the children of each row, in the current sort order
+	tree := rowTree{
+		Order:    make([]int, 0, len(rowOrder)),
+		Visible:  make([]int, 0, len(rowOrder)),
+		Depths:   make([]int, len(rows)),
+		Children: make([]int, len(rows)),
+		Closed:   make([]bool, len(rows)),
+	}
+
+	children := make([][]int, len(rows))
+	roots := make([]int, 0)
+
+	for _, rowIndex := range sortOrder {
+
+		tree.Depths[rowIndex] = -1
+
+		if !displayed[rowIndex] {
+			continue
+		}
+
+		if parent := parents[rowIndex]; parent >= 0 {
+			children[parent] = append(children[parent], rowIndex)
+			tree.Children[parent]++
+		} else {
+			roots = append(roots, rowIndex)
+		}
+	}
+
+	for index, key := range widget.rowKeys {
+		tree.Closed[index] = (key != "") && !open[index] && slices.Contains(widget.view.Collapsed, key)
+	}
+
+	for _, rowIndex := range roots {
+		tree.add(rowIndex, 0, true, children)
+	}
+
+	// Rows whose parents form a loop are never reached from the top of the
+	// tree, so they are displayed at the top instead
+	for _, rowIndex := range sortOrder {
+		if displayed[rowIndex] && (tree.Depths[rowIndex] < 0) {
+			tree.add(rowIndex, 0, true, children)
+		}
+	}
+
+	return tree
+}
+
+// add appends a row and all of its descendants to the tree.  Rows that are
+// already in the tree are skipped, so loops in the data always end.
+func (tree *rowTree) add(rowIndex int, depth int, visible bool, children [][]int) {
+
+	if tree.Depths[rowIndex] >= 0 {
+		return
+	}
+
+	tree.Depths[rowIndex] = depth
+	tree.Order = append(tree.Order, rowIndex)
+
+	if visible {
+		tree.Visible = append(tree.Visible, rowIndex)
+	}
+
+	for _, child := range children[rowIndex] {
+		tree.add(child, depth+1, visible && !tree.Closed[rowIndex], children)
+	}
+}
+
+// treeDepth returns the depth of a row in the tree, or -1 if the row is not in the tree
+func (widget Table) treeDepth(rowIndex int) int {
+
+	if (rowIndex < 0) || (rowIndex >= len(widget.tree.Depths)) {
+		return -1
+	}
+
+	return widget.tree.Depths[rowIndex]
+}
+
+// hasChildren returns TRUE if a row has children in the tree
+func (widget Table) hasChildren(rowIndex int) bool {
+	return (rowIndex >= 0) && (rowIndex < len(widget.tree.Children)) && (widget.tree.Children[rowIndex] > 0)
+}
+
+// isCollapsedRow returns TRUE if a row has children, and they are hidden
+func (widget Table) isCollapsedRow(rowIndex int) bool {
+	return widget.hasChildren(rowIndex) && widget.tree.Closed[rowIndex]
+}
+
+// lastDescendant returns the last visible row in the subtree that begins with
+// rowIndex, which is where the row for adding a new child is drawn
+func (widget Table) lastDescendant(rowIndex int) int {
+
+	position, ok := widget.rowPositions[rowIndex]
+
+	if !ok {
+		return rowIndex
+	}
+
+	depth := widget.treeDepth(rowIndex)
+	result := rowIndex
+
+	for _, index := range widget.rowOrder[position+1:] {
+
+		if widget.treeDepth(index) <= depth {
+			break
+		}
+
+		result = index
+	}
+
+	return result
+}
+
+// treeRowAttributes writes the ARIA attributes that describe a row's place in the tree
+func (widget Table) treeRowAttributes(depth int, rowIndex int, row *html.Element) {
+
+	row.Attr("aria-level", strconv.Itoa(depth+1))
+
+	if widget.hasChildren(rowIndex) {
+		row.Attr("aria-expanded", strconv.FormatBool(!widget.isCollapsedRow(rowIndex)))
+	}
+}
+
+// drawTreeToggle writes the indentation at the start of a row's first cell,
+// along with the button that collapses or expands the row's children.  Rows
+// without children (and rows being edited) are indented without a button.
+func (widget Table) drawTreeToggle(rowIndex int, depth int, toggle bool, b *html.Builder) {
+
+	b.Span().
+		Class("grid-tree-indent").
+		Attr("aria-hidden", "true").
+		Style("padding-inline-start:calc(" + strconv.Itoa(depth) + " * 1.5em)").
+		Close()
+
+	if !toggle || !widget.hasChildren(rowIndex) {
+		b.Span().Class("grid-tree-spacer").Attr("aria-hidden", "true").Close()
+		return
+	}
+
+	action, icon, labelKey := "collapse-row", "collapse", MessageCollapseRow
+	if widget.isCollapsedRow(rowIndex) {
+		action, icon, labelKey = "expand-row", "expand", MessageExpandRow
+	}
+
+	// Toggling never opens the editor of the cell that contains the button
+	b.Button().
+		Type("button").
+		Class("grid-tree-toggle").
+		Attr("aria-label", widget.rowLabel(labelKey, rowIndex)).
+		Attr("onclick", "event.stopPropagation()").
+		Data("hx-get", widget.getURL(action, rowIndex, 0)).
+		InnerHTML(widget.Icons.Get(icon)).
+		Close()
+}
+
+// treeCellPrefix returns the indentation (and toggle button) at the start of a
+// row's first cell.  Other cells, and rows outside the tree, have no prefix.
+func (widget Table) treeCellPrefix(colIndex int, rowIndex int, depth int, toggle bool) string {
+
+	if (colIndex != 0) || (depth < 0) {
+		return ""
+	}
+
+	b := html.New()
+	widget.drawTreeToggle(rowIndex, depth, toggle, b)
+	return string(b.Bytes())
+}
+
+/******************************************
+ * Tree Updates
+ ******************************************/
+
+// DoAddChild adds a dataset to the table as a new row, beneath the row at
+// parentIndex in the tree.  The new row's ParentPath is set to the parent's key
+// before the row is validated, and the row is written all at once, so an error
+// always leaves widget.Object unchanged.
+func (widget Table) DoAddChild(data map[string]any, parentIndex int) error {
+
+	const location = "table.Widget.DoAddChild"
+
+	if !widget.isTree() {
+		return derp.BadRequest(location, widget.message(MessageNotTree), widget.Path)
+	}
+
+	tableData, err := widget.Schema.Get(widget.Object, widget.Path)
+
+	if err != nil {
+		return derp.Wrap(err, location, "Locating table data", widget.Path)
+	}
+
+	length := convert.SliceLength(tableData)
+
+	if (parentIndex < 0) || (parentIndex >= length) {
+		return derp.BadRequest(location, widget.message(MessageRowNotFound), widget.Path, parentIndex, length)
+	}
+
+	parentKey, err := widget.Schema.Get(widget.Object, list.ByDot(widget.Path, strconv.Itoa(parentIndex), widget.KeyPath).String())
+
+	if (err != nil) || (convert.String(parentKey) == "") {
+		return derp.BadRequest(location, widget.message(MessageRowNotFound), widget.Path, parentIndex)
+	}
+
+	// Stage the parent's key with the rest of the new row (see DoEdit)
+	staged := maps.Clone(data)
+
+	if staged == nil {
+		staged = map[string]any{}
+	}
+
+	staged[widget.ParentPath] = convert.String(parentKey)
+	widget.addParent = null.NewInt(parentIndex)
+
+	if err := widget.DoEdit(staged, length); err != nil {
+		return derp.Wrap(err, location, "Adding row", widget.Path)
+	}
+
+	return nil
+}
+
+// treeRemovals returns every row that is removed when the requested rows are
+// deleted from a tree.  With CascadeDelete, this includes all of their
+// descendants.  Otherwise, the children of each deleted row move up to its
+// closest remaining ancestor (or to the top of the tree), and the new parent
+// key of each of these rows (by index) is returned too.
+func (widget Table) treeRemovals(indexes []int) ([]int, map[int]string, error) {
+
+	const location = "table.Widget.treeRemovals"
+
+	tableElement, err := widget.getTableElement()
+
+	if err != nil {
+		return nil, nil, derp.Wrap(err, location, "Getting table element")
+	}
+
+	tableData, err := widget.Schema.Get(widget.Object, widget.Path)
+
+	if err != nil {
+		return nil, nil, derp.Wrap(err, location, "Locating table data", widget.Path)
+	}
+
+	tableSchema := schema.New(tableElement)
+	rowSchema := schema.New(tableElement.Items)
+	rows := make([]any, convert.SliceLength(tableData))
+
+	for rowIndex := range rows {
+
+		rowValue, err := tableSchema.Get(tableData, strconv.Itoa(rowIndex))
+
+		if err != nil {
+			return nil, nil, derp.Wrap(err, location, "Getting row data", rowIndex)
+		}
+
+		rows[rowIndex] = rowValue
+	}
+
+	keys := widget.getRowKeys(&rowSchema, rows)
+	parents := widget.treeParents(&rowSchema, rows, keys)
+
+	removed := make([]bool, len(rows))
+	for _, index := range indexes {
+
+		if (index < 0) || (index >= len(rows)) {
+			return nil, nil, derp.BadRequest(location, widget.message(MessageRowNotFound), widget.Path, index, len(rows))
+		}
+
+		removed[index] = true
+	}
+
+	// Remove every row beneath a removed row, repeating until no more are found
+	for changed := widget.CascadeDelete; changed; {
+		changed = false
+		for index, parent := range parents {
+			if (parent >= 0) && removed[parent] && !removed[index] {
+				removed[index] = true
+				changed = true
+			}
+		}
+	}
+
+	result := make([]int, 0, len(indexes))
+	reparent := make(map[int]string)
+
+	for index, parent := range parents {
+
+		if removed[index] {
+			result = append(result, index)
+			continue
+		}
+
+		if (parent < 0) || !removed[parent] {
+			continue
+		}
+
+		// Find the closest ancestor that remains (stopping if the ancestors form a loop)
+		for steps := 0; (parent >= 0) && removed[parent] && (steps < len(rows)); steps++ {
+			parent = parents[parent]
+		}
+
+		reparent[index] = ""
+		if (parent >= 0) && !removed[parent] {
+			reparent[index] = keys[parent]
+		}
+	}
+
+	return result, reparent, nil
+}
+
+// removeRows removes rows from the table, from the highest index down, after
+// moving the children of those rows to their new parents (see treeRemovals).
+// Like DoBulkEdit, every new parent is staged on a copy of its row first, so
+// that a parent key that cannot be stored leaves the table unchanged.
+func (widget Table) removeRows(indexes []int, reparent map[int]string) error {
+
+	const location = "table.Widget.removeRows"
+
+	staged := make(map[int]any, len(reparent))
+
+	if len(reparent) > 0 {
+
+		tableElement, err := widget.getTableElement()
+
+		if err != nil {
+			return derp.Wrap(err, location, "Getting table element")
+		}
+
+		rowSchema := newRowSchema(tableElement)
+
+		for index, parentKey := range reparent {
+
+			rowValue, err := widget.getRow(tableElement, index)
+
+			if err != nil {
+				return derp.Wrap(err, location, "Getting row data", widget.Path, index)
+			}
+
+			row := cloneRow(rowValue)
+
+			if err := rowSchema.Set(&row, widget.ParentPath, parentKey); err != nil {
+				return derp.Wrap(err, location, "Setting new parent", widget.Path, index, parentKey)
+			}
+
+			// Empty values are removed from maps, so they are filled in with defaults first
+			row = completeRow(rowSchema.Element, row)
+
+			if staged[index], err = rowSchema.Get(&row, widget.ParentPath); err != nil {
+				return derp.Wrap(err, location, "Getting staged parent", widget.Path, index)
+			}
+		}
+	}
+
+	// Write the staged parents back into the table, then remove the rows
+	for index, parentKey := range staged {
+
+		path := list.ByDot(widget.Path, strconv.Itoa(index), widget.ParentPath).String()
+
+		if err := widget.Schema.Set(widget.Object, path, parentKey); err != nil {
+			return derp.Wrap(err, location, "Moving row to new parent", path)
+		}
+	}
+
+	indexes = slices.Clone(indexes)
+	slices.Sort(indexes)
+	indexes = slices.Compact(indexes)
+	slices.Reverse(indexes)
+
+	for _, index := range indexes {
+
+		path := list.ByDot(widget.Path, strconv.Itoa(index)).String()
+
+		if ok := widget.Schema.Remove(widget.Object, path); !ok {
+			return derp.Internal(location, widget.message(MessageRowNotRemoved), path)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,223 @@
+package table
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/benpate/derp"
+	"github.com/benpate/form"
+	"github.com/benpate/rosetta/mapof"
+	"github.com/benpate/rosetta/schema"
+	"github.com/benpate/rosetta/sliceof"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTreeTable returns a table of machines and people, arranged in a tree by their "parent" keys
+func newTreeTable() Table {
+
+	s := schema.Schema{
+		Element: schema.Object{
+			Properties: schema.ElementMap{
+				"data": schema.Array{
+					Items: schema.Object{
+						Properties: schema.ElementMap{
+							"id":     schema.String{},
+							"parent": schema.String{},
+							"name":   schema.String{},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	f := form.Element{
+		Type: "layout-vertical",
+		Children: []form.Element{
+			{Type: "text", Label: "Name", Path: "name"},
+		},
+	}
+
+	db := &testDatabase{
+		Data: sliceof.Object[mapof.Any]{
+			mapof.Any{"id": "a", "parent": "", "name": "Skynet"},
+			mapof.Any{"id": "b", "parent": "a", "name": "T-800"},
+			mapof.Any{"id": "c", "parent": "", "name": "Resistance"},
+			mapof.Any{"id": "d", "parent": "b", "name": "T-1000"},
+			mapof.Any{"id": "e", "parent": "c", "name": "John Connor"},
+		},
+	}
+
+	return New(&s, &f, db, "data", testIconProvider{}, "http://localhost/table").UseKeyPath("id").UseParentPath("parent")
+}
+
+// treeNames returns the name of every row in the table, in storage order
+func treeNames(table Table) []string {
+
+	db := table.Object.(*testDatabase)
+	result := make([]string, len(db.Data))
+
+	for index, row := range db.Data {
+		result[index] = row.GetString("name") + "<" + row.GetString("parent")
+	}
+
+	return result
+}
+
+func TestDraw_Tree(t *testing.T) {
+
+	result := drawGroupTable(t, newTreeTable().AllowMove(), "http://x")
+
+	// Children follow their parents, and are indented one level further
+	assert.Contains(t, result, `<table class="grid" role="treegrid">`)
+	assertOrder(t, result,
+		`<tr class="grid-row hover-trigger" aria-level="1" aria-expanded="true"`, "Skynet",
+		`aria-level="2" aria-expanded="true"`, "T-800",
+		`aria-level="3"`, `padding-inline-start:calc(2 * 1.5em)`, "T-1000",
+		`aria-level="1" aria-expanded="true"`, "Resistance",
+		`aria-level="2"`, "John Connor",
+	)
+
+	assert.Contains(t, result, `<button type="button" class="grid-tree-toggle" aria-label="collapse row 1" onclick="event.stopPropagation()" hx-get="http://localhost/table?collapsed=a">collapse</button>`)
+	assert.Contains(t, result, `<button type="button" aria-label="add child to row 1" hx-get="http://localhost/table?add=true&parent=a">add-child</button>`)
+
+	// Rows keep their place in the tree, so they cannot be moved
+	assert.NotContains(t, result, "move=")
+}
+
+func TestDraw_TreeCollapsed(t *testing.T) {
+
+	result := drawGroupTable(t, newTreeTable(), "http://x?collapsed=b")
+
+	assert.NotContains(t, result, "T-1000")
+	assertOrder(t, result, `aria-level="2" aria-expanded="false"`, `aria-label="expand row 2"`, `hx-get="http://localhost/table"`, "T-800", "Resistance")
+
+	// Collapsed rows are forced open while one of their children is edited
+	result = drawGroupTable(t, newTreeTable(), "http://x?collapsed=b&edit=d")
+	assert.Contains(t, result, `name="name" value="T-1000"`)
+}
+
+func TestDraw_TreeSearch(t *testing.T) {
+
+	// Rows that match the search are displayed along with their ancestors
+	result := drawGroupTable(t, newTreeTable().AllowSearch(), "http://x?q=1000")
+
+	assertOrder(t, result, "Skynet", "T-800", "T-<mark>1000</mark>")
+	assert.NotContains(t, result, "Resistance")
+}
+
+func TestDraw_TreeAddChild(t *testing.T) {
+
+	// New children are added after the last row beneath their parent, even when the parent was collapsed
+	result := drawGroupTable(t, newTreeTable(), "http://x?add=true&parent=b&collapsed=b")
+
+	assert.Contains(t, result, `hx-post="http://localhost/table?add=true&collapsed=b&parent=b"`)
+	assertOrder(t, result, "T-800", "T-1000", `<tr class="grid-row grid-editable" aria-level="3">`, `name="name"`, "Resistance")
+	assert.Equal(t, 1, strings.Count(result, `name="name"`))
+}
+
+func TestDraw_TreeLoop(t *testing.T) {
+
+	table := newTreeTable()
+	db := table.Object.(*testDatabase)
+	db.Data[0]["parent"] = "d"
+
+	// Rows whose parents form a loop are still displayed (once each)
+	result := drawGroupTable(t, table, "http://x")
+
+	for _, name := range []string{"Skynet", "T-800", "T-1000", "Resistance", "John Connor"} {
+		assert.Equal(t, 1, strings.Count(result, ">"+name+"<"), name)
+	}
+}
+
+func TestDo_TreeAddChild(t *testing.T) {
+
+	table := newTreeTable()
+
+	require.NoError(t, table.Do(mustURL(t, "http://x?add=true&parent=c"), map[string]any{"id": "f", "name": "Kyle Reese"}))
+	assert.Equal(t, "Kyle Reese<c", treeNames(table)[5])
+
+	// Children can only be added beneath rows that exist
+	require.Error(t, table.Do(mustURL(t, "http://x?add=true&parent=z"), map[string]any{"name": "Nobody"}))
+	assert.Len(t, treeNames(table), 6)
+}
+
+// Children are validated along with their parent's key, and written all at once
+func TestDoAddChild_Atomic(t *testing.T) {
+
+	table := newTreeTable()
+	rowElement := table.Schema.Element.(schema.Object).Properties["data"].(schema.Array).Items.(schema.Object)
+	rowElement.Properties["parent"] = schema.String{Enum: []string{"", "a"}}
+
+	require.NoError(t, table.DoAddChild(map[string]any{"id": "f", "name": "T-X"}, 0))
+	require.Error(t, table.DoAddChild(map[string]any{"id": "g", "name": "Kyle Reese"}, 2))
+	assert.Equal(t, []string{"Skynet<", "T-800<a", "Resistance<", "T-1000<b", "John Connor<c", "T-X<a"}, treeNames(table))
+
+	// Only trees can have children
+	err := table.UseParentPath("").DoAddChild(map[string]any{"name": "Nobody"}, 0)
+	assert.Equal(t, "Table is not a tree", derp.RootMessage(err))
+}
+
+func TestDo_TreeDelete(t *testing.T) {
+
+	table := newTreeTable()
+
+	// Children move up to the parent of the deleted row
+	require.NoError(t, table.Do(mustURL(t, "http://x?delete=b&version="+testRowVersion(t, table, 1)), nil))
+	assert.Equal(t, []string{"Skynet<", "Resistance<", "T-1000<a", "John Connor<c"}, treeNames(table))
+
+	// Children of top-level rows move to the top of the tree
+	require.NoError(t, table.Do(mustURL(t, "http://x?delete=a&version="+testRowVersion(t, table, 0)), nil))
+	assert.Equal(t, []string{"Resistance<", "T-1000<", "John Connor<c"}, treeNames(table))
+}
+
+func TestDo_TreeCascadeDelete(t *testing.T) {
+
+	table := newTreeTable().UseCascadeDelete()
+
+	require.NoError(t, table.Do(mustURL(t, "http://x?delete=a&version="+testRowVersion(t, table, 0)), nil))
+	assert.Equal(t, []string{"Resistance<", "John Connor<c"}, treeNames(table))
+}
+
+func TestDoBulkDelete_Tree(t *testing.T) {
+
+	table := newTreeTable()
+
+	// Children move up to their closest ancestor that remains
+	require.NoError(t, table.DoBulkDelete(testSelected(t, table, 0, 1)))
+	assert.Equal(t, []string{"Resistance<", "T-1000<", "John Connor<c"}, treeNames(table))
+
+	table = newTreeTable().UseCascadeDelete()
+	require.NoError(t, table.DoBulkDelete(testSelected(t, table, 1, 2)))
+	assert.Equal(t, []string{"Skynet<"}, treeNames(table))
+}
+
+// Rows removed along with their ancestors count towards the schema's MinLength,
+// and a refused batch leaves every row (and its parent) unchanged
+func TestDoBulkDelete_TreeMinLength(t *testing.T) {
+
+	table := newTreeTable()
+	element := table.Schema.Element.(schema.Object)
+	array := element.Properties["data"].(schema.Array)
+	array.MinLength = 4
+	table.Schema = &schema.Schema{Element: schema.Object{Properties: schema.ElementMap{"data": array}}}
+
+	require.Error(t, table.DoBulkDelete(testSelected(t, table, 0, 1)))
+	assert.Equal(t, []string{"Skynet<", "T-800<a", "Resistance<", "T-1000<b", "John Connor<c"}, treeNames(table))
+
+	table = table.UseCascadeDelete()
+	require.Error(t, table.DoBulkDelete(testSelected(t, table, 0)))
+	assert.Equal(t, 5, len(treeNames(table)))
+}
+
+func TestUseParentPath(t *testing.T) {
+	table := newTestTable()
+
+	result := table.UseParentPath("parentId").UseCascadeDelete()
+
+	assert.Equal(t, "parentId", result.ParentPath)
+	assert.True(t, result.CascadeDelete)
+	assert.Empty(t, table.ParentPath) // the original is left unchanged
+	assert.False(t, table.CascadeDelete)
+}
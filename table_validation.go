@@ -0,0 +1,133 @@
+package table
+
+import (
+	"io"
+	"net/url"
+	"strconv"
+
+	"github.com/benpate/derp"
+	"github.com/benpate/form"
+	"github.com/benpate/html"
+	"github.com/benpate/rosetta/convert"
+	"github.com/benpate/rosetta/mapof"
+	"github.com/benpate/rosetta/schema"
+)
+
+/******************************************
+ * Validation Methods
+ ******************************************/
+
+// DrawErrors redraws the table after an add or edit was rejected by Do, so that
+// the user can correct their mistakes instead of losing what they typed.  The
+// same add or edit row is drawn again with the values that the user submitted,
+// and an error message is displayed beneath each field that failed validation.
+// Errors that do not belong to a single field are displayed above the row.
+// Rejected bulk edits are redrawn in the same way, with the same rows selected.
+func (widget Table) DrawErrors(params *url.URL, data map[string]any, err error, buffer io.Writer) error {
+
+	const location = "table.Widget.DrawErrors"
+
+	if path := params.Query().Get("path"); widget.isSubTablePath(path) {
+
+		subTable, pathErr := widget.findSubTable(path)
+
+		if pathErr != nil {
+			return derp.Wrap(pathErr, location, "Finding sub-table", path)
+		}
+
+		return subTable.DrawErrors(params, data, err, buffer)
+	}
+
+	widget = widget.scalarColumns()
+
+	tableElement, tableErr := widget.getTableElement()
+
+	if tableErr != nil {
+		return derp.Wrap(tableErr, location, "Getting table element")
+	}
+
+	rowSchema := newRowSchema(tableElement)
+	fields := widget.rowFields()
+
+	// Bulk edits, cell edits, and auto-saves only submit the one column that is being changed
+	query := params.Query()
+	if query.Get("bulk") == "edit" {
+		column, _ := strconv.Atoi(query.Get("column"))
+		if field, ok := widget.editableField(column); ok {
+			fields = []form.Element{field}
+			widget.selected = convert.SliceOfString(data[selectedField])
+		}
+	} else if query.Get("cell") != "" {
+		column, _ := strconv.Atoi(query.Get("focus"))
+		if field, ok := widget.editableField(column); ok {
+			fields = []form.Element{field}
+		}
+	} else if query.Get("autosave") != "" {
+		column, _ := strconv.Atoi(query.Get("focus"))
+		if field, ok := widget.autoSaveField(column); ok {
+			fields = []form.Element{field}
+		}
+	}
+
+	widget.submitted = data
+	widget.fieldErrors = widget.validateFields(&rowSchema, fields, data)
+
+	if len(widget.fieldErrors) == 0 {
+		widget.rowError = widget.message(derp.RootMessage(err))
+	}
+
+	return widget.Draw(params, buffer)
+}
+
+// validateFields checks each submitted value against the row schema on its own,
+// returning the (translated) validation message for every field that fails (by path).
+func (widget Table) validateFields(rowSchema *schema.Schema, fields []form.Element, data map[string]any) map[string]string {
+
+	result := make(map[string]string)
+
+	for _, field := range fields {
+
+		scratch := mapof.Any{}
+
+		if err := rowSchema.Set(&scratch, field.Path, data[field.Path]); err != nil {
+			result[field.Path] = widget.message(derp.RootMessage(err))
+		}
+	}
+
+	return result
+}
+
+// errorColumn returns the position (see fieldCount) of the first field with a
+// validation error, or -1 if there are none.
+func (widget Table) errorColumn() int {
+
+	for index := range widget.fieldCount() {
+		if field, ok := widget.autoSaveField(index); ok {
+			if _, ok := widget.fieldErrors[field.Path]; ok {
+				return index
+			}
+		}
+	}
+
+	return -1
+}
+
+// drawFieldError writes the validation error for a single field, if there is one
+func (widget Table) drawFieldError(path string, b *html.Builder) {
+
+	if message, ok := widget.fieldErrors[path]; ok {
+		b.Div().Class("grid-error").InnerText(message).Close()
+	}
+}
+
+// drawMessage writes a full-width row containing a message about the row below it
+func (widget Table) drawMessage(className string, message string, b *html.Builder) {
+
+	b.TR().Class("grid-row", className)
+	b.TD().
+		Class("grid-cell").
+		Attr("colspan", strconv.Itoa(widget.columnCount())).
+		InnerText(message)
+	b.Close() // TD
+	b.Close() // TR
+}
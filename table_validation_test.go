@@ -0,0 +1,82 @@
+package table
+
+import (
+	"bytes"
+	"html"
+	"strings"
+	"testing"
+
+	"github.com/benpate/derp"
+	"github.com/benpate/rosetta/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrawErrors_Edit(t *testing.T) {
+
+	table := newConstrainedTable()
+	db := table.Object.(*testDatabase)
+	var buffer bytes.Buffer
+
+	submitted := map[string]any{"name": "Kyle", "age": "not-a-number"}
+	err := table.Do(mustURL(t, "http://x?edit=1"), submitted)
+	require.Error(t, err)
+
+	require.NoError(t, table.DrawErrors(mustURL(t, "http://x?edit=1"), submitted, err, &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, "<form")
+	assert.Contains(t, result, `value="Kyle"`) // the user's values are kept
+	assert.Contains(t, result, `<div class="grid-error">Value must be an integer</div>`)
+	assert.NotContains(t, result, "Sarah Connor")              // instead of the saved values
+	assert.Contains(t, autofocusedInput(result), `name="age"`) // the invalid field is focused
+	assert.Equal(t, "Sarah Connor", db.Data[1]["name"])        // and nothing was saved
+}
+
+func TestDrawErrors_Add(t *testing.T) {
+
+	table := newConstrainedTable()
+	var buffer bytes.Buffer
+
+	submitted := map[string]any{"name": "", "age": "33"}
+	err := table.Do(mustURL(t, "http://x?add=true"), submitted)
+	require.Error(t, err)
+
+	require.NoError(t, table.DrawErrors(mustURL(t, "http://x?add=true"), submitted, err, &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `value="33"`)
+	assert.Contains(t, result, `<div class="grid-error">Value is required</div>`)
+	assert.Equal(t, 1, strings.Count(result, "grid-error"))
+}
+
+func TestDrawErrors_RowError(t *testing.T) {
+
+	table := newTestTable()
+	var buffer bytes.Buffer
+
+	// Errors that do not belong to a field are shown above the row
+	submitted := map[string]any{"name": "Kyle", "age": 30}
+	err := derp.Validation("Something is wrong with this row")
+
+	require.NoError(t, table.DrawErrors(mustURL(t, "http://x?edit=0"), submitted, err, &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, "grid-error")
+	assert.Contains(t, result, "Something is wrong with this row")
+	assert.Contains(t, result, `value="Kyle"`)
+}
+
+func TestValidateFields(t *testing.T) {
+
+	table := newConstrainedTable()
+	tableElement, err := table.getTableElement()
+	require.NoError(t, err)
+	rowSchema := schema.New(tableElement.Items)
+
+	result := table.validateFields(&rowSchema, table.Form.AllElements(), map[string]any{"name": "Bob", "age": "x"})
+	assert.Equal(t, map[string]string{"age": "Value must be an integer"}, result)
+
+	result = table.validateFields(&rowSchema, table.Form.AllElements(), map[string]any{"name": "Bob", "age": 3})
+	assert.Empty(t, result)
+}
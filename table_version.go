@@ -0,0 +1,143 @@
+package table
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	stdhtml "html"
+	"net/http"
+
+	"github.com/benpate/derp"
+	"github.com/benpate/form"
+	"github.com/benpate/html"
+	"github.com/benpate/rosetta/convert"
+	"github.com/benpate/rosetta/list"
+	"github.com/benpate/rosetta/mapof"
+	"github.com/benpate/rosetta/schema"
+)
+
+// versionField is the name of the hidden input that carries a row's version
+// token in edit forms.  The leading underscore keeps it from colliding with a
+// column path.  Delete requests carry the same token in the "version" query
+// parameter instead.
+const versionField = "_version"
+
+/******************************************
+ * Version Methods
+ ******************************************/
+
+// IsConflict returns TRUE if the error was caused by an edit or delete that was
+// based on an out-of-date copy of a row.  Callers can use DrawConflict to let
+// the user reconcile their changes with the newer values.
+func IsConflict(err error) bool {
+	return derp.ErrorCode(err) == http.StatusConflict
+}
+
+// rowVersion returns a token that changes whenever the row changes.  When the
+// table has a VersionPath, the token is the value of that field (and the caller
+// is responsible for updating it).  Otherwise, or if the row has no value in
+// that field, it is a hash of the row's values.
+func (widget Table) rowVersion(rowSchema *schema.Schema, rowValue any) string {
+
+	if widget.VersionPath != "" {
+		version, _ := rowSchema.Get(rowValue, widget.VersionPath)
+
+		if result := convert.String(version); result != "" {
+			return result
+		}
+	}
+
+	// Maps are marshalled with sorted keys, so equal rows always hash the same
+	rowJSON, err := json.Marshal(rowValue)
+
+	if err != nil {
+		return ""
+	}
+
+	hash := sha256.Sum256(rowJSON)
+	return hex.EncodeToString(hash[:])
+}
+
+// getRowVersions returns the version token of each row (by index)
+func (widget Table) getRowVersions(rowSchema *schema.Schema, rows []any) []string {
+
+	result := make([]string, len(rows))
+
+	for index, row := range rows {
+		result[index] = widget.rowVersion(rowSchema, row)
+	}
+
+	return result
+}
+
+// checkVersion returns a Conflict error if the row at rowIndex no longer matches
+// the version token that the client was given.  Every edit form and delete link
+// carries a token, so a missing token is treated like an out-of-date one.
+func (widget Table) checkVersion(rowIndex int, version string) error {
+
+	const location = "table.Widget.checkVersion"
+
+	tableElement, err := widget.getTableElement()
+
+	if err != nil {
+		return derp.Wrap(err, location, "Getting table element")
+	}
+
+	rowSchema := newRowSchema(tableElement)
+	rowValue, err := widget.getRow(tableElement, rowIndex)
+
+	if err != nil {
+		return derp.Wrap(err, location, "Getting row data", widget.Path, rowIndex)
+	}
+
+	if widget.rowVersion(&rowSchema, rowValue) != version {
+		return derp.BadRequest(location, widget.message(MessageRowChanged), widget.Path, rowIndex, derp.WithCode(http.StatusConflict))
+	}
+
+	return nil
+}
+
+// submittedRow builds a row from the values that a user submitted, so that
+// they can be drawn back into the edit row.  Values are stored as-is (without
+// validation) because the user needs to see exactly what they entered.  Fields
+// that were not submitted keep their values from baseRow (which may be nil).
+func (widget Table) submittedRow(rowSchema *schema.Schema, baseRow any) mapof.Any {
+
+	result := cloneRow(baseRow)
+
+	for _, field := range widget.rowFields() {
+		if value, ok := widget.submitted[field.Path]; ok {
+			_ = result.SetObject(rowSchema.Element, list.ByDot(field.Path), value)
+		}
+	}
+
+	return result
+}
+
+// drawConflictValue writes the saved value of a single field beneath its input,
+// but only if it differs from the value that the user submitted.
+func (widget Table) drawConflictValue(f *form.Form, field form.Element, editValue any, savedValue any, b *html.Builder) error {
+
+	const location = "table.Widget.drawConflictValue"
+
+	savedHTML, err := widget.viewCell(f, field, savedValue)
+
+	if err != nil {
+		return derp.Wrap(err, location, "Rendering saved value", field)
+	}
+
+	editHTML, err := widget.viewCell(f, field, editValue)
+
+	if err != nil {
+		return derp.Wrap(err, location, "Rendering submitted value", field)
+	}
+
+	// The saved value is already HTML, so only the translated text around it is escaped
+	if savedHTML != editHTML {
+		format := stdhtml.EscapeString(widget.message(MessageSavedValue))
+		b.Div().Class("grid-conflict-value").InnerHTML(fmt.Sprintf(format, savedHTML)).Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,217 @@
+package table
+
+import (
+	"bytes"
+	"html"
+	"testing"
+
+	"github.com/benpate/derp"
+	"github.com/benpate/rosetta/mapof"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRowVersion returns the current version token of a row in a test table
+func testRowVersion(t *testing.T, table Table, rowIndex int) string {
+	t.Helper()
+
+	tableElement, err := table.getTableElement()
+	require.NoError(t, err)
+
+	rowSchema := newRowSchema(tableElement)
+	rowValue, err := table.getRow(tableElement, rowIndex)
+	require.NoError(t, err)
+
+	return table.rowVersion(&rowSchema, rowValue)
+}
+
+// withVersion adds the current version token of a row to the data posted by its edit form
+func withVersion(t *testing.T, table Table, rowIndex int, data map[string]any) map[string]any {
+	t.Helper()
+
+	data[versionField] = testRowVersion(t, table, rowIndex)
+	return data
+}
+
+/******************************************
+ * rowVersion()
+ ******************************************/
+
+func TestRowVersion_Hash(t *testing.T) {
+
+	table := newTestTable()
+	db := table.Object.(*testDatabase)
+
+	before := testRowVersion(t, table, 0)
+	assert.NotEmpty(t, before)
+	assert.Equal(t, before, testRowVersion(t, table, 0))    // stable
+	assert.NotEqual(t, before, testRowVersion(t, table, 1)) // different rows, different tokens
+
+	db.Data[0]["name"] = "Kyle Reese"
+	assert.NotEqual(t, before, testRowVersion(t, table, 0)) // changes with the row
+}
+
+func TestRowVersion_VersionPath(t *testing.T) {
+
+	table := newKeyTable().UseVersionPath("taskId")
+
+	assert.Equal(t, "1", testRowVersion(t, table, 0))
+	assert.Equal(t, "abc", testRowVersion(t, table, 2))
+}
+
+// Rows without a value in VersionPath use a hash, so an empty token never matches
+func TestRowVersion_MissingVersionPath(t *testing.T) {
+
+	table := newTestTable().UseVersionPath("version")
+	db := table.Object.(*testDatabase)
+
+	assert.NotEmpty(t, testRowVersion(t, table, 0))
+	require.True(t, IsConflict(table.DoDelete(0, "")))
+	assert.Equal(t, 2, len(db.Data))
+}
+
+/******************************************
+ * Do()
+ ******************************************/
+
+func TestDoEdit_CurrentVersion(t *testing.T) {
+
+	table := newTestTable()
+	db := table.Object.(*testDatabase)
+	version := testRowVersion(t, table, 0)
+
+	err := table.Do(mustURL(t, "http://x?edit=0"), map[string]any{"name": "Kyle Reese", "age": 30, "_version": version})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Kyle Reese", db.Data[0]["name"])
+}
+
+func TestDoEdit_StaleVersion(t *testing.T) {
+
+	table := newTestTable()
+	db := table.Object.(*testDatabase)
+	version := testRowVersion(t, table, 0)
+
+	// Someone else changes the row after it was drawn
+	db.Data[0]["name"] = "T-800"
+
+	err := table.Do(mustURL(t, "http://x?edit=0"), map[string]any{"name": "Kyle Reese", "age": 30, "_version": version})
+
+	require.Error(t, err)
+	assert.True(t, IsConflict(err))
+	assert.Equal(t, "T-800", db.Data[0]["name"]) // unchanged
+}
+
+func TestDo_EditNoVersion(t *testing.T) {
+
+	table := newTestTable()
+	db := table.Object.(*testDatabase)
+
+	// Requests without a version token cannot skip the check
+	err := table.Do(mustURL(t, "http://x?edit=0"), map[string]any{"name": "Kyle Reese", "age": 30})
+
+	require.Error(t, err)
+	assert.True(t, IsConflict(err))
+	assert.Equal(t, "John Connor", db.Data[0]["name"])
+
+	err = table.Do(mustURL(t, "http://x?delete=0"), nil)
+
+	require.Error(t, err)
+	assert.True(t, IsConflict(err))
+	assert.Equal(t, 2, len(db.Data))
+
+	// Calling the Do* methods directly does not skip the check either
+	require.True(t, IsConflict(table.DoEdit(map[string]any{"name": "Kyle Reese", "age": 30}, 0)))
+	require.True(t, IsConflict(table.DoEditCell(0, "name", "Kyle Reese", "")))
+	require.True(t, IsConflict(table.DoDelete(0, "")))
+	assert.Equal(t, "John Connor", db.Data[0]["name"])
+	assert.Equal(t, 2, len(db.Data))
+}
+
+func TestDraw_NoVersions(t *testing.T) {
+
+	// Tables that cannot be edited or deleted do not need version tokens
+	table := newTestTable()
+	table.CanEdit = false
+	table.CanDelete = false
+
+	result := drawGroupTable(t, table, "http://x")
+	assert.NotContains(t, result, "version")
+}
+
+func TestDo_DeleteStaleVersion(t *testing.T) {
+
+	table := newTestTable()
+	db := table.Object.(*testDatabase)
+
+	err := table.Do(mustURL(t, "http://x?delete=0&version=out-of-date"), nil)
+
+	require.Error(t, err)
+	assert.True(t, IsConflict(err))
+	assert.Equal(t, 2, len(db.Data))
+}
+
+func TestDo_DeleteCurrentVersion(t *testing.T) {
+
+	table := newTestTable()
+	db := table.Object.(*testDatabase)
+
+	err := table.Do(mustURL(t, "http://x?delete=0&version="+testRowVersion(t, table, 0)), nil)
+
+	require.NoError(t, err)
+	require.Equal(t, 1, len(db.Data))
+	assert.Equal(t, "Sarah Connor", db.Data[0]["name"])
+}
+
+func TestIsConflict(t *testing.T) {
+	assert.False(t, IsConflict(nil))
+	assert.False(t, IsConflict(derp.BadRequest("test", "Not a conflict")))
+}
+
+/******************************************
+ * Drawing
+ ******************************************/
+
+func TestDraw_EditVersion(t *testing.T) {
+
+	table := newTestTable()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x?edit=1"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, `name="_version"`)
+	assert.Contains(t, result, testRowVersion(t, table, 1))
+}
+
+func TestDraw_DeleteVersion(t *testing.T) {
+
+	table := newTestTable()
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, "delete=0&version="+testRowVersion(t, table, 0))
+}
+
+func TestDrawConflict(t *testing.T) {
+
+	table := newTestTable()
+	db := table.Object.(*testDatabase)
+	var buffer bytes.Buffer
+
+	// Someone else renames the row while the user is changing its age
+	db.Data[0] = mapof.Any{"name": "T-800", "age": 20}
+	submitted := map[string]any{"name": "John Connor", "age": "21", "_version": "out-of-date"}
+
+	require.NoError(t, table.DrawConflict(mustURL(t, "http://x?edit=0"), submitted, &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, "grid-conflict")
+	assert.Contains(t, result, `value="John Connor"`) // the user's values are kept
+	assert.Contains(t, result, `value="21"`)
+	assert.Contains(t, result, "Saved: T-800")              // the newer saved value is shown
+	assert.Contains(t, result, "Saved: 20")                 // every changed field shows its saved value
+	assert.Contains(t, result, testRowVersion(t, table, 0)) // saving again uses the newer version
+}
@@ -0,0 +1,258 @@
+package table
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// Translator supplies the text of every message that the table shows to its
+// users, in the requested locale (such as "de" or "es-MX").  Messages are
+// identified by the Message* keys, and may include fmt-style verbs for their
+// arguments (such as the row number in "edit row %d").
+type Translator interface {
+	Translate(locale string, key string, args ...any) string
+}
+
+// Message keys for each string that the table shows to its users
+const (
+	// Buttons and controls
+	MessageAddRow                = "add-row"
+	MessageActions               = "actions"
+	MessageSaveNewRow            = "save-new-row"
+	MessageSaveRow               = "save-row"
+	MessageCancel                = "cancel"
+	MessageInsertAboveRow        = "insert-above-row"
+	MessageInsertBelowRow        = "insert-below-row"
+	MessageDuplicateRow          = "duplicate-row"
+	MessageEditRow               = "edit-row"
+	MessageDeleteRow             = "delete-row"
+	MessageConfirmDeleteRow      = "confirm-delete-row"
+	MessageMoveUpRow             = "move-up-row"
+	MessageMoveDownRow           = "move-down-row"
+	MessageSelectRow             = "select-row"
+	MessageSelectAllRows         = "select-all-rows"
+	MessageChangeSelected        = "change-selected"
+	MessageChangeSelectedRows    = "change-selected-rows"
+	MessageDeleteSelected        = "delete-selected"
+	MessageConfirmDeleteSelected = "confirm-delete-selected"
+	MessageApplyToSelected       = "apply-to-selected"
+	MessageSearch                = "search"
+	MessageFilterAll             = "filter-all"
+	MessageFilterYes             = "filter-yes"
+	MessageFilterNo              = "filter-no"
+	MessageFilterMin             = "filter-min"
+	MessageFilterMax             = "filter-max"
+	MessageFilterFrom            = "filter-from"
+	MessageFilterTo              = "filter-to"
+	MessageFirstPage             = "first-page"
+	MessagePreviousPage          = "previous-page"
+	MessageNextPage              = "next-page"
+	MessageLastPage              = "last-page"
+	MessagePageStatus            = "page-status"
+	MessageConflict              = "conflict"
+	MessageSavedValue            = "saved-value"
+	MessageCollapseGroup         = "collapse-group"
+	MessageExpandGroup           = "expand-group"
+	MessageGroupCount            = "group-count"
+	MessageGroupEmpty            = "group-empty"
+	MessageShowDetails           = "show-details"
+	MessageHideDetails           = "hide-details"
+	MessageCollapseRow           = "collapse-row"
+	MessageExpandRow             = "expand-row"
+	MessageAddChildRow           = "add-child-row"
+
+	// Relative times (see FormatRelative)
+	MessageJustNow     = "just-now"
+	MessageTimeAgo     = "time-ago"
+	MessageTimeFromNow = "time-from-now"
+	MessageMinute      = "minute"
+	MessageMinutes     = "minutes"
+	MessageHour        = "hour"
+	MessageHours       = "hours"
+	MessageDay         = "day"
+	MessageDays        = "days"
+	MessageMonth       = "month"
+	MessageMonths      = "months"
+	MessageYear        = "year"
+	MessageYears       = "years"
+
+	// Announcements for screen readers
+	MessageRowAdded          = "row-added"
+	MessageRowSaved          = "row-saved"
+	MessageRowDuplicated     = "row-duplicated"
+	MessageRowDeleted        = "row-deleted"
+	MessageRowMoved          = "row-moved"
+	MessageSelectedDeleted   = "selected-deleted"
+	MessageSelectedSaved     = "selected-saved"
+	MessageChangesSaved      = "changes-saved"
+	MessageChangesDuplicated = "changes-duplicated"
+
+	// Errors returned by Do
+	MessageAddNotAllowed       = "add-not-allowed"
+	MessageEditNotAllowed      = "edit-not-allowed"
+	MessageDeleteNotAllowed    = "delete-not-allowed"
+	MessageMoveNotAllowed      = "move-not-allowed"
+	MessageInsertNotAllowed    = "insert-not-allowed"
+	MessageDuplicateNotAllowed = "duplicate-not-allowed"
+	MessageFieldNotEditable    = "field-not-editable"
+	MessageRowNotFound         = "row-not-found"
+	MessageRowChanged          = "row-changed"
+	MessageTooManyRows         = "too-many-rows"
+	MessageTooFewRows          = "too-few-rows"
+	MessagePathNotInTable      = "path-not-in-table"
+	MessageInvalidRowIndex     = "invalid-row-index"
+	MessageNotSubTable         = "not-sub-table"
+	MessageNotTree             = "not-tree"
+	MessageRowNotRemoved       = "row-not-removed"
+)
+
+// Messages is a Translator for a single language, which maps each message key
+// to its text.  Keys that are missing fall back to English, so a translation
+// can be built up a few messages at a time.  Validation messages from the data
+// schema can also be translated, by using their English text as the key.
+type Messages map[string]string
+
+// English returns a copy of the default text of every message.  Changing the
+// copy does not change the defaults: to change a message, pass Messages that
+// contain only the new text to UseTranslator, and every other message falls
+// back to its default.
+func English() Messages {
+	return maps.Clone(english)
+}
+
+// english contains the default text of every message
+var english = Messages{
+	MessageAddRow:                "Add a Row",
+	MessageActions:               "Actions",
+	MessageSaveNewRow:            "save new row",
+	MessageSaveRow:               "save row %d",
+	MessageCancel:                "cancel",
+	MessageInsertAboveRow:        "insert above row %d",
+	MessageInsertBelowRow:        "insert below row %d",
+	MessageDuplicateRow:          "duplicate row %d",
+	MessageEditRow:               "edit row %d",
+	MessageDeleteRow:             "delete row %d",
+	MessageConfirmDeleteRow:      "Are you sure you want to delete this row?",
+	MessageMoveUpRow:             "move up row %d",
+	MessageMoveDownRow:           "move down row %d",
+	MessageSelectRow:             "select row %d",
+	MessageSelectAllRows:         "select all rows",
+	MessageChangeSelected:        "Change Selected...",
+	MessageChangeSelectedRows:    "change selected rows",
+	MessageDeleteSelected:        "Delete Selected",
+	MessageConfirmDeleteSelected: "Are you sure you want to delete the selected rows?",
+	MessageApplyToSelected:       "Apply to Selected",
+	MessageSearch:                "Search",
+	MessageFilterAll:             "All",
+	MessageFilterYes:             "Yes",
+	MessageFilterNo:              "No",
+	MessageFilterMin:             "Min",
+	MessageFilterMax:             "Max",
+	MessageFilterFrom:            "From",
+	MessageFilterTo:              "To",
+	MessageFirstPage:             "first page",
+	MessagePreviousPage:          "previous page",
+	MessageNextPage:              "next page",
+	MessageLastPage:              "last page",
+	MessagePageStatus:            "Page %d of %d",
+	MessageConflict:              "This row was changed by someone else while you were editing it.  The saved values are shown below each field.  Save again to keep your changes.",
+	MessageSavedValue:            "Saved: %s",
+	MessageCollapseGroup:         "collapse %s",
+	MessageExpandGroup:           "expand %s",
+	MessageGroupCount:            "(%d)",
+	MessageGroupEmpty:            "(none)",
+	MessageShowDetails:           "show details of row %d",
+	MessageHideDetails:           "hide details of row %d",
+	MessageCollapseRow:           "collapse row %d",
+	MessageExpandRow:             "expand row %d",
+	MessageAddChildRow:           "add child to row %d",
+
+	MessageJustNow:     "just now",
+	MessageTimeAgo:     "%s ago",
+	MessageTimeFromNow: "in %s",
+	MessageMinute:      "1 minute",
+	MessageMinutes:     "%d minutes",
+	MessageHour:        "1 hour",
+	MessageHours:       "%d hours",
+	MessageDay:         "1 day",
+	MessageDays:        "%d days",
+	MessageMonth:       "1 month",
+	MessageMonths:      "%d months",
+	MessageYear:        "1 year",
+	MessageYears:       "%d years",
+
+	MessageRowAdded:          "Row added",
+	MessageRowSaved:          "Row %d saved",
+	MessageRowDuplicated:     "Row %d duplicated",
+	MessageRowDeleted:        "Row deleted",
+	MessageRowMoved:          "Row moved",
+	MessageSelectedDeleted:   "Selected rows deleted",
+	MessageSelectedSaved:     "Selected rows saved",
+	MessageChangesSaved:      "Row saved",
+	MessageChangesDuplicated: "Row duplicated",
+
+	MessageAddNotAllowed:       "Adding is not allowed",
+	MessageEditNotAllowed:      "Editing is not allowed",
+	MessageDeleteNotAllowed:    "Deleting is not allowed",
+	MessageMoveNotAllowed:      "Moving is not allowed",
+	MessageInsertNotAllowed:    "Inserting is not allowed",
+	MessageDuplicateNotAllowed: "Duplicating is not allowed",
+	MessageFieldNotEditable:    "Field cannot be edited",
+	MessageRowNotFound:         "Row does not exist",
+	MessageRowChanged:          "Row has been changed by someone else",
+	MessageTooManyRows:         "Table already has the maximum number of rows",
+	MessageTooFewRows:          "Table cannot have fewer than the minimum number of rows",
+	MessagePathNotInTable:      "Path is not inside this table",
+	MessageInvalidRowIndex:     "Invalid row index",
+	MessageNotSubTable:         "Path is not a sub-table",
+	MessageNotTree:             "Table is not a tree",
+	MessageRowNotRemoved:       "Row could not be removed from the table",
+}
+
+// Translate implements the Translator interface.  The locale is ignored,
+// because Messages only contains a single language.
+func (messages Messages) Translate(_ string, key string, args ...any) string {
+
+	if format, ok := messages[key]; ok {
+		return formatMessage(format, args)
+	}
+
+	if format, ok := english[key]; ok {
+		return formatMessage(format, args)
+	}
+
+	// Unknown keys (such as validation messages) are displayed as-is
+	return key
+}
+
+// Languages is a Translator that chooses Messages by locale.  Locales are
+// matched exactly first, then by their language alone (so "es-MX" uses "es"
+// when there is no "es-MX"), and then fall back to English.
+type Languages map[string]Messages
+
+// Translate implements the Translator interface
+func (languages Languages) Translate(locale string, key string, args ...any) string {
+
+	if messages, ok := languages[locale]; ok {
+		return messages.Translate(locale, key, args...)
+	}
+
+	if language, _, ok := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-"); ok {
+		if messages, ok := languages[language]; ok {
+			return messages.Translate(locale, key, args...)
+		}
+	}
+
+	return english.Translate(locale, key, args...)
+}
+
+// formatMessage fills in a message's arguments, if it has any
+func formatMessage(format string, args []any) string {
+
+	if len(args) == 0 {
+		return format
+	}
+
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,135 @@
+package table
+
+import (
+	"bytes"
+	"html"
+	"testing"
+
+	"github.com/benpate/derp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testLanguages returns a partial German and Spanish translation
+func testLanguages() Languages {
+	return Languages{
+		"de": Messages{
+			MessageAddRow:              "Zeile hinzufügen",
+			MessageEditRow:             "Zeile %d bearbeiten",
+			MessageConfirmDeleteRow:    "Möchten Sie diese Zeile wirklich löschen?",
+			MessageDeleteNotAllowed:    "Löschen ist nicht erlaubt",
+			"Value must be an integer": "Wert muss eine ganze Zahl sein",
+		},
+		"es": Messages{
+			MessageAddRow: "Agregar una fila",
+		},
+		"es-MX": Messages{
+			MessageAddRow: "Agregar un renglón",
+		},
+	}
+}
+
+func TestMessages_Translate(t *testing.T) {
+
+	messages := Messages{MessageEditRow: "Zeile %d bearbeiten"}
+
+	assert.Equal(t, "Zeile 2 bearbeiten", messages.Translate("de", MessageEditRow, 2))
+	assert.Equal(t, "Add a Row", messages.Translate("de", MessageAddRow))             // missing keys fall back to English
+	assert.Equal(t, "Page 2 of 5", messages.Translate("de", MessagePageStatus, 2, 5)) // including their arguments
+	assert.Equal(t, "Unknown message", messages.Translate("de", "Unknown message"))   // unknown keys are displayed as-is
+}
+
+func TestLanguages_Translate(t *testing.T) {
+
+	languages := testLanguages()
+
+	assert.Equal(t, "Zeile hinzufügen", languages.Translate("de", MessageAddRow))
+	assert.Equal(t, "Zeile hinzufügen", languages.Translate("de-AT", MessageAddRow)) // by language alone
+	assert.Equal(t, "Zeile hinzufügen", languages.Translate("de_CH", MessageAddRow))
+	assert.Equal(t, "Agregar un renglón", languages.Translate("es-MX", MessageAddRow)) // exact matches first
+	assert.Equal(t, "Agregar una fila", languages.Translate("es-ES", MessageAddRow))
+	assert.Equal(t, "Add a Row", languages.Translate("fr", MessageAddRow)) // unknown locales use English
+	assert.Equal(t, "Add a Row", languages.Translate("", MessageAddRow))
+}
+
+// Every message key has English text
+func TestEnglish_Complete(t *testing.T) {
+
+	for key, text := range English() {
+		assert.NotEmpty(t, text, key)
+	}
+}
+
+// English returns a copy, so callers cannot change the defaults
+func TestEnglish_Copy(t *testing.T) {
+
+	messages := English()
+	messages[MessageAddRow] = "Changed"
+
+	assert.Equal(t, "Add a Row", English()[MessageAddRow])
+	assert.Equal(t, "Add a Row", Messages{}.Translate("", MessageAddRow))
+}
+
+func TestDraw_Translated(t *testing.T) {
+
+	table := newTestTable().UseTranslator(testLanguages())
+	var buffer bytes.Buffer
+
+	require.NoError(t, table.UseLocale("de").Draw(mustURL(t, "http://x"), &buffer))
+
+	result := html.UnescapeString(buffer.String())
+	assert.Contains(t, result, "plus Zeile hinzufügen")
+	assert.Contains(t, result, `aria-label="Zeile 1 bearbeiten"`)
+	assert.Contains(t, result, `hx-confirm="Möchten Sie diese Zeile wirklich löschen?"`)
+	assert.Contains(t, result, `aria-label="delete row 1"`) // untranslated messages are still English
+
+	// Each request can use a different locale
+	buffer.Reset()
+	require.NoError(t, table.UseLocale("es").Draw(mustURL(t, "http://x"), &buffer))
+	assert.Contains(t, buffer.String(), "plus Agregar una fila")
+}
+
+// Errors returned by Do are translated, too
+func TestDo_TranslatedError(t *testing.T) {
+
+	table := newTestTable().AllowNone().UseTranslator(testLanguages()).UseLocale("de")
+
+	err := table.Do(mustURL(t, "http://x?delete=0"), nil)
+
+	require.Error(t, err)
+	assert.Equal(t, "Löschen ist nicht erlaubt", derp.RootMessage(err))
+}
+
+func TestDrawErrors_Translated(t *testing.T) {
+
+	table := newConstrainedTable().UseTranslator(testLanguages()).UseLocale("de")
+	var buffer bytes.Buffer
+
+	submitted := map[string]any{"name": "Kyle", "age": "not-a-number"}
+	err := table.Do(mustURL(t, "http://x?edit=1"), submitted)
+	require.Error(t, err)
+
+	require.NoError(t, table.DrawErrors(mustURL(t, "http://x?edit=1"), submitted, err, &buffer))
+
+	assert.Contains(t, html.UnescapeString(buffer.String()), `<div class="grid-error">Wert muss eine ganze Zahl sein</div>`)
+}
+
+// Translated text is escaped before it is written next to icons and saved values
+func TestDraw_TranslatedHTML(t *testing.T) {
+
+	table := newTestTable().UseTranslator(Messages{
+		MessageAddRow:     "<Zeile> & mehr",
+		MessageSavedValue: "<b>Gespeichert</b>: %s",
+	})
+
+	var buffer bytes.Buffer
+	require.NoError(t, table.Draw(mustURL(t, "http://x"), &buffer))
+	assert.Contains(t, buffer.String(), "plus &lt;Zeile&gt; &amp; mehr")
+
+	db := table.Object.(*testDatabase)
+	db.Data[0]["name"] = "T-800"
+
+	buffer.Reset()
+	require.NoError(t, table.DrawConflict(mustURL(t, "http://x?edit=0"), map[string]any{"name": "John Connor", "age": "20"}, &buffer))
+	assert.Contains(t, buffer.String(), `<div class="grid-conflict-value">&lt;b&gt;Gespeichert&lt;/b&gt;: T-800</div>`)
+}
@@ -0,0 +1,105 @@
+package table
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// viewState holds the view-only options (such as sorting, paging, and filtering) that are read from the
+// query string.  These options never change the underlying data, so every URL
+// that the table generates must carry them forward to keep the view stable
+// across add/edit/delete round trips.
+type viewState struct {
+	SortPath  string     // Path of the column to sort by (empty means original order)
+	SortDesc  bool       // If TRUE, then rows are sorted in descending order
+	Page      int        // Page number to display (1-based; zero means the first page)
+	PageSize  int        // Number of rows per page requested by the client (zero means the Table's default)
+	Search    string     // Free text that visible rows must contain
+	Filters   url.Values // Column filter parameters ("filter.*", "min.*", and "max.*")
+	Collapsed []string   // Groups (by their GroupPath value) or tree rows (by their KeyPath value) whose rows are hidden
+	Expanded  []string   // Rows (by key, or by index) whose Details are displayed beneath them
+}
+
+// parseViewState reads the view options from a set of query parameters.
+// Values come from untrusted input, so they are validated again before use.
+func parseViewState(query url.Values) viewState {
+
+	// Non-numeric values parse to zero, which means "use the default"
+	page, _ := strconv.Atoi(query.Get("page"))
+	pageSize, _ := strconv.Atoi(query.Get("size"))
+
+	return viewState{
+		SortPath:  query.Get("sort"),
+		SortDesc:  query.Get("dir") == "desc",
+		Page:      max(page, 0),
+		PageSize:  max(pageSize, 0),
+		Search:    query.Get("q"),
+		Filters:   filterParams(query),
+		Collapsed: query["collapsed"],
+		Expanded:  query["expand"],
+	}
+}
+
+// filterParams returns only the column filter parameters from a set of query parameters
+// (or nil if there are none).  They are validated against the table's columns when a
+// FilterState is parsed from them.
+func filterParams(query url.Values) url.Values {
+
+	var result url.Values
+
+	for key, values := range query {
+		if isFilterParam(key) {
+			if result == nil {
+				result = url.Values{}
+			}
+			result[key] = values
+		}
+	}
+
+	return result
+}
+
+// isFilterParam returns TRUE if a query parameter names a column filter
+func isFilterParam(key string) bool {
+	return strings.HasPrefix(key, "filter.") || strings.HasPrefix(key, "min.") || strings.HasPrefix(key, "max.")
+}
+
+// setQuery writes the view options into a set of query parameters,
+// omitting any options that are not in use.
+func (state viewState) setQuery(query url.Values) {
+
+	if state.SortPath != "" {
+		query.Set("sort", state.SortPath)
+
+		if state.SortDesc {
+			query.Set("dir", "desc")
+		} else {
+			query.Set("dir", "asc")
+		}
+	}
+
+	if state.Page > 1 {
+		query.Set("page", strconv.Itoa(state.Page))
+	}
+
+	if state.PageSize > 0 {
+		query.Set("size", strconv.Itoa(state.PageSize))
+	}
+
+	if state.Search != "" {
+		query.Set("q", state.Search)
+	}
+
+	for key, values := range state.Filters {
+		query[key] = values
+	}
+
+	if len(state.Collapsed) > 0 {
+		query["collapsed"] = state.Collapsed
+	}
+
+	if len(state.Expanded) > 0 {
+		query["expand"] = state.Expanded
+	}
+}